@@ -0,0 +1,53 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Critical",type=integer,JSONPath=`.status.vulnerabilities.critical`
+// +kubebuilder:printcolumn:name="High",type=integer,JSONPath=`.status.vulnerabilities.high`
+// +kubebuilder:printcolumn:name="Medium",type=integer,JSONPath=`.status.vulnerabilities.medium`,priority=1
+// +kubebuilder:printcolumn:name="Malware",type=integer,JSONPath=`.status.findings.malware`,priority=1
+// +kubebuilder:printcolumn:name="Last Scan",type=date,JSONPath=`.status.lastScanTime`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterImageScan is the Schema for the clusterimagescans API.
+// It tracks the scan of an image digest once for the whole cluster; only
+// ClusterScanPolicies apply to it.
+type ClusterImageScan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageScanSpec   `json:"spec,omitempty"`
+	Status ImageScanStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterImageScanList contains a list of ClusterImageScan
+type ClusterImageScanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterImageScan `json:"items"`
+}
+
+// ScanSpec returns the spec of the ImageScan.
+func (s *ImageScan) ScanSpec() *ImageScanSpec { return &s.Spec }
+
+// ScanStatus returns the status of the ImageScan.
+func (s *ImageScan) ScanStatus() *ImageScanStatus { return &s.Status }
+
+// ScanSpec returns the spec of the ClusterImageScan.
+func (s *ClusterImageScan) ScanSpec() *ImageScanSpec { return &s.Spec }
+
+// ScanStatus returns the status of the ClusterImageScan.
+func (s *ClusterImageScan) ScanStatus() *ImageScanStatus { return &s.Status }
+
+func init() {
+	SchemeBuilder.Register(&ClusterImageScan{}, &ClusterImageScanList{})
+}
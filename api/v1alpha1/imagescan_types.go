@@ -18,16 +18,79 @@ type ImageScanSpec struct {
 	// Registry is the source registry for the image
 	// +optional
 	Registry string `json:"registry,omitempty"`
+
+	// ClusterScanRef names the ClusterImageScan this ImageScan mirrors. Such an ImageScan
+	// copies the cluster scan's results instead of querying Aqua itself, and applies the
+	// ScanPolicies of its namespace on top of them.
+	// +optional
+	ClusterScanRef string `json:"clusterScanRef,omitempty"`
 }
 
 // ScanPhase represents the current phase of the scan
-// +kubebuilder:validation:Enum=Pending;Registered;Error
+// +kubebuilder:validation:Enum=Pending;Registered;Passed;Failed;Error
 type ScanPhase string
 
 const (
 	ScanPhasePending    ScanPhase = "Pending"
 	ScanPhaseRegistered ScanPhase = "Registered"
-	ScanPhaseError      ScanPhase = "Error"
+	// ScanPhasePassed means the image satisfied every applicable scan policy
+	ScanPhasePassed ScanPhase = "Passed"
+	// ScanPhaseFailed means the image violated at least one scan policy, or that
+	// Aqua could not scan it within the retry limit. Failed is terminal.
+	ScanPhaseFailed ScanPhase = "Failed"
+	// ScanPhaseError means the last Aqua request failed; it is retried with backoff
+	ScanPhaseError ScanPhase = "Error"
+)
+
+// ImageScan condition types
+const (
+	// ConditionScanTriggered reports whether Aqua has been asked to scan the image
+	ConditionScanTriggered = "ScanTriggered"
+	// ConditionScanCompleted reports whether Aqua has finished scanning the image
+	ConditionScanCompleted = "ScanCompleted"
+	// ConditionPolicyEvaluated reports whether scan policies were evaluated against the results
+	ConditionPolicyEvaluated = "PolicyEvaluated"
+	// ConditionAquaReachable reports whether the last request to Aqua succeeded
+	ConditionAquaReachable = "AquaReachable"
+	// ConditionReady reports whether the ImageScan has reached a verdict that admits the image
+	ConditionReady = "Ready"
+	// ConditionTimedOut is set when Aqua did not finish a scan within the scan timeout
+	ConditionTimedOut = "TimedOut"
+)
+
+// ImageScan condition reasons
+const (
+	// ReasonTriggered means the controller triggered the Aqua scan
+	ReasonTriggered = "Triggered"
+	// ReasonFoundInAqua means Aqua already knew the image, so no scan was triggered
+	ReasonFoundInAqua = "FoundInAqua"
+	// ReasonScanFinished means Aqua finished scanning the image
+	ReasonScanFinished = "ScanFinished"
+	// ReasonNoPolicies means no scan policy applies to the image
+	ReasonNoPolicies = "NoPolicies"
+	// ReasonReachable means the last request to Aqua succeeded
+	ReasonReachable    = "Reachable"
+	ReasonScanPending  = "ScanPending"
+	ReasonRegistered   = "Registered"
+	ReasonPolicyPassed = "PolicyPassed"
+	// ReasonPolicyViolation means a scan policy rejected the image
+	ReasonPolicyViolation = "PolicyViolation"
+	// ReasonAssurancePolicyFailed means Aqua's assurance policies disallow the image
+	ReasonAssurancePolicyFailed = "AssurancePolicyFailed"
+	// ReasonAuthenticationFailed means Aqua rejected the configured credentials
+	ReasonAuthenticationFailed = "AuthenticationFailed"
+	// ReasonRegistryNotFound means no Aqua registry matches the image's registry
+	ReasonRegistryNotFound = "RegistryNotFound"
+	// ReasonAquaServerError means Aqua responded with a 5xx status
+	ReasonAquaServerError = "AquaServerError"
+	// ReasonAquaRequestFailed covers any other failed request to Aqua
+	ReasonAquaRequestFailed = "AquaRequestFailed"
+	// ReasonScanFailed means Aqua reported that scanning the image failed
+	ReasonScanFailed = "ScanFailed"
+	// ReasonScanTimedOut means Aqua did not finish the scan within the scan timeout
+	ReasonScanTimedOut = "ScanTimedOut"
+	// ReasonRetriesExhausted means the scan kept erroring and is no longer retried
+	ReasonRetriesExhausted = "RetriesExhausted"
 )
 
 // VulnerabilitySummary contains counts of vulnerabilities by severity
@@ -39,6 +102,40 @@ type VulnerabilitySummary struct {
 	Unknown  int `json:"unknown"`
 }
 
+// FindingSummary contains counts of findings other than vulnerabilities
+type FindingSummary struct {
+	// Malware is the number of malware findings
+	Malware int `json:"malware"`
+	// SensitiveData is the number of exposed secrets, including private keys
+	SensitiveData int `json:"sensitiveData"`
+	// PrivateKeys is the number of embedded private keys
+	PrivateKeys int `json:"privateKeys"`
+	// Misconfigurations is the number of image misconfigurations
+	Misconfigurations int `json:"misconfigurations"`
+}
+
+// SuppressedVulnerability is a finding that an OpenVEX statement declares not_affected
+type SuppressedVulnerability struct {
+	// ID is the vulnerability ID (e.g., CVE-2023-12345)
+	ID string `json:"id"`
+
+	// Package is the affected package
+	// +optional
+	Package string `json:"package,omitempty"`
+
+	// Justification is the OpenVEX justification (e.g., vulnerable_code_not_in_execute_path)
+	// +optional
+	Justification string `json:"justification,omitempty"`
+
+	// ImpactStatement explains why the product is not affected
+	// +optional
+	ImpactStatement string `json:"impactStatement,omitempty"`
+
+	// Document is the @id of the OpenVEX document holding the statement
+	// +optional
+	Document string `json:"document,omitempty"`
+}
+
 // ImageScanStatus defines the observed state of ImageScan
 type ImageScanStatus struct {
 	// Phase is the current phase of the scan
@@ -49,6 +146,15 @@ type ImageScanStatus struct {
 	// +optional
 	AquaScanID string `json:"aquaScanId,omitempty"`
 
+	// TriggeredTime is when the Aqua scan was triggered, or first seen in progress.
+	// The scan timeout is measured from it.
+	// +optional
+	TriggeredTime *metav1.Time `json:"triggeredTime,omitempty"`
+
+	// PollCount is how many times Aqua was polled for the results of the current scan
+	// +optional
+	PollCount int `json:"pollCount,omitempty"`
+
 	// LastScanTime is when the scan was last performed
 	// +optional
 	LastScanTime *metav1.Time `json:"lastScanTime,omitempty"`
@@ -57,19 +163,73 @@ type ImageScanStatus struct {
 	// +optional
 	CompletedTime *metav1.Time `json:"completedTime,omitempty"`
 
+	// LastCheckedTime is when results were last fetched from Aqua; periodic
+	// rescans are scheduled relative to it
+	// +optional
+	LastCheckedTime *metav1.Time `json:"lastCheckedTime,omitempty"`
+
 	// Vulnerabilities contains the summary of found vulnerabilities
 	// +optional
 	Vulnerabilities *VulnerabilitySummary `json:"vulnerabilities,omitempty"`
 
+	// EffectiveVulnerabilities counts the vulnerabilities scan policies and their rules are
+	// evaluated against: Vulnerabilities without the findings that OpenVEX declares
+	// not_affected or that ScanExceptions waive
+	// +optional
+	EffectiveVulnerabilities *VulnerabilitySummary `json:"effectiveVulnerabilities,omitempty"`
+
+	// Fixable counts the EffectiveVulnerabilities that have a fixed version. It is set when
+	// the per-vulnerability details are known, which they always are once a scan policy
+	// has rules.
+	// +optional
+	Fixable *VulnerabilitySummary `json:"fixable,omitempty"`
+
+	// Findings contains the summary of malware, sensitive data and misconfiguration findings
+	// +optional
+	Findings *FindingSummary `json:"findings,omitempty"`
+
+	// AquaScanStatus is the scan status reported by Aqua (e.g., finished, pending, failed)
+	// +optional
+	AquaScanStatus string `json:"aquaScanStatus,omitempty"`
+
+	// ReportChunks is the number of ImageScanReports holding the per-vulnerability
+	// details of the last scan
+	// +optional
+	ReportChunks int `json:"reportChunks,omitempty"`
+
+	// Disallowed is true when Aqua's own assurance policies disallow the image
+	// +optional
+	Disallowed bool `json:"disallowed,omitempty"`
+
+	// FailedAssurancePolicies names the Aqua assurance policies the image failed
+	// +optional
+	FailedAssurancePolicies []string `json:"failedAssurancePolicies,omitempty"`
+
 	// Message provides additional details about the current phase
 	// +optional
 	Message string `json:"message,omitempty"`
 
+	// Policy is the scan policy that produced the Failed verdict
+	// (e.g., ClusterScanPolicy/baseline, ScanPolicy/team-a/strict or
+	// AquaAssurancePolicy/Default)
+	// +optional
+	Policy string `json:"policy,omitempty"`
+
+	// Exceptions lists the ScanExceptions that waived findings in the last policy evaluation
+	// +optional
+	Exceptions []string `json:"exceptions,omitempty"`
+
+	// Suppressed lists the findings OpenVEX statements declared not_affected, which
+	// were left out of the last policy evaluation
+	// +optional
+	Suppressed []SuppressedVulnerability `json:"suppressed,omitempty"`
+
 	// Conditions represent the latest available observations
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
-	// RetryCount tracks the number of consecutive errors for exponential backoff
+	// RetryCount tracks the number of consecutive errors for exponential backoff.
+	// Once it exceeds the configured maximum the scan is Failed.
 	// +optional
 	RetryCount int `json:"retryCount,omitempty"`
 }
@@ -80,6 +240,9 @@ type ImageScanStatus struct {
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Critical",type=integer,JSONPath=`.status.vulnerabilities.critical`
 // +kubebuilder:printcolumn:name="High",type=integer,JSONPath=`.status.vulnerabilities.high`
+// +kubebuilder:printcolumn:name="Medium",type=integer,JSONPath=`.status.vulnerabilities.medium`,priority=1
+// +kubebuilder:printcolumn:name="Malware",type=integer,JSONPath=`.status.findings.malware`,priority=1
+// +kubebuilder:printcolumn:name="Last Scan",type=date,JSONPath=`.status.lastScanTime`,priority=1
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ImageScan is the Schema for the imagescans API
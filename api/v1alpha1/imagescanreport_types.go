@@ -0,0 +1,90 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LabelImageScan names the ImageScan an ImageScanReport belongs to
+const LabelImageScan = "scans.aquasec.community/imagescan"
+
+// Vulnerability describes a single vulnerability found in an image
+type Vulnerability struct {
+	// ID is the vulnerability identifier (e.g., CVE-2023-12345)
+	ID string `json:"id"`
+
+	// Package is the name of the affected package or file
+	// +optional
+	Package string `json:"package,omitempty"`
+
+	// PackageType is the package's purl type (e.g., deb, npm, golang), if Aqua reports it
+	// +optional
+	PackageType string `json:"packageType,omitempty"`
+
+	// InstalledVersion is the version of the package in the image
+	// +optional
+	InstalledVersion string `json:"installedVersion,omitempty"`
+
+	// FixedVersion is the first version that fixes the vulnerability (empty if none)
+	// +optional
+	FixedVersion string `json:"fixedVersion,omitempty"`
+
+	// Severity is Aqua's severity rating (critical, high, medium, low, negligible)
+	// +optional
+	Severity string `json:"severity,omitempty"`
+
+	// Score is Aqua's vulnerability score (e.g., "7.5")
+	// +optional
+	Score string `json:"score,omitempty"`
+
+	// Acknowledged is true when the vulnerability was acknowledged in Aqua
+	// +optional
+	Acknowledged bool `json:"acknowledged,omitempty"`
+}
+
+// ImageScanReportData holds one chunk of the vulnerabilities found in an image
+type ImageScanReportData struct {
+	// Image is the full image reference of the scanned image
+	Image string `json:"image"`
+
+	// Digest is the image digest (sha256:...)
+	Digest string `json:"digest"`
+
+	// Chunk is the index of this report among the ImageScan's reports, starting at 0
+	Chunk int `json:"chunk"`
+
+	// Chunks is the total number of reports holding the ImageScan's vulnerabilities
+	Chunks int `json:"chunks"`
+
+	// Vulnerabilities lists the vulnerabilities in this chunk
+	// +optional
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.report.image`
+// +kubebuilder:printcolumn:name="Chunk",type=integer,JSONPath=`.report.chunk`
+// +kubebuilder:printcolumn:name="Chunks",type=integer,JSONPath=`.report.chunks`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImageScanReport is the Schema for the imagescanreports API.
+// It holds per-vulnerability details of an ImageScan, which owns it. Large
+// reports are split across several ImageScanReports labeled with the ImageScan name.
+type ImageScanReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Report ImageScanReportData `json:"report"`
+}
+
+// +kubebuilder:object:root=true
+
+// ImageScanReportList contains a list of ImageScanReport
+type ImageScanReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageScanReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageScanReport{}, &ImageScanReportList{})
+}
@@ -0,0 +1,87 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScanExceptionSpec defines which findings are waived, where, and until when.
+// With CVEs only, those vulnerabilities are waived in every image. With Images or
+// Digests only, every finding in those images is waived. With both, the CVEs are
+// only waived in those images.
+// +kubebuilder:validation:XValidation:rule="has(self.cves) || has(self.images) || has(self.digests)",message="at least one of cves, images or digests is required"
+type ScanExceptionSpec struct {
+	// CVEs lists the waived vulnerability IDs (e.g., CVE-2023-12345)
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	CVEs []string `json:"cves,omitempty"`
+
+	// Images lists image repositories whose findings are waived, without tag or digest
+	// (e.g., registry.example.com/team/app)
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	Images []string `json:"images,omitempty"`
+
+	// Digests lists image digests whose findings are waived
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	Digests []string `json:"digests,omitempty"`
+
+	// Namespaces the exception applies to. Empty applies it to every namespace and
+	// to ClusterImageScans.
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Expires is when the exception stops applying
+	// +kubebuilder:validation:Required
+	Expires metav1.Time `json:"expires"`
+
+	// ApprovedBy is the user who created the exception or last changed its spec.
+	// It is set by the mutating webhook from the request's user; values set by clients
+	// are replaced.
+	// +optional
+	ApprovedBy string `json:"approvedBy,omitempty"`
+
+	// Reason records why the findings are waived
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Reason string `json:"reason"`
+}
+
+// ScanExceptionStatus defines the observed state of ScanException
+type ScanExceptionStatus struct {
+	// Expired is true once the exception has passed its expiry and no longer applies
+	// +optional
+	Expired bool `json:"expired,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.spec.expires`
+// +kubebuilder:printcolumn:name="Expired",type=boolean,JSONPath=`.status.expired`
+// +kubebuilder:printcolumn:name="Approved By",type=string,JSONPath=`.spec.approvedBy`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ScanException is the Schema for the scanexceptions API.
+// It waives findings during scan policy evaluation until it expires.
+type ScanException struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScanExceptionSpec   `json:"spec,omitempty"`
+	Status ScanExceptionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ScanExceptionList contains a list of ScanException
+type ScanExceptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScanException `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ScanException{}, &ScanExceptionList{})
+}
@@ -0,0 +1,52 @@
+package v1alpha1
+
+import (
+	"os"
+	"testing"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"sigs.k8s.io/yaml"
+)
+
+func TestScanExceptionRejectsEmptyLists(t *testing.T) {
+	data, err := os.ReadFile("../../config/crd/bases/scans.aquasec.community_scanexceptions.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := yaml.Unmarshal(data, &crd); err != nil {
+		t.Fatal(err)
+	}
+	var schema apiextensions.CustomResourceValidation
+	if err := apiextensionsv1.Convert_v1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(
+		crd.Spec.Versions[0].Schema, &schema, nil); err != nil {
+		t.Fatal(err)
+	}
+	validator, _, err := validation.NewSchemaValidator(schema.OpenAPIV3Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exception := func(list string, items ...any) map[string]any {
+		return map[string]any{
+			"apiVersion": "scans.aquasec.community/v1alpha1",
+			"kind":       "ScanException",
+			"metadata":   map[string]any{"name": "openssl"},
+			"spec": map[string]any{
+				list:      items,
+				"expires": "2030-01-01T00:00:00Z",
+				"reason":  "No fix available upstream",
+			},
+		}
+	}
+	for _, list := range []string{"cves", "images", "digests", "namespaces"} {
+		if result := validator.Validate(exception(list)); result.IsValid() {
+			t.Errorf("expected an empty %s list to be rejected", list)
+		}
+	}
+	if result := validator.Validate(exception("cves", "CVE-2024-0001")); !result.IsValid() {
+		t.Errorf("expected a valid exception, got %v", result.Errors)
+	}
+}
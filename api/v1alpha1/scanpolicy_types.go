@@ -0,0 +1,120 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScanPolicySpec defines the vulnerability and finding thresholds an image must satisfy.
+// A nil threshold means that severity or finding category is not limited.
+type ScanPolicySpec struct {
+	// MaxCritical is the maximum number of critical vulnerabilities allowed
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxCritical *int `json:"maxCritical,omitempty"`
+
+	// MaxHigh is the maximum number of high vulnerabilities allowed
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxHigh *int `json:"maxHigh,omitempty"`
+
+	// MaxMedium is the maximum number of medium vulnerabilities allowed
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxMedium *int `json:"maxMedium,omitempty"`
+
+	// BlockMalware rejects images in which Aqua found malware, whatever their vulnerabilities
+	// +optional
+	BlockMalware bool `json:"blockMalware,omitempty"`
+
+	// BlockPrivateKeys rejects images that embed private keys
+	// +optional
+	BlockPrivateKeys bool `json:"blockPrivateKeys,omitempty"`
+
+	// MaxSensitiveData is the maximum number of sensitive data findings (e.g., keys,
+	// tokens, passwords) allowed
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxSensitiveData *int `json:"maxSensitiveData,omitempty"`
+
+	// MaxMisconfigurations is the maximum number of image misconfigurations allowed
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxMisconfigurations *int `json:"maxMisconfigurations,omitempty"`
+
+	// Rules are CEL expressions every image of a pod must satisfy. They are evaluated by
+	// the Pod gate controller once the image's scan has passed, with the pod, namespaceObject,
+	// image and scan variables.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// Rule is a CEL expression over a pod, one of its images and the image's scan result
+type Rule struct {
+	// Name identifies the rule in violation messages
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Expression must evaluate to true for the image to be admitted
+	// (e.g., pod.ownerKind == 'Job' || scan.fixable.high == 0)
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+
+	// Message is reported when the expression evaluates to false
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Max Critical",type=integer,JSONPath=`.spec.maxCritical`
+// +kubebuilder:printcolumn:name="Max High",type=integer,JSONPath=`.spec.maxHigh`
+// +kubebuilder:printcolumn:name="Max Medium",type=integer,JSONPath=`.spec.maxMedium`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ScanPolicy is the Schema for the scanpolicies API.
+// It applies to ImageScans in its own namespace.
+type ScanPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ScanPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ScanPolicyList contains a list of ScanPolicy
+type ScanPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScanPolicy `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Max Critical",type=integer,JSONPath=`.spec.maxCritical`
+// +kubebuilder:printcolumn:name="Max High",type=integer,JSONPath=`.spec.maxHigh`
+// +kubebuilder:printcolumn:name="Max Medium",type=integer,JSONPath=`.spec.maxMedium`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterScanPolicy is the Schema for the clusterscanpolicies API.
+// It applies to ImageScans in every namespace.
+type ClusterScanPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ScanPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterScanPolicyList contains a list of ClusterScanPolicy
+type ClusterScanPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterScanPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ScanPolicy{}, &ScanPolicyList{}, &ClusterScanPolicy{}, &ClusterScanPolicyList{})
+}
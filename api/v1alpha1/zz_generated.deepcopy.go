@@ -23,6 +23,138 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterImageScan) DeepCopyInto(out *ClusterImageScan) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterImageScan.
+func (in *ClusterImageScan) DeepCopy() *ClusterImageScan {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterImageScan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterImageScan) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterImageScanList) DeepCopyInto(out *ClusterImageScanList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterImageScan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterImageScanList.
+func (in *ClusterImageScanList) DeepCopy() *ClusterImageScanList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterImageScanList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterImageScanList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterScanPolicy) DeepCopyInto(out *ClusterScanPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterScanPolicy.
+func (in *ClusterScanPolicy) DeepCopy() *ClusterScanPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterScanPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterScanPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterScanPolicyList) DeepCopyInto(out *ClusterScanPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterScanPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterScanPolicyList.
+func (in *ClusterScanPolicyList) DeepCopy() *ClusterScanPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterScanPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterScanPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FindingSummary) DeepCopyInto(out *FindingSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FindingSummary.
+func (in *FindingSummary) DeepCopy() *FindingSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(FindingSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageScan) DeepCopyInto(out *ImageScan) {
 	*out = *in
@@ -82,6 +214,84 @@ func (in *ImageScanList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanReport) DeepCopyInto(out *ImageScanReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Report.DeepCopyInto(&out.Report)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanReport.
+func (in *ImageScanReport) DeepCopy() *ImageScanReport {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageScanReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanReportData) DeepCopyInto(out *ImageScanReportData) {
+	*out = *in
+	if in.Vulnerabilities != nil {
+		in, out := &in.Vulnerabilities, &out.Vulnerabilities
+		*out = make([]Vulnerability, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanReportData.
+func (in *ImageScanReportData) DeepCopy() *ImageScanReportData {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanReportData)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanReportList) DeepCopyInto(out *ImageScanReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageScanReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanReportList.
+func (in *ImageScanReportList) DeepCopy() *ImageScanReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageScanReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageScanSpec) DeepCopyInto(out *ImageScanSpec) {
 	*out = *in
@@ -100,6 +310,10 @@ func (in *ImageScanSpec) DeepCopy() *ImageScanSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageScanStatus) DeepCopyInto(out *ImageScanStatus) {
 	*out = *in
+	if in.TriggeredTime != nil {
+		in, out := &in.TriggeredTime, &out.TriggeredTime
+		*out = (*in).DeepCopy()
+	}
 	if in.LastScanTime != nil {
 		in, out := &in.LastScanTime, &out.LastScanTime
 		*out = (*in).DeepCopy()
@@ -108,11 +322,45 @@ func (in *ImageScanStatus) DeepCopyInto(out *ImageScanStatus) {
 		in, out := &in.CompletedTime, &out.CompletedTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastCheckedTime != nil {
+		in, out := &in.LastCheckedTime, &out.LastCheckedTime
+		*out = (*in).DeepCopy()
+	}
 	if in.Vulnerabilities != nil {
 		in, out := &in.Vulnerabilities, &out.Vulnerabilities
 		*out = new(VulnerabilitySummary)
 		**out = **in
 	}
+	if in.EffectiveVulnerabilities != nil {
+		in, out := &in.EffectiveVulnerabilities, &out.EffectiveVulnerabilities
+		*out = new(VulnerabilitySummary)
+		**out = **in
+	}
+	if in.Fixable != nil {
+		in, out := &in.Fixable, &out.Fixable
+		*out = new(VulnerabilitySummary)
+		**out = **in
+	}
+	if in.Findings != nil {
+		in, out := &in.Findings, &out.Findings
+		*out = new(FindingSummary)
+		**out = **in
+	}
+	if in.FailedAssurancePolicies != nil {
+		in, out := &in.FailedAssurancePolicies, &out.FailedAssurancePolicies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exceptions != nil {
+		in, out := &in.Exceptions, &out.Exceptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Suppressed != nil {
+		in, out := &in.Suppressed, &out.Suppressed
+		*out = make([]SuppressedVulnerability, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -132,6 +380,264 @@ func (in *ImageScanStatus) DeepCopy() *ImageScanStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rule) DeepCopyInto(out *Rule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rule.
+func (in *Rule) DeepCopy() *Rule {
+	if in == nil {
+		return nil
+	}
+	out := new(Rule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanException) DeepCopyInto(out *ScanException) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanException.
+func (in *ScanException) DeepCopy() *ScanException {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanException)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScanException) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanExceptionList) DeepCopyInto(out *ScanExceptionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ScanException, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanExceptionList.
+func (in *ScanExceptionList) DeepCopy() *ScanExceptionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanExceptionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScanExceptionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanExceptionSpec) DeepCopyInto(out *ScanExceptionSpec) {
+	*out = *in
+	if in.CVEs != nil {
+		in, out := &in.CVEs, &out.CVEs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Digests != nil {
+		in, out := &in.Digests, &out.Digests
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Expires.DeepCopyInto(&out.Expires)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanExceptionSpec.
+func (in *ScanExceptionSpec) DeepCopy() *ScanExceptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanExceptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanExceptionStatus) DeepCopyInto(out *ScanExceptionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanExceptionStatus.
+func (in *ScanExceptionStatus) DeepCopy() *ScanExceptionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanExceptionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanPolicy) DeepCopyInto(out *ScanPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanPolicy.
+func (in *ScanPolicy) DeepCopy() *ScanPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScanPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanPolicyList) DeepCopyInto(out *ScanPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ScanPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanPolicyList.
+func (in *ScanPolicyList) DeepCopy() *ScanPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScanPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanPolicySpec) DeepCopyInto(out *ScanPolicySpec) {
+	*out = *in
+	if in.MaxCritical != nil {
+		in, out := &in.MaxCritical, &out.MaxCritical
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxHigh != nil {
+		in, out := &in.MaxHigh, &out.MaxHigh
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxMedium != nil {
+		in, out := &in.MaxMedium, &out.MaxMedium
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxSensitiveData != nil {
+		in, out := &in.MaxSensitiveData, &out.MaxSensitiveData
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxMisconfigurations != nil {
+		in, out := &in.MaxMisconfigurations, &out.MaxMisconfigurations
+		*out = new(int)
+		**out = **in
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]Rule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanPolicySpec.
+func (in *ScanPolicySpec) DeepCopy() *ScanPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SuppressedVulnerability) DeepCopyInto(out *SuppressedVulnerability) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SuppressedVulnerability.
+func (in *SuppressedVulnerability) DeepCopy() *SuppressedVulnerability {
+	if in == nil {
+		return nil
+	}
+	out := new(SuppressedVulnerability)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Vulnerability) DeepCopyInto(out *Vulnerability) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Vulnerability.
+func (in *Vulnerability) DeepCopy() *Vulnerability {
+	if in == nil {
+		return nil
+	}
+	out := new(Vulnerability)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VulnerabilitySummary) DeepCopyInto(out *VulnerabilitySummary) {
 	*out = *in
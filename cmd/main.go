@@ -9,20 +9,32 @@ import (
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
 	"github.com/richardmsong/aqua-scan-gate/internal/controller"
 	webhookpkg "github.com/richardmsong/aqua-scan-gate/internal/webhook"
 	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+	"github.com/richardmsong/aqua-scan-gate/pkg/enforcement"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageexclude"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imagepolicy"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/reloadfile"
+	"github.com/richardmsong/aqua-scan-gate/pkg/scope"
 	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+	"github.com/richardmsong/aqua-scan-gate/pkg/vex"
 )
 
 var (
@@ -52,9 +64,38 @@ func main() {
 	pflag.String("api-key", "", "Aqua API key (env: AQUA_API_KEY)")
 	pflag.String("hmac-secret", "", "HMAC secret for signing (env: AQUA_HMAC_SECRET)")
 	pflag.String("excluded-namespaces", "kube-system,kube-public,cert-manager", "Namespaces to exclude (env: AQUA_EXCLUDED_NAMESPACES)")
+	pflag.String("namespace-selector", "", "Only gate pods in namespaces matching this label selector, empty gates all (env: AQUA_NAMESPACE_SELECTOR)")
+	pflag.String("excluded-namespace-selector", "", "Exclude namespaces matching this label selector (env: AQUA_EXCLUDED_NAMESPACE_SELECTOR)")
+	pflag.Bool("authorize-bypass", true, "Only admit the "+webhookpkg.AnnotationBypassScan+" annotation from users allowed to "+webhookpkg.BypassVerb+" imagescans in the namespace (env: AQUA_AUTHORIZE_BYPASS)")
+	pflag.String("bypass-exempt-service-accounts", "", "Comma-separated namespace/name service accounts that may always bypass scanning (env: AQUA_BYPASS_EXEMPT_SERVICE_ACCOUNTS)")
+	pflag.String("bypass-exempt-groups", "", "Comma-separated groups that may always bypass scanning (env: AQUA_BYPASS_EXEMPT_GROUPS)")
+	pflag.String("enforcement-mode", string(enforcement.Enforce), "Default enforcement mode: enforce, warn or audit; namespaces override it with the "+enforcement.LabelMode+" label (env: AQUA_ENFORCEMENT_MODE)")
 	pflag.String("scan-namespace", "", "Namespace for ImageScan CRs (env: AQUA_SCAN_NAMESPACE)")
-	pflag.Duration("rescan-interval", 24*time.Hour, "Rescan interval (env: AQUA_RESCAN_INTERVAL)")
+	pflag.Bool("cluster-scans", false, "Gate pods on one cluster-scoped ClusterImageScan per digest (env: AQUA_CLUSTER_SCANS)")
+	pflag.Bool("cluster-scan-references", false, "With --cluster-scans, also create namespaced ImageScans referencing the ClusterImageScan (env: AQUA_CLUSTER_SCAN_REFERENCES)")
+	pflag.Duration("rescan-interval", 24*time.Hour, "Interval for refreshing completed scan results from Aqua, 0 disables (env: AQUA_RESCAN_INTERVAL)")
+	pflag.Int("max-scan-retries", 5, "Retries for a failing scan before it is marked Failed, 0 retries forever (env: AQUA_MAX_SCAN_RETRIES)")
+	pflag.Duration("scan-poll-initial-delay", controller.DefaultScanPollInitialDelay, "Delay before the first poll of a triggered scan (env: AQUA_SCAN_POLL_INITIAL_DELAY)")
+	pflag.Duration("scan-poll-interval", controller.DefaultScanPollInterval, "Interval between polls of a pending scan (env: AQUA_SCAN_POLL_INTERVAL)")
+	pflag.Duration("scan-timeout", 30*time.Minute, "How long a triggered scan may stay pending before it times out, 0 disables (env: AQUA_SCAN_TIMEOUT)")
+	pflag.Bool("scan-reports", true, "Store per-vulnerability details in ImageScanReports (env: AQUA_SCAN_REPORTS)")
+	pflag.Int("report-chunk-size", controller.DefaultReportChunkSize, "Vulnerabilities per ImageScanReport (env: AQUA_REPORT_CHUNK_SIZE)")
+	pflag.Bool("assurance-policies", false, "Fail images disallowed by Aqua assurance policies and pass the rest (env: AQUA_ASSURANCE_POLICIES)")
+	pflag.String("vex-files", "", "Comma-separated OpenVEX files or directories of .json files (env: AQUA_VEX_FILES)")
+	pflag.String("vex-namespace", "", "Namespace of ConfigMaps labelled "+controller.LabelOpenVEX+"=true holding OpenVEX documents, empty disables (env: AQUA_VEX_NAMESPACE)")
 	pflag.String("registry-mirrors", "", "Registry mirror mappings (env: AQUA_REGISTRY_MIRRORS)")
+	pflag.String("image-policy-file", "", "YAML file with the registry allowlist and image reference policy, empty disables (env: AQUA_IMAGE_POLICY_FILE)")
+	pflag.Duration("image-policy-reload-interval", reloadfile.DefaultInterval, "Interval between checks of the image policy file for changes (env: AQUA_IMAGE_POLICY_RELOAD_INTERVAL)")
+	pflag.String("excluded-images", "", "Comma-separated images exempt from scanning: globs, "+imageexclude.PrefixRegex+"<expression>, "+imageexclude.PrefixRepository+"<registry[/repository]> or sha256:<digest> (env: AQUA_EXCLUDED_IMAGES)")
+	pflag.String("excluded-images-file", "", "YAML file with images exempt from scanning, reloaded when it changes (env: AQUA_EXCLUDED_IMAGES_FILE)")
+	pflag.Duration("gc-ttl", 24*time.Hour, "Delete ImageScans no pod has referenced for this long, 0 disables (env: AQUA_GC_TTL)")
+	pflag.Duration("gc-interval", 10*time.Minute, "Interval between ImageScan garbage collection runs (env: AQUA_GC_INTERVAL)")
+	pflag.Bool("gc-workload-templates", false, "Also keep ImageScans referenced by workload pod templates (env: AQUA_GC_WORKLOAD_TEMPLATES)")
+	pflag.Duration("fast-path-max-age", 0, "Admit pods without the gate when every image's scan was approved within this age, 0 disables (env: AQUA_FAST_PATH_MAX_AGE)")
+	pflag.Bool("pin-digests", false, "Rewrite pod images to the scanned repo:tag@digest at admission (env: AQUA_PIN_DIGESTS)")
+	pflag.Bool("prescan-workloads", false, "Scan Deployment, StatefulSet, DaemonSet, Job and CronJob pod templates when their images change, and annotate the workloads with the verdict (env: AQUA_PRESCAN_WORKLOADS)")
+	pflag.Int("admission-scan-workers", 4, "Workers starting the scans of gated pods at admission, 0 leaves it to the Pod gate controller (env: AQUA_ADMISSION_SCAN_WORKERS)")
+	pflag.Duration("digest-cache-ttl", imageref.DefaultDigestCacheTTL, "How long resolved tag digests are cached (env: AQUA_DIGEST_CACHE_TTL)")
 
 	// Tracing flags - tracing is enabled when endpoint is provided
 	// These use explicit BindEnv to support OTEL standardized env var names
@@ -95,9 +136,38 @@ func main() {
 	aquaAPIKey := viper.GetString("api-key")
 	aquaHMACSecret := viper.GetString("hmac-secret")
 	excludedNamespaces := viper.GetString("excluded-namespaces")
+	namespaceSelector := viper.GetString("namespace-selector")
+	excludedNamespaceSelector := viper.GetString("excluded-namespace-selector")
+	enforcementMode := viper.GetString("enforcement-mode")
+	authorizeBypass := viper.GetBool("authorize-bypass")
+	bypassExemptServiceAccounts := viper.GetString("bypass-exempt-service-accounts")
+	bypassExemptGroups := viper.GetString("bypass-exempt-groups")
 	scanNamespace := viper.GetString("scan-namespace")
+	clusterScans := viper.GetBool("cluster-scans")
+	clusterScanReferences := viper.GetBool("cluster-scan-references")
 	rescanInterval := viper.GetDuration("rescan-interval")
+	maxScanRetries := viper.GetInt("max-scan-retries")
+	scanPollInitialDelay := viper.GetDuration("scan-poll-initial-delay")
+	scanPollInterval := viper.GetDuration("scan-poll-interval")
+	scanTimeout := viper.GetDuration("scan-timeout")
+	scanReports := viper.GetBool("scan-reports")
+	reportChunkSize := viper.GetInt("report-chunk-size")
+	assurancePolicies := viper.GetBool("assurance-policies")
+	vexFiles := viper.GetString("vex-files")
+	vexNamespace := viper.GetString("vex-namespace")
 	registryMirrors := viper.GetString("registry-mirrors")
+	imagePolicyFile := viper.GetString("image-policy-file")
+	imagePolicyReloadInterval := viper.GetDuration("image-policy-reload-interval")
+	excludedImages := viper.GetString("excluded-images")
+	excludedImagesFile := viper.GetString("excluded-images-file")
+	digestCacheTTL := viper.GetDuration("digest-cache-ttl")
+	fastPathMaxAge := viper.GetDuration("fast-path-max-age")
+	pinDigests := viper.GetBool("pin-digests")
+	admissionScanWorkers := viper.GetInt("admission-scan-workers")
+	prescanWorkloads := viper.GetBool("prescan-workloads")
+	gcTTL := viper.GetDuration("gc-ttl")
+	gcInterval := viper.GetDuration("gc-interval")
+	gcWorkloadTemplates := viper.GetBool("gc-workload-templates")
 	tracingEndpoint := viper.GetString("tracing-endpoint")
 	tracingProtocol := viper.GetString("tracing-protocol")
 	tracingSampleRatio := viper.GetFloat64("tracing-sample-ratio")
@@ -139,6 +209,16 @@ func main() {
 		}
 	}
 
+	// Parse the default enforcement mode
+	defaultMode, err := enforcement.ParseMode(enforcementMode)
+	if err != nil {
+		setupLog.Error(err, "invalid enforcement mode")
+		os.Exit(1)
+	}
+	if defaultMode != enforcement.Enforce {
+		setupLog.Info("pods are audited instead of gated by default", "mode", defaultMode)
+	}
+
 	// Parse registry mirrors
 	mirrors, err := aqua.ParseRegistryMirrors(registryMirrors)
 	if err != nil {
@@ -152,6 +232,58 @@ func main() {
 		}
 	}
 
+	// Load the image reference policy; it is reloaded when the file changes
+	var imagePolicy *imagepolicy.FileSource
+	if imagePolicyFile != "" {
+		if imagePolicy, err = imagepolicy.NewFileSource(imagePolicyFile, imagePolicyReloadInterval); err != nil {
+			setupLog.Error(err, "failed to load image policy")
+			os.Exit(1)
+		}
+		setupLog.Info("loaded image policy", "path", imagePolicyFile)
+	}
+
+	// Load the images exempt from scanning; the file is reloaded when it changes
+	excludedImageList, err := imageexclude.ParseList(excludedImages)
+	if err != nil {
+		setupLog.Error(err, "invalid excluded images")
+		os.Exit(1)
+	}
+	imageExclusions, err := imageexclude.New(excludedImageList, excludedImagesFile, reloadfile.DefaultInterval)
+	if err != nil {
+		setupLog.Error(err, "failed to load image exclusions")
+		os.Exit(1)
+	}
+	if imageExclusions.Len() > 0 {
+		setupLog.Info("images exempt from scanning", "count", imageExclusions.Len())
+	}
+
+	// Load OpenVEX documents from files
+	var vexPaths []string
+	for _, path := range strings.Split(vexFiles, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			vexPaths = append(vexPaths, path)
+		}
+	}
+	vexDocuments, err := vex.LoadFiles(vexPaths)
+	if err != nil {
+		setupLog.Error(err, "failed to load OpenVEX documents")
+		os.Exit(1)
+	}
+	if len(vexDocuments) > 0 {
+		setupLog.Info("loaded OpenVEX documents", "count", len(vexDocuments))
+	}
+
+	// Only OpenVEX ConfigMaps are read, so only those are cached
+	cacheOptions := cache.Options{}
+	if vexNamespace != "" {
+		cacheOptions.ByObject = map[client.Object]cache.ByObject{
+			&corev1.ConfigMap{}: {
+				Namespaces: map[string]cache.Config{vexNamespace: {}},
+				Label:      labels.SelectorFromSet(labels.Set{controller.LabelOpenVEX: "true"}),
+			},
+		}
+	}
+
 	// Create Aqua client
 	aquaClient := aqua.NewClient(aqua.Config{
 		BaseURL: aquaURL,
@@ -172,6 +304,7 @@ func main() {
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "aqua-scan-gate.security.example.com",
 		HealthProbeBindAddress: probeAddr,
+		Cache:                  cacheOptions,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -179,35 +312,189 @@ func main() {
 	}
 
 	// Setup ImageScan controller
-	if err = (&controller.ImageScanReconciler{
-		Client:         mgr.GetClient(),
-		Scheme:         mgr.GetScheme(),
-		AquaClient:     aquaClient,
-		RescanInterval: rescanInterval,
-	}).SetupWithManager(mgr); err != nil {
+	imageScanReconciler := controller.ImageScanReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		AquaClient:           aquaClient,
+		RescanInterval:       rescanInterval,
+		MaxRetries:           maxScanRetries,
+		ScanPollInitialDelay: scanPollInitialDelay,
+		ScanPollInterval:     scanPollInterval,
+		ScanTimeout:          scanTimeout,
+		Reports:              scanReports,
+		ReportChunkSize:      reportChunkSize,
+		VEXDocuments:         vexDocuments,
+		VEXNamespace:         vexNamespace,
+		AssurancePolicies:    assurancePolicies,
+		VulnerabilityCache:   controller.NewVulnerabilityCache(0),
+	}
+	if err = (&imageScanReconciler).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ImageScan")
 		os.Exit(1)
 	}
 
-	// Setup Pod gate controller
-	if err = (&controller.PodGateReconciler{
-		Client:             mgr.GetClient(),
-		Scheme:             mgr.GetScheme(),
-		Recorder:           mgr.GetEventRecorderFor("aqua-scan-gate"),
-		ScanNamespace:      scanNamespace,
-		ExcludedNamespaces: excludedNS,
+	// Setup ClusterImageScan controller with the same scan settings
+	if err = (&controller.ClusterImageScanReconciler{
+		ImageScanReconciler: imageScanReconciler,
 	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterImageScan")
+		os.Exit(1)
+	}
+
+	// Setup ScanException controller
+	if err = (&controller.ScanExceptionReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("aqua-scan-gate"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ScanException")
+		os.Exit(1)
+	}
+
+	// Tag digests resolved by the Pod gate controller are shared with ImageScan garbage collection
+	digestResolver := imageref.NewCachingResolver(imageref.NewResolver(), digestCacheTTL)
+
+	// Namespace exclusions and enforcement modes are read from the Namespace informer
+	// cache, so relabelling a namespace takes effect without a restart
+	if _, err := mgr.GetCache().GetInformer(context.Background(), &corev1.Namespace{}); err != nil {
+		setupLog.Error(err, "unable to set up Namespace informer")
+		os.Exit(1)
+	}
+	namespaces, err := scope.NewNamespaces(mgr.GetClient(), excludedNS, namespaceSelector, excludedNamespaceSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid namespace selector")
+		os.Exit(1)
+	}
+	enforcementModes := &enforcement.Modes{Reader: mgr.GetClient(), Default: defaultMode}
+
+	// Setup Pod gate controller
+	podGateReconciler := &controller.PodGateReconciler{
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		Recorder:              mgr.GetEventRecorderFor("aqua-scan-gate"),
+		ScanNamespace:         scanNamespace,
+		Namespaces:            namespaces,
+		Resolver:              digestResolver,
+		APIReader:             mgr.GetAPIReader(),
+		ClusterScans:          clusterScans,
+		ClusterScanReferences: clusterScanReferences,
+		Enforcement:           enforcementModes,
+		Exclusions:            imageExclusions,
+	}
+	if err = podGateReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PodGate")
 		os.Exit(1)
 	}
 
-	// Setup webhook
-	mgr.GetWebhookServer().Register("/mutate-v1-pod", &webhook.Admission{
-		Handler: &webhookpkg.PodMutator{
-			Client:             mgr.GetClient(),
-			ExcludedNamespaces: excludedNS,
+	// Setup workload template pre-scanning
+	if prescanWorkloads {
+		if err = (&controller.WorkloadScanReconciler{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor("aqua-scan-gate"),
+			Gate:     podGateReconciler,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "WorkloadScan")
+			os.Exit(1)
+		}
+	}
+
+	// Setup ImageScan garbage collection
+	if gcTTL > 0 {
+		if err := mgr.Add(&controller.ImageScanGarbageCollector{
+			Client:           mgr.GetClient(),
+			Resolver:         digestResolver,
+			ScanNamespace:    scanNamespace,
+			TTL:              gcTTL,
+			Interval:         gcInterval,
+			IncludeWorkloads: gcWorkloadTemplates,
+			ClusterScans:     clusterScans,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up ImageScan garbage collection")
+			os.Exit(1)
+		}
+	}
+
+	// Setup webhooks; both look up scan verdicts the way the Pod gate controller creates them
+	scanLookup := &webhookpkg.ScanLookup{
+		Client:                mgr.GetClient(),
+		ResolvesTags:          true,
+		ScanNamespace:         scanNamespace,
+		ClusterScans:          clusterScans,
+		ClusterScanReferences: clusterScanReferences,
+	}
+	podMutator := &webhookpkg.PodMutator{
+		Client:         mgr.GetClient(),
+		Namespaces:     namespaces,
+		Scans:          scanLookup,
+		FastPathMaxAge: fastPathMaxAge,
+		PinDigests:     pinDigests,
+		Resolver:       digestResolver,
+		APIReader:      mgr.GetAPIReader(),
+		Enforcement:    enforcementModes,
+		Exclusions:     imageExclusions,
+	}
+	if admissionScanWorkers > 0 {
+		// Scans of gated pods are created by the webhook's replica, leader or not
+		podMutator.Prefetcher = webhookpkg.NewScanPrefetcher(podGateReconciler, admissionScanWorkers, 100*admissionScanWorkers)
+		if err := mgr.Add(podMutator.Prefetcher); err != nil {
+			setupLog.Error(err, "unable to set up admission scan prefetching")
+			os.Exit(1)
+		}
+	}
+	if err := podMutator.InjectDecoder(admission.NewDecoder(mgr.GetScheme())); err != nil {
+		setupLog.Error(err, "unable to set up pod webhook decoder")
+		os.Exit(1)
+	}
+	mgr.GetWebhookServer().Register("/mutate-v1-pod", &webhook.Admission{Handler: podMutator})
+	var bypassAuthorizer *webhookpkg.BypassAuthorizer
+	if authorizeBypass {
+		bypassAuthorizer = &webhookpkg.BypassAuthorizer{
+			Client:                mgr.GetClient(),
+			Reader:                mgr.GetAPIReader(),
+			ExemptServiceAccounts: webhookpkg.ParseExemptions(bypassExemptServiceAccounts),
+			ExemptGroups:          webhookpkg.ParseExemptions(bypassExemptGroups),
+		}
+	}
+	mgr.GetWebhookServer().Register("/validate-v1-pod", &webhook.Admission{
+		Handler: &webhookpkg.PodValidator{
+			Scans:           scanLookup,
+			ImagePolicy:     imagePolicy,
+			RegistryMirrors: mirrors,
+			Namespaces:      namespaces,
+			Enforcement:     enforcementModes,
+			Bypass:          bypassAuthorizer,
+			Exclusions:      imageExclusions,
 		},
 	})
+	// Bypass annotations on pod templates are authorized when the workload is admitted
+	mgr.GetWebhookServer().Register("/mutate-workload", &webhook.Admission{
+		Handler: &webhookpkg.WorkloadMutator{Namespaces: namespaces},
+	})
+	mgr.GetWebhookServer().Register("/validate-workload", &webhook.Admission{
+		Handler: &webhookpkg.WorkloadValidator{Namespaces: namespaces, Bypass: bypassAuthorizer},
+	})
+	if imagePolicy != nil {
+		if err := mgr.Add(imagePolicy); err != nil {
+			setupLog.Error(err, "unable to set up image policy reloading")
+			os.Exit(1)
+		}
+	}
+	if excludedImagesFile != "" {
+		if err := mgr.Add(imageExclusions); err != nil {
+			setupLog.Error(err, "unable to set up image exclusions reloading")
+			os.Exit(1)
+		}
+	}
+	if err := mgr.Add(digestResolver); err != nil {
+		setupLog.Error(err, "unable to set up digest cache expiry")
+		os.Exit(1)
+	}
+	mgr.GetWebhookServer().Register("/validate-scans-aquasec-community-v1alpha1-scanpolicy", &webhook.Admission{
+		Handler: &webhookpkg.ScanPolicyValidator{},
+	})
+	mgr.GetWebhookServer().Register("/mutate-scans-aquasec-community-v1alpha1-scanexception", &webhook.Admission{
+		Handler: &webhookpkg.ScanExceptionMutator{},
+	})
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+// IndexFieldClusterScanRef is the field name for the ImageScan index by ClusterImageScan reference
+const IndexFieldClusterScanRef = "spec.clusterScanRef"
+
+// ClusterImageScanReconciler reconciles a ClusterImageScan object with the same scan
+// logic as ImageScanReconciler. Only ClusterScanPolicies apply to ClusterImageScans.
+type ClusterImageScanReconciler struct {
+	ImageScanReconciler
+}
+
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=clusterimagescans,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=clusterimagescans/status,verbs=get;update;patch
+
+func (r *ClusterImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.StartSpan(ctx, "ClusterImageScanReconciler.Reconcile",
+		trace.WithAttributes(
+			attribute.String("clusterimagescan.name", req.Name),
+		),
+	)
+	defer span.End()
+
+	var clusterScan securityv1alpha1.ClusterImageScan
+	if err := r.Get(ctx, req.NamespacedName, &clusterScan); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	span.SetAttributes(
+		tracing.AttrImageName.String(clusterScan.Spec.Image),
+		tracing.AttrImageDigest.String(clusterScan.Spec.Digest),
+	)
+
+	return r.reconcileScan(ctx, &clusterScan)
+}
+
+func (r *ClusterImageScanReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		// Status updates must not trigger reconciles, or Error retries would skip their backoff
+		For(&securityv1alpha1.ClusterImageScan{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&securityv1alpha1.ClusterScanPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToClusterImageScans),
+		).
+		Watches(
+			&securityv1alpha1.ScanException{},
+			handler.EnqueueRequestsFromMapFunc(r.mapExceptionToClusterImageScans),
+		).
+		// Pods are evaluated against the rules of their namespace's ScanPolicies using the
+		// fixable counts of their ClusterImageScans
+		Watches(
+			&securityv1alpha1.ScanPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToClusterImageScans),
+			builder.WithPredicates(predicate.NewPredicateFuncs(hasRules)),
+		)
+
+	if r.VEXNamespace != "" {
+		bldr = bldr.Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToClusterImageScans),
+			builder.WithPredicates(r.isVEXConfigMap()),
+		)
+	}
+
+	return bldr.Complete(r)
+}
+
+// mapPolicyToClusterImageScans re-evaluates every ClusterImageScan with results when a
+// ClusterScanPolicy, a ScanPolicy with rules or an OpenVEX ConfigMap changes.
+func (r *ClusterImageScanReconciler) mapPolicyToClusterImageScans(ctx context.Context, _ client.Object) []reconcile.Request {
+	var clusterScans securityv1alpha1.ClusterImageScanList
+	if err := r.List(ctx, &clusterScans); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ClusterImageScans for policy mapping")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range clusterScans.Items {
+		if !hasScanResults(&clusterScans.Items[i]) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: clusterScans.Items[i].Name},
+		})
+	}
+	return requests
+}
+
+// reconcileReference mirrors the ClusterImageScan an ImageScan references and applies the
+// ScanPolicies of the ImageScan's namespace on top of the copied results. It never triggers
+// scans in Aqua, so tenants see the verdict for their images without duplicate scans; Aqua
+// is only asked for per-CVE details when a ScanException waives specific CVEs.
+func (r *ImageScanReconciler) reconcileReference(ctx context.Context, imageScan *securityv1alpha1.ImageScan) (ctrl.Result, error) {
+	ctx, span := tracing.StartSpan(ctx, "ImageScanReconciler.reconcileReference",
+		trace.WithAttributes(
+			attribute.String("clusterimagescan.name", imageScan.Spec.ClusterScanRef),
+		),
+	)
+	defer span.End()
+
+	logger := log.FromContext(ctx)
+	previous := imageScan.Status.DeepCopy()
+
+	var clusterScan securityv1alpha1.ClusterImageScan
+	err := r.Get(ctx, types.NamespacedName{Name: imageScan.Spec.ClusterScanRef}, &clusterScan)
+	switch {
+	case apierrors.IsNotFound(err):
+		imageScan.Status.Phase = securityv1alpha1.ScanPhasePending
+		imageScan.Status.Message = fmt.Sprintf("Waiting for ClusterImageScan %s", imageScan.Spec.ClusterScanRef)
+		setPendingConditions(imageScan, imageScan.Status.Message)
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get ClusterImageScan")
+		return ctrl.Result{}, err
+	default:
+		mirrorClusterImageScan(imageScan, &clusterScan)
+		if hasScanResults(&clusterScan) {
+			if err := r.applyPolicies(ctx, imageScan, nil); err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "Failed to evaluate scan policies")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	span.SetAttributes(tracing.AttrScanPhase.String(string(imageScan.Status.Phase)))
+	if !equality.Semantic.DeepEqual(*previous, imageScan.Status) {
+		logger.V(1).Info("Updating ImageScan from ClusterImageScan",
+			"imageScan", imageScan.Name,
+			"clusterImageScan", imageScan.Spec.ClusterScanRef,
+			"phase", imageScan.Status.Phase)
+		if err := r.Status().Update(ctx, imageScan); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// mirrorClusterImageScan copies the status of a ClusterImageScan into a referencing ImageScan.
+// Conditions are merged so that transition times only move when a condition changes. When the
+// cluster scan has results, PolicyEvaluated and Ready are left for the namespace's own
+// policy evaluation.
+func mirrorClusterImageScan(imageScan *securityv1alpha1.ImageScan, clusterScan *securityv1alpha1.ClusterImageScan) {
+	evaluateLocally := hasScanResults(clusterScan)
+	ownCondition := func(condType string) bool {
+		return evaluateLocally &&
+			(condType == securityv1alpha1.ConditionPolicyEvaluated || condType == securityv1alpha1.ConditionReady)
+	}
+
+	conditions := imageScan.Status.Conditions
+	imageScan.Status = *clusterScan.Status.DeepCopy()
+	imageScan.Status.Conditions = conditions
+
+	for _, cond := range clusterScan.Status.Conditions {
+		if ownCondition(cond.Type) {
+			continue
+		}
+		setCondition(imageScan, cond.Type, cond.Status, cond.Reason, cond.Message)
+	}
+	for _, cond := range conditions {
+		if !ownCondition(cond.Type) && meta.FindStatusCondition(clusterScan.Status.Conditions, cond.Type) == nil {
+			meta.RemoveStatusCondition(&imageScan.Status.Conditions, cond.Type)
+		}
+	}
+}
+
+// mapClusterImageScanToReferences enqueues the ImageScans that mirror a ClusterImageScan.
+func (r *ImageScanReconciler) mapClusterImageScanToReferences(ctx context.Context, obj client.Object) []reconcile.Request {
+	var imageScans securityv1alpha1.ImageScanList
+	if err := r.List(ctx, &imageScans, client.MatchingFields{
+		IndexFieldClusterScanRef: obj.GetName(),
+	}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ImageScans for ClusterImageScan mapping")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(imageScans.Items))
+	for _, imageScan := range imageScans.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      imageScan.Name,
+				Namespace: imageScan.Namespace,
+			},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+)
+
+var _ = Describe("ClusterImageScanReconciler", func() {
+	const scanName = "sha256-0123456789abcdef"
+
+	var (
+		scheme *runtime.Scheme
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		ctx = context.Background()
+	})
+
+	newClusterScan := func(phase securityv1alpha1.ScanPhase, vulns *securityv1alpha1.VulnerabilitySummary) *securityv1alpha1.ClusterImageScan {
+		return &securityv1alpha1.ClusterImageScan{
+			ObjectMeta: metav1.ObjectMeta{Name: scanName},
+			Spec: securityv1alpha1.ImageScanSpec{
+				Image:  "nginx:1.25",
+				Digest: testDigest,
+			},
+			Status: securityv1alpha1.ImageScanStatus{
+				Phase:           phase,
+				Vulnerabilities: vulns,
+			},
+		}
+	}
+
+	newReference := func(namespace string) *securityv1alpha1.ImageScan {
+		return &securityv1alpha1.ImageScan{
+			ObjectMeta: metav1.ObjectMeta{Name: scanName, Namespace: namespace},
+			Spec: securityv1alpha1.ImageScanSpec{
+				Image:          "nginx:1.25",
+				Digest:         testDigest,
+				ClusterScanRef: scanName,
+			},
+		}
+	}
+
+	clusterScanRefIndexer := func(obj client.Object) []string {
+		imageScan, ok := obj.(*securityv1alpha1.ImageScan)
+		if !ok || imageScan.Spec.ClusterScanRef == "" {
+			return nil
+		}
+		return []string{imageScan.Spec.ClusterScanRef}
+	}
+
+	It("should scan the image and apply only ClusterScanPolicies", func() {
+		clusterScan := newClusterScan(securityv1alpha1.ScanPhasePending, nil)
+		clusterPolicy := &securityv1alpha1.ClusterScanPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "baseline"},
+			Spec:       securityv1alpha1.ScanPolicySpec{MaxCritical: intPtr(0)},
+		}
+		nsPolicy := &securityv1alpha1.ScanPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "relaxed", Namespace: "default"},
+			Spec:       securityv1alpha1.ScanPolicySpec{MaxCritical: intPtr(5)},
+		}
+		fakeClient := fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(clusterScan, clusterPolicy, nsPolicy).
+			WithStatusSubresource(clusterScan).
+			Build()
+
+		r := &ClusterImageScanReconciler{ImageScanReconciler{
+			Client: fakeClient,
+			Scheme: scheme,
+			AquaClient: &fakeAquaClient{
+				result: &aqua.ScanResult{
+					Status:          aqua.StatusFound,
+					Vulnerabilities: aqua.VulnerabilityCounts{Critical: 1},
+				},
+			},
+		}}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: scanName}})
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated securityv1alpha1.ClusterImageScan
+		Expect(fakeClient.Get(ctx, types.NamespacedName{Name: scanName}, &updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+		Expect(updated.Status.Policy).To(Equal("ClusterScanPolicy/baseline"))
+	})
+
+	Describe("namespaced references", func() {
+		reconcileReference := func(c client.Client, aquaClient *fakeAquaClient) *securityv1alpha1.ImageScan {
+			r := &ImageScanReconciler{Client: c, Scheme: scheme, AquaClient: aquaClient}
+			key := types.NamespacedName{Name: scanName, Namespace: "team-a"}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated securityv1alpha1.ImageScan
+			Expect(c.Get(ctx, key, &updated)).To(Succeed())
+			return &updated
+		}
+
+		It("should wait for the ClusterImageScan to exist", func() {
+			reference := newReference("team-a")
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(reference).
+				WithStatusSubresource(reference).
+				Build()
+
+			aquaClient := &fakeAquaClient{}
+			updated := reconcileReference(fakeClient, aquaClient)
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePending))
+			Expect(updated.Status.Message).To(ContainSubstring(scanName))
+			Expect(aquaClient.getCalls + aquaClient.triggerCalls).To(BeZero())
+		})
+
+		It("should mirror the cluster results and apply the namespace's ScanPolicies", func() {
+			clusterScan := newClusterScan(securityv1alpha1.ScanPhaseRegistered,
+				&securityv1alpha1.VulnerabilitySummary{High: 3})
+			clusterScan.Status.Conditions = []metav1.Condition{{
+				Type:   securityv1alpha1.ConditionScanCompleted,
+				Status: metav1.ConditionTrue,
+				Reason: securityv1alpha1.ReasonScanFinished,
+			}}
+			reference := newReference("team-a")
+			nsPolicy := &securityv1alpha1.ScanPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "strict", Namespace: "team-a"},
+				Spec:       securityv1alpha1.ScanPolicySpec{MaxHigh: intPtr(0)},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(clusterScan, reference, nsPolicy).
+				WithStatusSubresource(reference).
+				Build()
+
+			aquaClient := &fakeAquaClient{}
+			updated := reconcileReference(fakeClient, aquaClient)
+			Expect(updated.Status.Vulnerabilities).To(Equal(clusterScan.Status.Vulnerabilities))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+			Expect(updated.Status.Policy).To(Equal("ScanPolicy/team-a/strict"))
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, securityv1alpha1.ConditionScanCompleted)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(updated.Status.Conditions, securityv1alpha1.ConditionReady)).To(BeTrue())
+			Expect(aquaClient.getCalls + aquaClient.triggerCalls).To(BeZero())
+		})
+
+		It("should enqueue the references of a ClusterImageScan", func() {
+			unrelated := newReference("team-c")
+			unrelated.Spec.ClusterScanRef = ""
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(newReference("team-a"), newReference("team-b"), unrelated).
+				WithIndex(&securityv1alpha1.ImageScan{}, IndexFieldClusterScanRef, clusterScanRefIndexer).
+				Build()
+			r := &ImageScanReconciler{Client: fakeClient, Scheme: scheme}
+
+			requests := r.mapClusterImageScanToReferences(ctx, newClusterScan("", nil))
+			Expect(requests).To(ConsistOf(
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: scanName, Namespace: "team-a"}},
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: scanName, Namespace: "team-b"}},
+			))
+		})
+	})
+})
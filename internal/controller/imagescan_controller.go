@@ -2,25 +2,47 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
 	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+	"github.com/richardmsong/aqua-scan-gate/pkg/policy"
 	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+	"github.com/richardmsong/aqua-scan-gate/pkg/vex"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
 	baseBackoff = 30 * time.Second
 	maxBackoff  = 10 * time.Minute
+
+	// rescanJitterFactor spreads rescans of ImageScans by up to 10% of the interval
+	rescanJitterFactor = 0.1
+
+	// DefaultScanPollInitialDelay is how long to wait after triggering a scan before the first poll
+	DefaultScanPollInitialDelay = 10 * time.Second
+	// DefaultScanPollInterval is how often Aqua is polled while a scan is pending
+	DefaultScanPollInterval = 30 * time.Second
 )
 
 // calculateBackoff returns an exponential backoff duration based on retry count.
@@ -36,17 +58,52 @@ func calculateBackoff(retryCount int) time.Duration {
 	return backoff
 }
 
+// scanObject is an ImageScan or a ClusterImageScan.
+type scanObject interface {
+	client.Object
+	ScanSpec() *securityv1alpha1.ImageScanSpec
+	ScanStatus() *securityv1alpha1.ImageScanStatus
+}
+
 // ImageScanReconciler reconciles a ImageScan object
 type ImageScanReconciler struct {
 	client.Client
 	Scheme         *runtime.Scheme
 	AquaClient     aqua.Client
 	RescanInterval time.Duration
+	// MaxRetries is how many times a failing scan is retried before it is marked Failed.
+	// Zero retries forever.
+	MaxRetries int
+	// ScanPollInitialDelay and ScanPollInterval schedule polls of pending scans.
+	// Zero uses DefaultScanPollInitialDelay and DefaultScanPollInterval.
+	ScanPollInitialDelay time.Duration
+	ScanPollInterval     time.Duration
+	// ScanTimeout is how long a triggered scan may stay pending before it times out.
+	// Zero disables the timeout.
+	ScanTimeout time.Duration
+	// Reports stores per-vulnerability details in ImageScanReports whenever results are fetched
+	Reports bool
+	// ReportChunkSize is the number of vulnerabilities per ImageScanReport.
+	// Zero uses DefaultReportChunkSize.
+	ReportChunkSize int
+	// VEXDocuments are OpenVEX documents loaded from files
+	VEXDocuments []vex.Document
+	// VEXNamespace is the namespace of the ConfigMaps holding OpenVEX documents.
+	// Empty disables reading them.
+	VEXNamespace string
+	// AssurancePolicies uses Aqua's assurance policy verdict: images Aqua disallows are
+	// Failed, and images without applicable ScanPolicies are Passed instead of Registered
+	AssurancePolicies bool
+	// VulnerabilityCache caches the per-CVE details of scans without ImageScanReports,
+	// shared by the ImageScan and ClusterImageScan controllers (nil = fetch them every time)
+	VulnerabilityCache *VulnerabilityCache
 }
 
 // +kubebuilder:rbac:groups=scans.aquasec.community,resources=imagescans,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=scans.aquasec.community,resources=imagescans/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=scans.aquasec.community,resources=imagescans/finalizers,verbs=update
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=scanpolicies;clusterscanpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=scanexceptions,verbs=get;list;watch
 
 func (r *ImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	ctx, span := tracing.StartSpan(ctx, "ImageScanReconciler.Reconcile",
@@ -57,8 +114,6 @@ func (r *ImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	)
 	defer span.End()
 
-	logger := log.FromContext(ctx)
-
 	var imageScan securityv1alpha1.ImageScan
 	if err := r.Get(ctx, req.NamespacedName, &imageScan); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
@@ -70,93 +125,752 @@ func (r *ImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		tracing.AttrImageDigest.String(imageScan.Spec.Digest),
 	)
 
-	// Error state is terminal - don't attempt to reconcile
-	if imageScan.Status.Phase == securityv1alpha1.ScanPhaseError {
-		span.SetAttributes(tracing.AttrScanPhase.String(string(securityv1alpha1.ScanPhaseError)))
-		logger.Info("ImageScan in error state, not reconciling",
-			"image", imageScan.Spec.Image,
-			"message", imageScan.Status.Message)
-		return ctrl.Result{}, nil
+	if imageScan.Spec.ClusterScanRef != "" {
+		return r.reconcileReference(ctx, &imageScan)
 	}
+	return r.reconcileScan(ctx, &imageScan)
+}
+
+// reconcileScan drives an ImageScan or ClusterImageScan through scanning in Aqua and
+// scan policy evaluation.
+func (r *ImageScanReconciler) reconcileScan(ctx context.Context, scan scanObject) (ctrl.Result, error) {
+	span := trace.SpanFromContext(ctx)
+	logger := log.FromContext(ctx)
+	spec, status := scan.ScanSpec(), scan.ScanStatus()
 
-	// If already registered, no need to rescan
-	if imageScan.Status.Phase == securityv1alpha1.ScanPhaseRegistered {
-		span.SetAttributes(tracing.AttrScanPhase.String(string(securityv1alpha1.ScanPhaseRegistered)))
+	// Retries exhausted is terminal - the ImageScan has to be deleted to try again
+	if retriesExhausted(scan) {
+		span.SetAttributes(tracing.AttrScanPhase.String(string(securityv1alpha1.ScanPhaseFailed)))
+		logger.Info("ImageScan failed after retries, not reconciling",
+			"image", spec.Image,
+			"message", status.Message)
 		return ctrl.Result{}, nil
 	}
 
+	// Already scanned: refresh the results from Aqua once the rescan interval has
+	// elapsed. Otherwise scan policies may have changed since the last evaluation,
+	// so re-evaluate the stored results
+	if isCompletedPhase(status.Phase) {
+		if r.RescanInterval > 0 && r.untilRescan(scan) <= 0 {
+			return r.rescan(ctx, scan)
+		}
+
+		previous := status.DeepCopy()
+		if err := r.applyPolicies(ctx, scan, nil); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to evaluate scan policies")
+			return ctrl.Result{}, err
+		}
+		span.SetAttributes(tracing.AttrScanPhase.String(string(status.Phase)))
+		if !equality.Semantic.DeepEqual(*previous, *status) {
+			logger.Info("Scan policy verdict changed",
+				"image", spec.Image,
+				"from", previous.Phase,
+				"to", status.Phase)
+			if updateErr := r.Status().Update(ctx, scan); updateErr != nil {
+				return ctrl.Result{}, updateErr
+			}
+		}
+		return r.rescanResult(scan), nil
+	}
+
 	// Check current scan status in Aqua
 	// With v2 API: not 404 = image is scanned and ready
-	result, err := r.AquaClient.GetScanResult(ctx, imageScan.Spec.Image, imageScan.Spec.Digest)
+	polling := status.Phase == securityv1alpha1.ScanPhasePending && status.TriggeredTime != nil
+	result, err := r.AquaClient.GetScanResult(ctx, spec.Image, spec.Digest)
+	if polling {
+		status.PollCount++
+		span.SetAttributes(attribute.Int("poll_count", status.PollCount))
+	}
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to get scan result from Aqua")
 		logger.Error(err, "Failed to get scan result from Aqua")
-		imageScan.Status.Phase = securityv1alpha1.ScanPhaseError
-		imageScan.Status.Message = err.Error()
-		imageScan.Status.RetryCount++
-		if updateErr := r.Status().Update(ctx, &imageScan); updateErr != nil {
-			logger.Error(updateErr, "Failed to update ImageScan status")
-		}
-		return ctrl.Result{RequeueAfter: calculateBackoff(imageScan.Status.RetryCount)}, nil
+		setAquaReachable(scan, err)
+		return r.recordError(ctx, scan, errorReason(err), err.Error())
 	}
 
+	setAquaReachable(scan, nil)
 	span.SetAttributes(tracing.AttrScanStatus.String(string(result.Status)))
 
 	switch result.Status {
 	case aqua.StatusNotFound:
+		// Already triggered - Aqua hasn't registered the image yet
+		if polling {
+			return r.waitForScan(ctx, scan, "Scan triggered, waiting for Aqua to process")
+		}
+
 		// Image not found in Aqua - trigger a new scan
-		logger.Info("Image not found in Aqua, triggering scan", "image", imageScan.Spec.Image, "digest", imageScan.Spec.Digest)
-		scanID, err := r.AquaClient.TriggerScan(ctx, imageScan.Spec.Image, imageScan.Spec.Digest)
+		logger.Info("Image not found in Aqua, triggering scan", "image", spec.Image, "digest", spec.Digest)
+		scanID, err := r.AquaClient.TriggerScan(ctx, spec.Image, spec.Digest)
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "Failed to trigger scan")
 			logger.Error(err, "Failed to trigger scan")
-			imageScan.Status.Phase = securityv1alpha1.ScanPhaseError
-			imageScan.Status.Message = err.Error()
-			imageScan.Status.RetryCount++
-			if updateErr := r.Status().Update(ctx, &imageScan); updateErr != nil {
-				return ctrl.Result{}, updateErr
-			}
-			return ctrl.Result{RequeueAfter: calculateBackoff(imageScan.Status.RetryCount)}, nil
+			setAquaReachable(scan, err)
+			setCondition(scan, securityv1alpha1.ConditionScanTriggered, metav1.ConditionFalse,
+				errorReason(err), err.Error())
+			return r.recordError(ctx, scan, errorReason(err), err.Error())
 		}
 		span.SetAttributes(tracing.AttrScanID.String(scanID))
-		imageScan.Status.Phase = securityv1alpha1.ScanPhasePending
-		imageScan.Status.AquaScanID = scanID
-		imageScan.Status.Message = "Scan triggered, waiting for Aqua to process"
-		imageScan.Status.RetryCount = 0 // Reset retry count on success
-		if updateErr := r.Status().Update(ctx, &imageScan); updateErr != nil {
+		now := metav1.Now()
+		status.Phase = securityv1alpha1.ScanPhasePending
+		status.AquaScanID = scanID
+		status.Message = "Scan triggered, waiting for Aqua to process"
+		status.RetryCount = 0 // Reset retry count on success
+		status.TriggeredTime = &now
+		status.PollCount = 0
+		meta.RemoveStatusCondition(&status.Conditions, securityv1alpha1.ConditionTimedOut)
+		setCondition(scan, securityv1alpha1.ConditionScanTriggered, metav1.ConditionTrue,
+			securityv1alpha1.ReasonTriggered, fmt.Sprintf("Triggered Aqua scan %s", scanID))
+		setPendingConditions(scan, status.Message)
+		if updateErr := r.Status().Update(ctx, scan); updateErr != nil {
 			return ctrl.Result{}, updateErr
 		}
-		// Requeue immediately to check if the image has been registered
-		return ctrl.Result{Requeue: true}, nil
+		// Give Aqua time to register the image before the first poll
+		return ctrl.Result{RequeueAfter: r.pollInitialDelay()}, nil
 
 	case aqua.StatusFound:
-		// Image found in Aqua (not 404) - it's registered
-		// Aqua Enforcer will handle actual enforcement
+		status.AquaScanStatus = result.ScanStatus
+		if meta.FindStatusCondition(status.Conditions, securityv1alpha1.ConditionScanTriggered) == nil {
+			setCondition(scan, securityv1alpha1.ConditionScanTriggered, metav1.ConditionTrue,
+				securityv1alpha1.ReasonFoundInAqua, "Image was already registered in Aqua")
+		}
+
+		// Aqua knows the image but hasn't finished scanning it yet
+		if result.InProgress() {
+			if status.TriggeredTime == nil {
+				now := metav1.Now()
+				status.TriggeredTime = &now
+			}
+			return r.waitForScan(ctx, scan, fmt.Sprintf("Aqua scan in progress (%s)", result.ScanStatus))
+		}
+
+		if result.ScanFailed() {
+			span.SetStatus(codes.Error, "Aqua scan failed")
+			logger.Info("Aqua scan failed", "image", spec.Image, "error", result.ScanError)
+			message := fmt.Sprintf("Aqua scan failed: %s", result.ScanError)
+			setCondition(scan, securityv1alpha1.ConditionScanCompleted, metav1.ConditionFalse,
+				securityv1alpha1.ReasonScanFailed, message)
+			return r.recordError(ctx, scan, securityv1alpha1.ReasonScanFailed, message)
+		}
+
+		// Image scanned by Aqua - record the results
+		// Scan policies, if any, decide whether it passed
 		now := metav1.Now()
-		imageScan.Status.LastScanTime = &now
-		imageScan.Status.CompletedTime = &now
-		imageScan.Status.Phase = securityv1alpha1.ScanPhaseRegistered
-		imageScan.Status.Message = "Image registered in Aqua"
-		imageScan.Status.RetryCount = 0 // Reset retry count on success
+		recordScanResult(scan, result, now)
+		status.CompletedTime = &now
+		// Reports are informational - a failure is retried with the next rescan
+		vulnerabilities, err := r.syncReports(ctx, scan)
+		if err != nil {
+			logger.Error(err, "Failed to update ImageScanReports", "image", spec.Image)
+		}
+		if err := r.applyPolicies(ctx, scan, vulnerabilities); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to evaluate scan policies")
+			return ctrl.Result{}, err
+		}
 
-		span.SetAttributes(tracing.AttrScanPhase.String(string(securityv1alpha1.ScanPhaseRegistered)))
+		span.SetAttributes(tracing.AttrScanPhase.String(string(status.Phase)))
 		logger.Info("Image registered in Aqua",
-			"image", imageScan.Spec.Image,
-			"digest", imageScan.Spec.Digest)
+			"image", spec.Image,
+			"digest", spec.Digest,
+			"phase", status.Phase)
 
-		if updateErr := r.Status().Update(ctx, &imageScan); updateErr != nil {
+		if updateErr := r.Status().Update(ctx, scan); updateErr != nil {
 			return ctrl.Result{}, updateErr
 		}
-		return ctrl.Result{}, nil
+		return r.rescanResult(scan), nil
+	}
+
+	return ctrl.Result{RequeueAfter: r.pollInterval()}, nil
+}
+
+// waitForScan keeps a Pending ImageScan polling Aqua at the poll interval. Once the scan
+// has been pending for longer than ScanTimeout it is marked TimedOut and handled as an
+// error, so that it is retried with backoff like any other failure.
+func (r *ImageScanReconciler) waitForScan(ctx context.Context, scan scanObject, message string) (ctrl.Result, error) {
+	status := scan.ScanStatus()
+	if r.ScanTimeout > 0 && status.TriggeredTime != nil &&
+		time.Since(status.TriggeredTime.Time) > r.ScanTimeout {
+		log.FromContext(ctx).Info("Aqua scan timed out",
+			"image", scan.ScanSpec().Image,
+			"triggered", status.TriggeredTime.Time,
+			"polls", status.PollCount)
+		timeoutMessage := fmt.Sprintf("Aqua did not finish scanning within %s after %d polls",
+			r.ScanTimeout, status.PollCount)
+		setCondition(scan, securityv1alpha1.ConditionTimedOut, metav1.ConditionTrue,
+			securityv1alpha1.ReasonScanTimedOut, timeoutMessage)
+		setCondition(scan, securityv1alpha1.ConditionScanCompleted, metav1.ConditionFalse,
+			securityv1alpha1.ReasonScanTimedOut, timeoutMessage)
+		return r.recordError(ctx, scan, securityv1alpha1.ReasonScanTimedOut, timeoutMessage)
+	}
+
+	status.Phase = securityv1alpha1.ScanPhasePending
+	status.Message = message
+	setPendingConditions(scan, message)
+	if updateErr := r.Status().Update(ctx, scan); updateErr != nil {
+		return ctrl.Result{}, updateErr
+	}
+	return ctrl.Result{RequeueAfter: r.pollInterval()}, nil
+}
+
+// pollInitialDelay returns the delay before the first poll of a triggered scan.
+func (r *ImageScanReconciler) pollInitialDelay() time.Duration {
+	if r.ScanPollInitialDelay > 0 {
+		return r.ScanPollInitialDelay
 	}
+	return DefaultScanPollInitialDelay
+}
 
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+// pollInterval returns the interval between polls of a pending scan.
+func (r *ImageScanReconciler) pollInterval() time.Duration {
+	if r.ScanPollInterval > 0 {
+		return r.ScanPollInterval
+	}
+	return DefaultScanPollInterval
+}
+
+// recordError moves the ImageScan to Error and requeues it with exponential backoff.
+// Once more than MaxRetries consecutive attempts have failed, the ImageScan is marked
+// Failed instead and no longer retried, with the RetriesExhausted reason. Otherwise the
+// reason classifies the error.
+func (r *ImageScanReconciler) recordError(ctx context.Context, scan scanObject, reason, message string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	status := scan.ScanStatus()
+
+	status.RetryCount++
+	status.Policy = ""
+	result := ctrl.Result{RequeueAfter: calculateBackoff(status.RetryCount)}
+
+	if r.MaxRetries > 0 && status.RetryCount > r.MaxRetries {
+		logger.Info("Giving up on ImageScan after retries",
+			"image", scan.ScanSpec().Image,
+			"attempts", status.RetryCount,
+			"reason", reason)
+		now := metav1.Now()
+		status.Phase = securityv1alpha1.ScanPhaseFailed
+		status.Message = fmt.Sprintf("Giving up after %d attempts: %s", status.RetryCount, message)
+		status.CompletedTime = &now
+		reason = securityv1alpha1.ReasonRetriesExhausted
+		result = ctrl.Result{}
+	} else {
+		status.Phase = securityv1alpha1.ScanPhaseError
+		status.Message = message
+	}
+	setCondition(scan, securityv1alpha1.ConditionReady, metav1.ConditionFalse, reason, status.Message)
+
+	if updateErr := r.Status().Update(ctx, scan); updateErr != nil {
+		return ctrl.Result{}, updateErr
+	}
+	return result, nil
+}
+
+// errorReason classifies an Aqua client error into a Ready condition reason.
+func errorReason(err error) string {
+	switch {
+	case errors.Is(err, aqua.ErrAuthentication):
+		return securityv1alpha1.ReasonAuthenticationFailed
+	case errors.Is(err, aqua.ErrRegistryNotFound):
+		return securityv1alpha1.ReasonRegistryNotFound
+	case aqua.IsServerError(err):
+		return securityv1alpha1.ReasonAquaServerError
+	default:
+		return securityv1alpha1.ReasonAquaRequestFailed
+	}
+}
+
+// retriesExhausted returns true for ImageScans marked Failed because Aqua kept
+// erroring, as opposed to Failed by a scan policy or by Aqua's assurance policies.
+func retriesExhausted(scan scanObject) bool {
+	status := scan.ScanStatus()
+	if status.Phase != securityv1alpha1.ScanPhaseFailed {
+		return false
+	}
+	ready := meta.FindStatusCondition(status.Conditions, securityv1alpha1.ConditionReady)
+	return ready != nil && ready.Reason == securityv1alpha1.ReasonRetriesExhausted
+}
+
+// setCondition sets a condition of the ImageScan for its current generation.
+// It returns true if the condition changed.
+func setCondition(scan scanObject, condType string, status metav1.ConditionStatus, reason, message string) bool {
+	return meta.SetStatusCondition(&scan.ScanStatus().Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: scan.GetGeneration(),
+	})
+}
+
+// setPendingConditions marks a scan that Aqua has not finished yet.
+func setPendingConditions(scan scanObject, message string) {
+	setCondition(scan, securityv1alpha1.ConditionScanCompleted, metav1.ConditionFalse,
+		securityv1alpha1.ReasonScanPending, message)
+	setCondition(scan, securityv1alpha1.ConditionPolicyEvaluated, metav1.ConditionFalse,
+		securityv1alpha1.ReasonScanPending, "Waiting for scan results")
+	setCondition(scan, securityv1alpha1.ConditionReady, metav1.ConditionFalse,
+		securityv1alpha1.ReasonScanPending, message)
+}
+
+// setAquaReachable records the outcome of the last request to Aqua. A missing Aqua
+// registry is a configuration problem, not a connectivity one, so it leaves Aqua reachable.
+// It returns true if the condition changed.
+func setAquaReachable(scan scanObject, err error) bool {
+	if err == nil || errors.Is(err, aqua.ErrRegistryNotFound) {
+		return setCondition(scan, securityv1alpha1.ConditionAquaReachable, metav1.ConditionTrue,
+			securityv1alpha1.ReasonReachable, "Last request to Aqua succeeded")
+	}
+	return setCondition(scan, securityv1alpha1.ConditionAquaReachable, metav1.ConditionFalse,
+		errorReason(err), err.Error())
+}
+
+// untilRescan returns how long until a completed ImageScan is due for a rescan.
+// A zero or negative duration means the rescan is due now.
+func (r *ImageScanReconciler) untilRescan(scan scanObject) time.Duration {
+	status := scan.ScanStatus()
+	lastChecked := status.LastCheckedTime
+	if lastChecked == nil {
+		lastChecked = status.CompletedTime
+	}
+	if lastChecked == nil {
+		return 0
+	}
+	return time.Until(lastChecked.Add(r.RescanInterval))
+}
+
+// rescanResult requeues a completed ImageScan for its next rescan, with jitter so that
+// ImageScans completed together don't all hit Aqua at the same moment.
+func (r *ImageScanReconciler) rescanResult(scan scanObject) ctrl.Result {
+	if r.RescanInterval <= 0 {
+		return ctrl.Result{}
+	}
+	remaining := r.untilRescan(scan)
+	if remaining <= 0 {
+		remaining = r.RescanInterval
+	}
+	return ctrl.Result{RequeueAfter: wait.Jitter(remaining, rescanJitterFactor)}
+}
+
+// rescan re-fetches the results of a completed ImageScan from Aqua and re-evaluates
+// scan policies. Failures keep the current verdict so that an Aqua outage doesn't
+// block pods running long-approved images; the rescan is simply retried later.
+func (r *ImageScanReconciler) rescan(ctx context.Context, scan scanObject) (ctrl.Result, error) {
+	spec, status := scan.ScanSpec(), scan.ScanStatus()
+	ctx, span := tracing.StartSpan(ctx, "ImageScanReconciler.rescan",
+		trace.WithAttributes(
+			tracing.AttrImageName.String(spec.Image),
+			tracing.AttrImageDigest.String(spec.Digest),
+		),
+	)
+	defer span.End()
+
+	logger := log.FromContext(ctx)
+	retryAfter := ctrl.Result{RequeueAfter: wait.Jitter(baseBackoff, rescanJitterFactor)}
+
+	result, err := r.AquaClient.GetScanResult(ctx, spec.Image, spec.Digest)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get scan result from Aqua")
+		logger.Error(err, "Failed to rescan image, keeping previous results", "image", spec.Image)
+		if setAquaReachable(scan, err) {
+			if updateErr := r.Status().Update(ctx, scan); updateErr != nil {
+				return ctrl.Result{}, updateErr
+			}
+		}
+		return retryAfter, nil
+	}
+	setAquaReachable(scan, nil)
+
+	switch {
+	case result.Status == aqua.StatusNotFound:
+		// Aqua no longer knows the image - register it again and pick up the results later
+		logger.Info("Image no longer found in Aqua, triggering scan", "image", spec.Image)
+		if _, err := r.AquaClient.TriggerScan(ctx, spec.Image, spec.Digest); err != nil {
+			span.RecordError(err)
+			logger.Error(err, "Failed to trigger rescan", "image", spec.Image)
+		}
+		return retryAfter, nil
+	case result.InProgress():
+		return ctrl.Result{RequeueAfter: r.pollInterval()}, nil
+	case result.ScanFailed():
+		logger.Info("Aqua rescan failed, keeping previous results",
+			"image", spec.Image, "error", result.ScanError)
+		return retryAfter, nil
+	}
+
+	previousPhase := status.Phase
+	recordScanResult(scan, result, metav1.Now())
+	vulnerabilities, err := r.syncReports(ctx, scan)
+	if err != nil {
+		logger.Error(err, "Failed to update ImageScanReports", "image", spec.Image)
+	}
+	if err := r.applyPolicies(ctx, scan, vulnerabilities); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to evaluate scan policies")
+		return ctrl.Result{}, err
+	}
+
+	span.SetAttributes(tracing.AttrScanPhase.String(string(status.Phase)))
+	logger.Info("Rescanned image",
+		"image", spec.Image,
+		"digest", spec.Digest,
+		"from", previousPhase,
+		"to", status.Phase)
+
+	if updateErr := r.Status().Update(ctx, scan); updateErr != nil {
+		return ctrl.Result{}, updateErr
+	}
+	return r.rescanResult(scan), nil
+}
+
+// recordScanResult copies a finished Aqua scan result into the ImageScan status.
+func recordScanResult(scan scanObject, result *aqua.ScanResult, now metav1.Time) {
+	status := scan.ScanStatus()
+	status.LastCheckedTime = &now
+	status.LastScanTime = &now
+	if !result.ScanDate.IsZero() {
+		scanDate := metav1.NewTime(result.ScanDate)
+		status.LastScanTime = &scanDate
+	}
+	status.AquaScanStatus = result.ScanStatus
+	status.Vulnerabilities = vulnerabilitySummary(result.Vulnerabilities)
+	status.Findings = &securityv1alpha1.FindingSummary{
+		Malware:           result.Findings.Malware,
+		SensitiveData:     result.Findings.SensitiveData,
+		PrivateKeys:       result.Findings.PrivateKeys,
+		Misconfigurations: result.Findings.Misconfigurations,
+	}
+	status.Disallowed = result.Disallowed
+	status.FailedAssurancePolicies = result.FailedPolicies
+	status.RetryCount = 0 // Reset retry count on success
+	meta.RemoveStatusCondition(&status.Conditions, securityv1alpha1.ConditionTimedOut)
+	setCondition(scan, securityv1alpha1.ConditionScanCompleted, metav1.ConditionTrue,
+		securityv1alpha1.ReasonScanFinished, fmt.Sprintf("Aqua scan %s", result.ScanStatus))
+}
+
+// vulnerabilitySummary converts Aqua's vulnerability counts to the ImageScan status summary.
+// Vulnerabilities that are not critical, high, medium or low (negligible or unrated)
+// are counted as unknown.
+func vulnerabilitySummary(counts aqua.VulnerabilityCounts) *securityv1alpha1.VulnerabilitySummary {
+	unknown := counts.Total - counts.Critical - counts.High - counts.Medium - counts.Low
+	if unknown < 0 {
+		unknown = 0
+	}
+	return &securityv1alpha1.VulnerabilitySummary{
+		Critical: counts.Critical,
+		High:     counts.High,
+		Medium:   counts.Medium,
+		Low:      counts.Low,
+		Unknown:  unknown,
+	}
+}
+
+// hasScanResults returns true if the scan holds finished Aqua results that scan
+// policies can be evaluated against.
+func hasScanResults(scan scanObject) bool {
+	return isCompletedPhase(scan.ScanStatus().Phase) && !retriesExhausted(scan)
+}
+
+// isCompletedPhase returns true for phases where Aqua has finished scanning the image.
+func isCompletedPhase(phase securityv1alpha1.ScanPhase) bool {
+	return phase == securityv1alpha1.ScanPhaseRegistered ||
+		phase == securityv1alpha1.ScanPhasePassed ||
+		phase == securityv1alpha1.ScanPhaseFailed
+}
+
+// applyPolicies evaluates the ClusterScanPolicies and the ScanPolicies in the ImageScan's
+// namespace against its vulnerability and finding summaries, and sets the resulting phase.
+// ClusterImageScans are only evaluated against ClusterScanPolicies.
+// Without any applicable policy the image is only Registered. With AssurancePolicies,
+// Aqua's verdict is applied first. vulnerabilities are the
+// per-CVE details when they were just fetched from Aqua, or nil to load them if needed.
+func (r *ImageScanReconciler) applyPolicies(ctx context.Context, scan scanObject, vulnerabilities []securityv1alpha1.Vulnerability) error {
+	ctx, span := tracing.StartSpan(ctx, "ImageScanReconciler.applyPolicies")
+	defer span.End()
+
+	status := scan.ScanStatus()
+
+	var clusterPolicies securityv1alpha1.ClusterScanPolicyList
+	if err := r.List(ctx, &clusterPolicies); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("listing cluster scan policies: %w", err)
+	}
+	// ClusterImageScans have no namespace, so only ClusterScanPolicies apply to them
+	var policies securityv1alpha1.ScanPolicyList
+	if scan.GetNamespace() != "" {
+		if err := r.List(ctx, &policies, client.InNamespace(scan.GetNamespace())); err != nil {
+			span.RecordError(err)
+			return fmt.Errorf("listing scan policies: %w", err)
+		}
+	}
+
+	applicable := policy.FromScanPolicies(clusterPolicies.Items, policies.Items)
+	span.SetAttributes(attribute.Int("policy_count", len(applicable)))
+
+	status.Exceptions = nil
+	status.Suppressed = nil
+	status.EffectiveVulnerabilities = nil
+	status.Fixable = nil
+
+	// Aqua's assurance policies are managed in Aqua, so ScanExceptions and OpenVEX
+	// documents don't change their verdict. ScanPolicies can still reject allowed images
+	var assurance policy.Result
+	if r.AssurancePolicies {
+		assurance = policy.EvaluateAssurance(status.Disallowed, status.FailedAssurancePolicies)
+		span.SetAttributes(attribute.Bool("assurance_passed", assurance.Passed))
+		if !assurance.Passed {
+			status.Phase = securityv1alpha1.ScanPhaseFailed
+			status.Policy = assurance.Policy
+			status.Message = assurance.Message
+			setCondition(scan, securityv1alpha1.ConditionPolicyEvaluated, metav1.ConditionTrue,
+				securityv1alpha1.ReasonAssurancePolicyFailed, assurance.Message)
+			setCondition(scan, securityv1alpha1.ConditionReady, metav1.ConditionFalse,
+				securityv1alpha1.ReasonAssurancePolicyFailed, assurance.Message)
+			return nil
+		}
+	}
+
+	// Rules are evaluated per pod by the Pod gate controller against the counts recorded
+	// here, and need the per-CVE details for the fixable counts
+	rules, err := r.rulesExist(ctx, applicable)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	span.SetAttributes(attribute.Bool("rules", rules))
+
+	// Findings declared not_affected by OpenVEX or waived by ScanExceptions don't count
+	// against the thresholds or rules
+	summary := status.Vulnerabilities
+	if len(applicable) > 0 || rules {
+		if summary, err = r.effectiveSummary(ctx, scan, vulnerabilities, rules); err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	if len(applicable) == 0 {
+		if r.AssurancePolicies {
+			status.Phase = securityv1alpha1.ScanPhasePassed
+			status.Policy = ""
+			status.Message = assurance.Message
+			setCondition(scan, securityv1alpha1.ConditionPolicyEvaluated, metav1.ConditionTrue,
+				securityv1alpha1.ReasonPolicyPassed, status.Message)
+			setCondition(scan, securityv1alpha1.ConditionReady, metav1.ConditionTrue,
+				securityv1alpha1.ReasonPolicyPassed, status.Message)
+			return nil
+		}
+		status.Phase = securityv1alpha1.ScanPhaseRegistered
+		status.Policy = ""
+		status.Message = "Image registered in Aqua"
+		setCondition(scan, securityv1alpha1.ConditionPolicyEvaluated, metav1.ConditionTrue,
+			securityv1alpha1.ReasonNoPolicies, "No scan policy applies to the image")
+		setCondition(scan, securityv1alpha1.ConditionReady, metav1.ConditionTrue,
+			securityv1alpha1.ReasonRegistered, status.Message)
+		return nil
+	}
+
+	result := policy.Evaluate(summary, status.Findings, applicable)
+	span.SetAttributes(
+		attribute.Bool("policy_passed", result.Passed),
+		attribute.Int("exception_count", len(status.Exceptions)),
+		attribute.Int("suppressed_count", len(status.Suppressed)),
+	)
+	if len(status.Suppressed) > 0 {
+		result.Message += fmt.Sprintf(" (%d findings not affected per OpenVEX)", len(status.Suppressed))
+	}
+	if len(status.Exceptions) > 0 {
+		result.Message += fmt.Sprintf(" (findings waived by ScanException %s)", strings.Join(status.Exceptions, ", "))
+	}
+	status.Message = result.Message
+	if result.Passed {
+		status.Phase = securityv1alpha1.ScanPhasePassed
+		status.Policy = ""
+		setCondition(scan, securityv1alpha1.ConditionPolicyEvaluated, metav1.ConditionTrue,
+			securityv1alpha1.ReasonPolicyPassed, result.Message)
+		setCondition(scan, securityv1alpha1.ConditionReady, metav1.ConditionTrue,
+			securityv1alpha1.ReasonPolicyPassed, result.Message)
+	} else {
+		status.Phase = securityv1alpha1.ScanPhaseFailed
+		status.Policy = result.Policy
+		message := fmt.Sprintf("%s: %s", result.Policy, result.Message)
+		setCondition(scan, securityv1alpha1.ConditionPolicyEvaluated, metav1.ConditionTrue,
+			securityv1alpha1.ReasonPolicyViolation, message)
+		setCondition(scan, securityv1alpha1.ConditionReady, metav1.ConditionFalse,
+			securityv1alpha1.ReasonPolicyViolation, message)
+	}
+	return nil
+}
+
+// effectiveSummary returns the scan's vulnerability summary without the findings that
+// OpenVEX statements declare not_affected or that active ScanExceptions waive, and records
+// both, the resulting summary and, when the per-CVE details are known, the fixable counts
+// in the scan status. Per-CVE details are fetched when there are VEX documents or CVE
+// exceptions, or when details is true; vulnerabilities are used instead when not nil.
+func (r *ImageScanReconciler) effectiveSummary(ctx context.Context, scan scanObject, vulnerabilities []securityv1alpha1.Vulnerability, details bool) (*securityv1alpha1.VulnerabilitySummary, error) {
+	spec, status := scan.ScanSpec(), scan.ScanStatus()
+	status.Exceptions = nil
+	status.Suppressed = nil
+
+	var exceptions securityv1alpha1.ScanExceptionList
+	if err := r.List(ctx, &exceptions); err != nil {
+		return nil, fmt.Errorf("listing scan exceptions: %w", err)
+	}
+	active := policy.ActiveExceptions(exceptions.Items, scan.GetNamespace(), spec.Image, spec.Digest, time.Now())
+	for _, e := range active {
+		status.Exceptions = append(status.Exceptions, e.Name)
+	}
+
+	documents, err := r.vexDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy.WaivesImage(active) {
+		status.EffectiveVulnerabilities = &securityv1alpha1.VulnerabilitySummary{}
+		status.Fixable = &securityv1alpha1.VulnerabilitySummary{}
+		return status.EffectiveVulnerabilities, nil
+	}
+	if len(active) == 0 && len(documents) == 0 && !details && vulnerabilities == nil {
+		status.EffectiveVulnerabilities = status.Vulnerabilities.DeepCopy()
+		return status.EffectiveVulnerabilities, nil
+	}
+
+	if vulnerabilities == nil {
+		if vulnerabilities, err = r.scanVulnerabilities(ctx, scan); err != nil {
+			return nil, err
+		}
+	}
+
+	summary := status.Vulnerabilities
+	if len(documents) > 0 {
+		var affected, notAffected []securityv1alpha1.Vulnerability
+		for _, v := range vulnerabilities {
+			match, ok := vex.NotAffected(documents, spec.Image, spec.Digest, v)
+			if !ok {
+				affected = append(affected, v)
+				continue
+			}
+			notAffected = append(notAffected, v)
+			status.Suppressed = append(status.Suppressed, securityv1alpha1.SuppressedVulnerability{
+				ID:              v.ID,
+				Package:         v.Package,
+				Justification:   match.Statement.Justification,
+				ImpactStatement: match.Statement.ImpactStatement,
+				Document:        match.Document,
+			})
+		}
+		summary = policy.Subtract(summary, notAffected)
+		vulnerabilities = affected
+	}
+	status.EffectiveVulnerabilities = policy.Waive(summary, vulnerabilities, active).DeepCopy()
+	status.Fixable = policy.Fixable(policy.Unwaived(vulnerabilities, active))
+	return status.EffectiveVulnerabilities, nil
+}
+
+// rulesExist returns true if a scan policy with rules applies to the scan, or, since pods
+// are evaluated against the rules of their own namespace wherever their scans are, if any
+// ScanPolicy in the cluster has rules.
+func (r *ImageScanReconciler) rulesExist(ctx context.Context, applicable []policy.Policy) (bool, error) {
+	if len(policy.WithRules(applicable)) > 0 {
+		return true, nil
+	}
+	var policies securityv1alpha1.ScanPolicyList
+	if err := r.List(ctx, &policies); err != nil {
+		return false, fmt.Errorf("listing scan policies: %w", err)
+	}
+	for _, p := range policies.Items {
+		if len(p.Spec.Rules) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 func (r *ImageScanReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&securityv1alpha1.ImageScan{}).
-		Complete(r)
+	// Index ImageScans by the ClusterImageScan they mirror
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&securityv1alpha1.ImageScan{},
+		IndexFieldClusterScanRef,
+		func(obj client.Object) []string {
+			imageScan, ok := obj.(*securityv1alpha1.ImageScan)
+			if !ok || imageScan.Spec.ClusterScanRef == "" {
+				return nil
+			}
+			return []string{imageScan.Spec.ClusterScanRef}
+		},
+	); err != nil {
+		return fmt.Errorf("failed to set up field indexer: %w", err)
+	}
+
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		// Status updates must not trigger reconciles, or Error retries would skip their backoff
+		For(&securityv1alpha1.ImageScan{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&securityv1alpha1.ClusterScanPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToImageScans),
+		).
+		Watches(
+			&securityv1alpha1.ScanPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToImageScans),
+		).
+		Watches(
+			&securityv1alpha1.ScanException{},
+			handler.EnqueueRequestsFromMapFunc(r.mapExceptionToImageScans),
+		).
+		Watches(
+			&securityv1alpha1.ClusterImageScan{},
+			handler.EnqueueRequestsFromMapFunc(r.mapClusterImageScanToReferences),
+		)
+
+	if r.VEXNamespace != "" {
+		bldr = bldr.Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.mapVEXToImageScans),
+			builder.WithPredicates(r.isVEXConfigMap()),
+		)
+	}
+
+	return bldr.Complete(r)
+}
+
+// mapPolicyToImageScans re-evaluates the ImageScans a policy applies to when it changes:
+// every ImageScan for a ClusterScanPolicy, and those in the same namespace for a ScanPolicy.
+// ScanPolicies with rules re-evaluate every ImageScan, since the rules need the fixable
+// counts of the scans of their namespace's pods wherever those scans are.
+func (r *ImageScanReconciler) mapPolicyToImageScans(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	var opts []client.ListOption
+	if obj.GetNamespace() != "" && !hasRules(obj) {
+		opts = append(opts, client.InNamespace(obj.GetNamespace()))
+	}
+
+	var imageScans securityv1alpha1.ImageScanList
+	if err := r.List(ctx, &imageScans, opts...); err != nil {
+		logger.Error(err, "Failed to list ImageScans for policy mapping")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, imageScan := range imageScans.Items {
+		if !hasScanResults(&imageScan) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      imageScan.Name,
+				Namespace: imageScan.Namespace,
+			},
+		})
+	}
+	return requests
+}
+
+// hasRules returns true if obj is a ScanPolicy with CEL rules.
+func hasRules(obj client.Object) bool {
+	p, ok := obj.(*securityv1alpha1.ScanPolicy)
+	return ok && len(p.Spec.Rules) > 0
 }
@@ -0,0 +1,752 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// fakeAquaClient is an in-memory aqua.Client for reconciler tests
+type fakeAquaClient struct {
+	result       *aqua.ScanResult
+	getErr       error
+	triggerErr   error
+	getCalls     int
+	triggerCalls int
+
+	vulnerabilities    []aqua.Vulnerability
+	vulnerabilitiesErr error
+	vulnerabilityCalls int
+}
+
+func (f *fakeAquaClient) GetScanResult(_ context.Context, image, digest string) (*aqua.ScanResult, error) {
+	f.getCalls++
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	if f.result == nil {
+		return &aqua.ScanResult{Status: aqua.StatusNotFound, Image: image, Digest: digest}, nil
+	}
+	return f.result, nil
+}
+
+func (f *fakeAquaClient) TriggerScan(_ context.Context, image, digest string) (string, error) {
+	f.triggerCalls++
+	if f.triggerErr != nil {
+		return "", f.triggerErr
+	}
+	return "registry/" + image + "@" + digest, nil
+}
+
+func (f *fakeAquaClient) GetVulnerabilities(context.Context, string, string) ([]aqua.Vulnerability, error) {
+	f.vulnerabilityCalls++
+	if f.vulnerabilitiesErr != nil {
+		return nil, f.vulnerabilitiesErr
+	}
+	return f.vulnerabilities, nil
+}
+
+func (f *fakeAquaClient) GetRegistries(context.Context) ([]aqua.Registry, error) {
+	return nil, nil
+}
+
+func (f *fakeAquaClient) FindRegistryByPrefix(context.Context, string) (string, error) {
+	return "registry", nil
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+var _ = Describe("ImageScanReconciler", func() {
+	var (
+		scheme *runtime.Scheme
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		ctx = context.Background()
+	})
+
+	newImageScan := func(phase securityv1alpha1.ScanPhase, vulns *securityv1alpha1.VulnerabilitySummary) *securityv1alpha1.ImageScan {
+		return &securityv1alpha1.ImageScan{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sha256-0123456789abcdef",
+				Namespace: "default",
+			},
+			Spec: securityv1alpha1.ImageScanSpec{
+				Image:  "nginx:1.25",
+				Digest: testDigest,
+			},
+			Status: securityv1alpha1.ImageScanStatus{
+				Phase:           phase,
+				Vulnerabilities: vulns,
+			},
+		}
+	}
+
+	reconcileScan := func(c client.Client, aquaClient aqua.Client) (reconcile.Result, *securityv1alpha1.ImageScan) {
+		r := &ImageScanReconciler{
+			Client:     c,
+			Scheme:     scheme,
+			AquaClient: aquaClient,
+		}
+		key := types.NamespacedName{Name: "sha256-0123456789abcdef", Namespace: "default"}
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated securityv1alpha1.ImageScan
+		Expect(c.Get(ctx, key, &updated)).To(Succeed())
+		return result, &updated
+	}
+
+	Describe("scan results", func() {
+		It("should copy vulnerability counts and scan details into status", func() {
+			imageScan := newImageScan(securityv1alpha1.ScanPhasePending, nil)
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(imageScan).
+				WithStatusSubresource(imageScan).
+				Build()
+
+			scanDate := time.Date(2026, 1, 8, 12, 0, 0, 0, time.UTC)
+			_, updated := reconcileScan(fakeClient, &fakeAquaClient{
+				result: &aqua.ScanResult{
+					Status: aqua.StatusFound,
+					Vulnerabilities: aqua.VulnerabilityCounts{
+						Critical: 1, High: 2, Medium: 3, Low: 4, Negligible: 5, Total: 15,
+					},
+					ScanDate:   scanDate,
+					Disallowed: true,
+					ScanStatus: aqua.AquaScanStatusFinished,
+				},
+			})
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseRegistered))
+			Expect(updated.Status.Vulnerabilities).To(Equal(&securityv1alpha1.VulnerabilitySummary{
+				Critical: 1, High: 2, Medium: 3, Low: 4, Unknown: 5,
+			}))
+			Expect(updated.Status.LastScanTime.Time.Equal(scanDate)).To(BeTrue())
+			Expect(updated.Status.Disallowed).To(BeTrue())
+			Expect(updated.Status.AquaScanStatus).To(Equal(aqua.AquaScanStatusFinished))
+		})
+
+		It("should stay Pending while Aqua is still scanning", func() {
+			imageScan := newImageScan(securityv1alpha1.ScanPhasePending, nil)
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(imageScan).
+				WithStatusSubresource(imageScan).
+				Build()
+
+			result, updated := reconcileScan(fakeClient, &fakeAquaClient{
+				result: &aqua.ScanResult{Status: aqua.StatusFound, ScanStatus: aqua.AquaScanStatusInProgress},
+			})
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePending))
+			Expect(updated.Status.Vulnerabilities).To(BeNil())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		})
+
+		It("should move to Error when Aqua reports a failed scan", func() {
+			imageScan := newImageScan(securityv1alpha1.ScanPhasePending, nil)
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(imageScan).
+				WithStatusSubresource(imageScan).
+				Build()
+
+			_, updated := reconcileScan(fakeClient, &fakeAquaClient{
+				result: &aqua.ScanResult{
+					Status:     aqua.StatusFound,
+					ScanStatus: aqua.AquaScanStatusFailed,
+					ScanError:  "manifest unknown",
+				},
+			})
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseError))
+			Expect(updated.Status.Message).To(ContainSubstring("manifest unknown"))
+		})
+	})
+
+	Describe("pending scan polling", func() {
+		reconcilePoll := func(imageScan *securityv1alpha1.ImageScan, aquaClient *fakeAquaClient) (reconcile.Result, *securityv1alpha1.ImageScan) {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(imageScan).
+				WithStatusSubresource(imageScan).
+				Build()
+			r := &ImageScanReconciler{
+				Client:               fakeClient,
+				Scheme:               scheme,
+				AquaClient:           aquaClient,
+				ScanPollInitialDelay: 5 * time.Second,
+				ScanPollInterval:     20 * time.Second,
+				ScanTimeout:          time.Hour,
+			}
+			key := types.NamespacedName{Name: imageScan.Name, Namespace: imageScan.Namespace}
+			result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated securityv1alpha1.ImageScan
+			Expect(fakeClient.Get(ctx, key, &updated)).To(Succeed())
+			return result, &updated
+		}
+
+		newTriggeredScan := func(triggeredAgo time.Duration, polls int) *securityv1alpha1.ImageScan {
+			imageScan := newImageScan(securityv1alpha1.ScanPhasePending, nil)
+			triggered := metav1.NewTime(time.Now().Add(-triggeredAgo))
+			imageScan.Status.TriggeredTime = &triggered
+			imageScan.Status.PollCount = polls
+			return imageScan
+		}
+
+		It("should record the trigger time and wait the initial delay", func() {
+			aquaClient := &fakeAquaClient{}
+			result, updated := reconcilePoll(newImageScan("", nil), aquaClient)
+			Expect(aquaClient.triggerCalls).To(Equal(1))
+			Expect(result).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Second}))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePending))
+			Expect(updated.Status.TriggeredTime).NotTo(BeNil())
+			Expect(updated.Status.PollCount).To(BeZero())
+		})
+
+		It("should poll without re-triggering while Aqua registers the image", func() {
+			aquaClient := &fakeAquaClient{}
+			result, updated := reconcilePoll(newTriggeredScan(time.Minute, 2), aquaClient)
+			Expect(aquaClient.triggerCalls).To(BeZero())
+			Expect(result).To(Equal(reconcile.Result{RequeueAfter: 20 * time.Second}))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePending))
+			Expect(updated.Status.PollCount).To(Equal(3))
+		})
+
+		It("should poll at the interval while the scan is in progress", func() {
+			aquaClient := &fakeAquaClient{
+				result: &aqua.ScanResult{Status: aqua.StatusFound, ScanStatus: aqua.AquaScanStatusInProgress},
+			}
+			result, updated := reconcilePoll(newTriggeredScan(time.Minute, 0), aquaClient)
+			Expect(result).To(Equal(reconcile.Result{RequeueAfter: 20 * time.Second}))
+			Expect(updated.Status.PollCount).To(Equal(1))
+		})
+
+		It("should keep the poll count once the scan completes", func() {
+			aquaClient := &fakeAquaClient{
+				result: &aqua.ScanResult{Status: aqua.StatusFound, ScanStatus: aqua.AquaScanStatusFinished},
+			}
+			_, updated := reconcilePoll(newTriggeredScan(5*time.Minute, 9), aquaClient)
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseRegistered))
+			Expect(updated.Status.PollCount).To(Equal(10))
+			Expect(updated.Status.TriggeredTime).NotTo(BeNil())
+		})
+
+		It("should time out scans pending longer than the scan timeout", func() {
+			aquaClient := &fakeAquaClient{
+				result: &aqua.ScanResult{Status: aqua.StatusFound, ScanStatus: aqua.AquaScanStatusPending},
+			}
+			result, updated := reconcilePoll(newTriggeredScan(2*time.Hour, 239), aquaClient)
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseError))
+			Expect(updated.Status.Message).To(ContainSubstring("240 polls"))
+			Expect(result.RequeueAfter).To(Equal(calculateBackoff(1)))
+
+			timedOut := meta.FindStatusCondition(updated.Status.Conditions, securityv1alpha1.ConditionTimedOut)
+			Expect(timedOut).NotTo(BeNil())
+			Expect(timedOut.Status).To(Equal(metav1.ConditionTrue))
+			Expect(timedOut.Reason).To(Equal(securityv1alpha1.ReasonScanTimedOut))
+			ready := meta.FindStatusCondition(updated.Status.Conditions, securityv1alpha1.ConditionReady)
+			Expect(ready.Reason).To(Equal(securityv1alpha1.ReasonScanTimedOut))
+		})
+	})
+
+	Describe("conditions", func() {
+		expectCondition := func(imageScan *securityv1alpha1.ImageScan, condType string, status metav1.ConditionStatus, reason string) {
+			cond := meta.FindStatusCondition(imageScan.Status.Conditions, condType)
+			Expect(cond).NotTo(BeNil(), "missing condition %s", condType)
+			Expect(cond.Status).To(Equal(status), "condition %s", condType)
+			Expect(cond.Reason).To(Equal(reason), "condition %s", condType)
+			Expect(cond.Message).NotTo(BeEmpty(), "condition %s", condType)
+			Expect(cond.ObservedGeneration).To(Equal(imageScan.Generation), "condition %s", condType)
+		}
+
+		newScanClient := func(imageScan *securityv1alpha1.ImageScan, objs ...client.Object) client.Client {
+			imageScan.Generation = 3
+			return fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(append(objs, imageScan)...).
+				WithStatusSubresource(imageScan).
+				Build()
+		}
+
+		It("should report a triggered scan as pending", func() {
+			fakeClient := newScanClient(newImageScan("", nil))
+			_, updated := reconcileScan(fakeClient, &fakeAquaClient{})
+
+			expectCondition(updated, securityv1alpha1.ConditionScanTriggered, metav1.ConditionTrue, securityv1alpha1.ReasonTriggered)
+			expectCondition(updated, securityv1alpha1.ConditionScanCompleted, metav1.ConditionFalse, securityv1alpha1.ReasonScanPending)
+			expectCondition(updated, securityv1alpha1.ConditionPolicyEvaluated, metav1.ConditionFalse, securityv1alpha1.ReasonScanPending)
+			expectCondition(updated, securityv1alpha1.ConditionAquaReachable, metav1.ConditionTrue, securityv1alpha1.ReasonReachable)
+			expectCondition(updated, securityv1alpha1.ConditionReady, metav1.ConditionFalse, securityv1alpha1.ReasonScanPending)
+		})
+
+		It("should report a completed scan that violates a policy", func() {
+			clusterPolicy := &securityv1alpha1.ClusterScanPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "baseline"},
+				Spec:       securityv1alpha1.ScanPolicySpec{MaxCritical: intPtr(0)},
+			}
+			fakeClient := newScanClient(newImageScan(securityv1alpha1.ScanPhasePending, nil), clusterPolicy)
+			_, updated := reconcileScan(fakeClient, &fakeAquaClient{
+				result: &aqua.ScanResult{
+					Status:          aqua.StatusFound,
+					ScanStatus:      aqua.AquaScanStatusFinished,
+					Vulnerabilities: aqua.VulnerabilityCounts{Critical: 2, Total: 2},
+				},
+			})
+
+			expectCondition(updated, securityv1alpha1.ConditionScanTriggered, metav1.ConditionTrue, securityv1alpha1.ReasonFoundInAqua)
+			expectCondition(updated, securityv1alpha1.ConditionScanCompleted, metav1.ConditionTrue, securityv1alpha1.ReasonScanFinished)
+			expectCondition(updated, securityv1alpha1.ConditionPolicyEvaluated, metav1.ConditionTrue, securityv1alpha1.ReasonPolicyViolation)
+			expectCondition(updated, securityv1alpha1.ConditionReady, metav1.ConditionFalse, securityv1alpha1.ReasonPolicyViolation)
+			Expect(meta.FindStatusCondition(updated.Status.Conditions, securityv1alpha1.ConditionReady).Message).
+				To(ContainSubstring("ClusterScanPolicy/baseline"))
+		})
+
+		It("should report a registered image as Ready", func() {
+			fakeClient := newScanClient(newImageScan(securityv1alpha1.ScanPhasePending, nil))
+			_, updated := reconcileScan(fakeClient, &fakeAquaClient{
+				result: &aqua.ScanResult{Status: aqua.StatusFound, ScanStatus: aqua.AquaScanStatusFinished},
+			})
+
+			expectCondition(updated, securityv1alpha1.ConditionPolicyEvaluated, metav1.ConditionTrue, securityv1alpha1.ReasonNoPolicies)
+			expectCondition(updated, securityv1alpha1.ConditionReady, metav1.ConditionTrue, securityv1alpha1.ReasonRegistered)
+		})
+
+		It("should report Aqua as unreachable when requests fail", func() {
+			fakeClient := newScanClient(newImageScan(securityv1alpha1.ScanPhasePending, nil))
+			_, updated := reconcileScan(fakeClient, &fakeAquaClient{getErr: &aqua.APIError{StatusCode: 502}})
+
+			expectCondition(updated, securityv1alpha1.ConditionAquaReachable, metav1.ConditionFalse, securityv1alpha1.ReasonAquaServerError)
+			expectCondition(updated, securityv1alpha1.ConditionReady, metav1.ConditionFalse, securityv1alpha1.ReasonAquaServerError)
+		})
+
+		It("should keep Aqua reachable when no Aqua registry matches", func() {
+			fakeClient := newScanClient(newImageScan(securityv1alpha1.ScanPhasePending, nil))
+			_, updated := reconcileScan(fakeClient, &fakeAquaClient{
+				getErr: fmt.Errorf("finding Aqua registry: %w", aqua.ErrRegistryNotFound),
+			})
+
+			expectCondition(updated, securityv1alpha1.ConditionAquaReachable, metav1.ConditionTrue, securityv1alpha1.ReasonReachable)
+			expectCondition(updated, securityv1alpha1.ConditionReady, metav1.ConditionFalse, securityv1alpha1.ReasonRegistryNotFound)
+		})
+	})
+
+	Describe("error retries", func() {
+		reconcileWithRetries := func(imageScan *securityv1alpha1.ImageScan, aquaClient *fakeAquaClient, maxRetries int) (reconcile.Result, *securityv1alpha1.ImageScan) {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(imageScan).
+				WithStatusSubresource(imageScan).
+				Build()
+			r := &ImageScanReconciler{
+				Client:     fakeClient,
+				Scheme:     scheme,
+				AquaClient: aquaClient,
+				MaxRetries: maxRetries,
+			}
+			key := types.NamespacedName{Name: imageScan.Name, Namespace: imageScan.Namespace}
+			result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated securityv1alpha1.ImageScan
+			Expect(fakeClient.Get(ctx, key, &updated)).To(Succeed())
+			return result, &updated
+		}
+
+		It("should retry an ImageScan in Error", func() {
+			imageScan := newImageScan(securityv1alpha1.ScanPhaseError, nil)
+			imageScan.Status.RetryCount = 2
+			aquaClient := &fakeAquaClient{
+				result: &aqua.ScanResult{Status: aqua.StatusFound, ScanStatus: aqua.AquaScanStatusFinished},
+			}
+
+			_, updated := reconcileWithRetries(imageScan, aquaClient, 5)
+			Expect(aquaClient.getCalls).To(Equal(1))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseRegistered))
+			Expect(updated.Status.RetryCount).To(BeZero())
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, securityv1alpha1.ConditionReady)).To(BeTrue())
+		})
+
+		It("should back off exponentially while retries remain", func() {
+			imageScan := newImageScan(securityv1alpha1.ScanPhaseError, nil)
+			imageScan.Status.RetryCount = 2
+
+			result, updated := reconcileWithRetries(imageScan, &fakeAquaClient{getErr: fmt.Errorf("connection refused")}, 5)
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseError))
+			Expect(updated.Status.RetryCount).To(Equal(3))
+			Expect(result.RequeueAfter).To(Equal(calculateBackoff(3)))
+		})
+
+		It("should classify Aqua errors into condition reasons", func() {
+			cases := map[string]error{
+				securityv1alpha1.ReasonAuthenticationFailed: fmt.Errorf("getting auth token: %w",
+					&aqua.APIError{Op: "token request", StatusCode: 401}),
+				securityv1alpha1.ReasonRegistryNotFound:  fmt.Errorf("finding Aqua registry: %w", aqua.ErrRegistryNotFound),
+				securityv1alpha1.ReasonAquaServerError:   &aqua.APIError{StatusCode: 503},
+				securityv1alpha1.ReasonAquaRequestFailed: fmt.Errorf("connection refused"),
+			}
+			for reason, err := range cases {
+				_, updated := reconcileWithRetries(newImageScan(securityv1alpha1.ScanPhasePending, nil),
+					&fakeAquaClient{getErr: err}, 5)
+				Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseError))
+				ready := meta.FindStatusCondition(updated.Status.Conditions, securityv1alpha1.ConditionReady)
+				Expect(ready).NotTo(BeNil())
+				Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+				Expect(ready.Reason).To(Equal(reason), "error: %v", err)
+			}
+		})
+
+		It("should mark the ImageScan Failed once retries are exhausted", func() {
+			imageScan := newImageScan(securityv1alpha1.ScanPhaseError, nil)
+			imageScan.Status.RetryCount = 3
+			aquaClient := &fakeAquaClient{getErr: &aqua.APIError{StatusCode: 500, Body: "internal server error"}}
+
+			result, updated := reconcileWithRetries(imageScan, aquaClient, 3)
+			Expect(result).To(Equal(reconcile.Result{}))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+			Expect(updated.Status.Policy).To(BeEmpty())
+			Expect(updated.Status.Message).To(ContainSubstring("Giving up after 4 attempts"))
+			Expect(updated.Status.CompletedTime).NotTo(BeNil())
+			ready := meta.FindStatusCondition(updated.Status.Conditions, securityv1alpha1.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Reason).To(Equal(securityv1alpha1.ReasonRetriesExhausted))
+			reachable := meta.FindStatusCondition(updated.Status.Conditions, securityv1alpha1.ConditionAquaReachable)
+			Expect(reachable).NotTo(BeNil())
+			Expect(reachable.Reason).To(Equal(securityv1alpha1.ReasonAquaServerError))
+			Expect(retriesExhausted(updated)).To(BeTrue())
+
+			By("not contacting Aqua again")
+			_, again := reconcileWithRetries(updated, aquaClient, 3)
+			Expect(aquaClient.getCalls).To(Equal(1))
+			Expect(again.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+		})
+
+		It("should retry forever when MaxRetries is zero", func() {
+			imageScan := newImageScan(securityv1alpha1.ScanPhaseError, nil)
+			imageScan.Status.RetryCount = 50
+
+			result, updated := reconcileWithRetries(imageScan, &fakeAquaClient{getErr: fmt.Errorf("timeout")}, 0)
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseError))
+			Expect(result.RequeueAfter).To(Equal(maxBackoff))
+		})
+	})
+
+	Describe("rescans", func() {
+		newCompletedScan := func(checkedAgo time.Duration) *securityv1alpha1.ImageScan {
+			imageScan := newImageScan(securityv1alpha1.ScanPhaseRegistered,
+				&securityv1alpha1.VulnerabilitySummary{Critical: 0})
+			checked := metav1.NewTime(time.Now().Add(-checkedAgo))
+			imageScan.Status.LastCheckedTime = &checked
+			imageScan.Status.CompletedTime = &checked
+			return imageScan
+		}
+
+		reconcileWithInterval := func(imageScan *securityv1alpha1.ImageScan, aquaClient *fakeAquaClient, objs ...client.Object) (reconcile.Result, *securityv1alpha1.ImageScan) {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(append(objs, imageScan)...).
+				WithStatusSubresource(imageScan).
+				Build()
+			r := &ImageScanReconciler{
+				Client:         fakeClient,
+				Scheme:         scheme,
+				AquaClient:     aquaClient,
+				RescanInterval: time.Hour,
+			}
+			key := types.NamespacedName{Name: imageScan.Name, Namespace: imageScan.Namespace}
+			result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated securityv1alpha1.ImageScan
+			Expect(fakeClient.Get(ctx, key, &updated)).To(Succeed())
+			return result, &updated
+		}
+
+		It("should requeue with jitter until the rescan is due", func() {
+			aquaClient := &fakeAquaClient{}
+			result, _ := reconcileWithInterval(newCompletedScan(15*time.Minute), aquaClient)
+			Expect(aquaClient.getCalls).To(BeZero())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 44*time.Minute))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", 50*time.Minute))
+		})
+
+		It("should re-fetch results and re-evaluate the phase when due", func() {
+			clusterPolicy := &securityv1alpha1.ClusterScanPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "baseline"},
+				Spec:       securityv1alpha1.ScanPolicySpec{MaxCritical: intPtr(0)},
+			}
+			aquaClient := &fakeAquaClient{
+				result: &aqua.ScanResult{
+					Status:          aqua.StatusFound,
+					ScanStatus:      aqua.AquaScanStatusFinished,
+					Vulnerabilities: aqua.VulnerabilityCounts{Critical: 1, Total: 1},
+				},
+			}
+			imageScan := newCompletedScan(2 * time.Hour)
+			imageScan.Status.Phase = securityv1alpha1.ScanPhasePassed
+
+			result, updated := reconcileWithInterval(imageScan, aquaClient, clusterPolicy)
+			Expect(aquaClient.getCalls).To(Equal(1))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+			Expect(updated.Status.Vulnerabilities.Critical).To(Equal(1))
+			Expect(updated.Status.LastCheckedTime.Time).To(BeTemporally("~", time.Now(), time.Minute))
+			Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+		})
+
+		It("should keep the previous verdict when Aqua is unreachable", func() {
+			aquaClient := &fakeAquaClient{getErr: fmt.Errorf("connection refused")}
+			imageScan := newCompletedScan(2 * time.Hour)
+			imageScan.Status.Phase = securityv1alpha1.ScanPhasePassed
+
+			result, updated := reconcileWithInterval(imageScan, aquaClient)
+			Expect(aquaClient.getCalls).To(Equal(1))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePassed))
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(meta.IsStatusConditionFalse(updated.Status.Conditions, securityv1alpha1.ConditionAquaReachable)).To(BeTrue())
+		})
+	})
+
+	Describe("scan policies", func() {
+		Context("when no policy applies", func() {
+			It("should mark the image Registered", func() {
+				imageScan := newImageScan("", nil)
+				fakeClient := fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(imageScan).
+					WithStatusSubresource(imageScan).
+					Build()
+
+				_, updated := reconcileScan(fakeClient, &fakeAquaClient{
+					result: &aqua.ScanResult{Status: aqua.StatusFound},
+				})
+				Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseRegistered))
+			})
+		})
+
+		Context("when the image violates a ClusterScanPolicy", func() {
+			It("should mark the image Failed and name the policy", func() {
+				imageScan := newImageScan(securityv1alpha1.ScanPhaseRegistered,
+					&securityv1alpha1.VulnerabilitySummary{Critical: 2})
+				clusterPolicy := &securityv1alpha1.ClusterScanPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "baseline"},
+					Spec:       securityv1alpha1.ScanPolicySpec{MaxCritical: intPtr(0)},
+				}
+				fakeClient := fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(imageScan, clusterPolicy).
+					WithStatusSubresource(imageScan).
+					Build()
+
+				aquaClient := &fakeAquaClient{}
+				_, updated := reconcileScan(fakeClient, aquaClient)
+				Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+				Expect(updated.Status.Policy).To(Equal("ClusterScanPolicy/baseline"))
+				Expect(updated.Status.Message).To(ContainSubstring("critical"))
+				// Re-evaluation uses stored results without calling Aqua
+				Expect(aquaClient.getCalls).To(BeZero())
+			})
+		})
+
+		Context("when Aqua found malware in an image without vulnerabilities", func() {
+			It("should mark the image Failed under a policy that blocks malware", func() {
+				imageScan := newImageScan("", nil)
+				clusterPolicy := &securityv1alpha1.ClusterScanPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "no-malware"},
+					Spec:       securityv1alpha1.ScanPolicySpec{BlockMalware: true, MaxCritical: intPtr(0)},
+				}
+				fakeClient := fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(imageScan, clusterPolicy).
+					WithStatusSubresource(imageScan).
+					Build()
+
+				_, updated := reconcileScan(fakeClient, &fakeAquaClient{
+					result: &aqua.ScanResult{
+						Status:     aqua.StatusFound,
+						ScanStatus: aqua.AquaScanStatusFinished,
+						Findings:   aqua.FindingCounts{Malware: 1},
+					},
+				})
+				Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+				Expect(updated.Status.Policy).To(Equal("ClusterScanPolicy/no-malware"))
+				Expect(updated.Status.Findings).To(Equal(&securityv1alpha1.FindingSummary{Malware: 1}))
+				Expect(updated.Status.Message).To(ContainSubstring("malware"))
+			})
+		})
+
+		Context("when the image satisfies a namespaced ScanPolicy", func() {
+			It("should mark the image Passed", func() {
+				imageScan := newImageScan(securityv1alpha1.ScanPhaseFailed,
+					&securityv1alpha1.VulnerabilitySummary{High: 1})
+				nsPolicy := &securityv1alpha1.ScanPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "relaxed", Namespace: "default"},
+					Spec:       securityv1alpha1.ScanPolicySpec{MaxHigh: intPtr(5)},
+				}
+				otherNsPolicy := &securityv1alpha1.ScanPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "strict", Namespace: "other"},
+					Spec:       securityv1alpha1.ScanPolicySpec{MaxHigh: intPtr(0)},
+				}
+				fakeClient := fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(imageScan, nsPolicy, otherNsPolicy).
+					WithStatusSubresource(imageScan).
+					Build()
+
+				_, updated := reconcileScan(fakeClient, &fakeAquaClient{})
+				Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePassed))
+				Expect(updated.Status.Policy).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Aqua assurance policies", func() {
+		reconcileAssurance := func(result *aqua.ScanResult, objs ...client.Object) *securityv1alpha1.ImageScan {
+			imageScan := newImageScan("", nil)
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(append(objs, imageScan)...).
+				WithStatusSubresource(imageScan).
+				Build()
+			r := &ImageScanReconciler{
+				Client:            fakeClient,
+				Scheme:            scheme,
+				AquaClient:        &fakeAquaClient{result: result},
+				AssurancePolicies: true,
+			}
+			key := client.ObjectKeyFromObject(imageScan)
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated securityv1alpha1.ImageScan
+			Expect(fakeClient.Get(ctx, key, &updated)).To(Succeed())
+			return &updated
+		}
+
+		It("should fail an image Aqua disallows and name the failed policies", func() {
+			updated := reconcileAssurance(&aqua.ScanResult{
+				Status:         aqua.StatusFound,
+				ScanStatus:     aqua.AquaScanStatusFinished,
+				Disallowed:     true,
+				FailedPolicies: []string{"Default", "Production"},
+			})
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+			Expect(updated.Status.Policy).To(Equal("AquaAssurancePolicy/Default,Production"))
+			Expect(updated.Status.FailedAssurancePolicies).To(Equal([]string{"Default", "Production"}))
+			ready := meta.FindStatusCondition(updated.Status.Conditions, securityv1alpha1.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Reason).To(Equal(securityv1alpha1.ReasonAssurancePolicyFailed))
+		})
+
+		It("should rescan an image Aqua disallowed and pass it once Aqua allows it", func() {
+			updated := reconcileAssurance(&aqua.ScanResult{
+				Status:     aqua.StatusFound,
+				ScanStatus: aqua.AquaScanStatusFinished,
+				Disallowed: true,
+			})
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+			Expect(retriesExhausted(updated)).To(BeFalse())
+			Expect(hasScanResults(updated)).To(BeTrue())
+
+			// The verdict is changed in the Aqua console before the next rescan
+			checked := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			updated.Status.LastCheckedTime = &checked
+			updated.ResourceVersion = ""
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(updated).
+				WithStatusSubresource(updated).
+				Build()
+			aquaClient := &fakeAquaClient{result: &aqua.ScanResult{
+				Status:     aqua.StatusFound,
+				ScanStatus: aqua.AquaScanStatusFinished,
+			}}
+			r := &ImageScanReconciler{
+				Client:            fakeClient,
+				Scheme:            scheme,
+				AquaClient:        aquaClient,
+				AssurancePolicies: true,
+				RescanInterval:    time.Hour,
+			}
+			key := client.ObjectKeyFromObject(updated)
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			var rescanned securityv1alpha1.ImageScan
+			Expect(fakeClient.Get(ctx, key, &rescanned)).To(Succeed())
+			Expect(aquaClient.getCalls).To(Equal(1))
+			Expect(rescanned.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePassed))
+		})
+
+		It("should pass an image Aqua allows when no ScanPolicy applies", func() {
+			updated := reconcileAssurance(&aqua.ScanResult{
+				Status:     aqua.StatusFound,
+				ScanStatus: aqua.AquaScanStatusFinished,
+			})
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePassed))
+			Expect(updated.Status.Message).To(ContainSubstring("allowed by Aqua"))
+		})
+
+		It("should still apply ScanPolicies to images Aqua allows", func() {
+			clusterPolicy := &securityv1alpha1.ClusterScanPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "baseline"},
+				Spec:       securityv1alpha1.ScanPolicySpec{MaxCritical: intPtr(0)},
+			}
+			updated := reconcileAssurance(&aqua.ScanResult{
+				Status:          aqua.StatusFound,
+				ScanStatus:      aqua.AquaScanStatusFinished,
+				Vulnerabilities: aqua.VulnerabilityCounts{Critical: 1, Total: 1},
+			}, clusterPolicy)
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+			Expect(updated.Status.Policy).To(Equal("ClusterScanPolicy/baseline"))
+		})
+	})
+
+	Describe("mapPolicyToImageScans", func() {
+		It("should only enqueue completed ImageScans in the policy's namespace", func() {
+			completed := newImageScan(securityv1alpha1.ScanPhaseRegistered, nil)
+			pending := newImageScan(securityv1alpha1.ScanPhasePending, nil)
+			pending.Name = "pending"
+			otherNs := newImageScan(securityv1alpha1.ScanPhasePassed, nil)
+			otherNs.Namespace = "other"
+
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(completed, pending, otherNs).
+				Build()
+			r := &ImageScanReconciler{Client: fakeClient, Scheme: scheme}
+
+			requests := r.mapPolicyToImageScans(ctx, &securityv1alpha1.ScanPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "default"},
+			})
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Name).To(Equal(completed.Name))
+
+			requests = r.mapPolicyToImageScans(ctx, &securityv1alpha1.ClusterScanPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "p"},
+			})
+			Expect(requests).To(HaveLen(2))
+		})
+	})
+})
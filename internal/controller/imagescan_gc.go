@@ -0,0 +1,323 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+const (
+	// AnnotationKeep prevents an ImageScan from being garbage-collected
+	AnnotationKeep = "scans.aquasec.community/keep"
+
+	// AnnotationUnreferencedSince records when garbage collection first found an
+	// ImageScan unreferenced. It is removed once the image is referenced again.
+	AnnotationUnreferencedSince = "scans.aquasec.community/unreferenced-since"
+)
+
+// imageScansCollected counts ImageScans deleted by garbage collection
+var imageScansCollected = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "aqua_scan_gate_imagescans_garbage_collected_total",
+	Help: "Number of unreferenced ImageScans deleted by garbage collection",
+})
+
+func init() {
+	metrics.Registry.MustRegister(imageScansCollected)
+}
+
+// ImageScanGarbageCollector periodically deletes ImageScans whose image no pod
+// (and optionally no workload template) has referenced for TTL.
+type ImageScanGarbageCollector struct {
+	client.Client
+	// Resolver maps tag-only pod images to cached digests (nil = match tags by image reference only)
+	Resolver *imageref.CachingResolver
+	// Namespace where ImageScan CRs are created (empty = same as pod)
+	ScanNamespace string
+	// TTL is how long an ImageScan must stay unreferenced before it is deleted
+	TTL time.Duration
+	// Interval between garbage collection runs
+	Interval time.Duration
+	// IncludeWorkloads also counts images in Deployment, StatefulSet, DaemonSet,
+	// ReplicaSet, Job and CronJob pod templates as references
+	IncludeWorkloads bool
+	// ClusterScans also collects ClusterImageScans no pod in any namespace references
+	ClusterScans bool
+
+	now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=imagescans,verbs=list;patch;delete
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=clusterimagescans,verbs=list;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=list
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets;replicasets,verbs=list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=list;watch
+
+// Start runs garbage collection every Interval until the context is cancelled.
+func (gc *ImageScanGarbageCollector) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("imagescan-gc")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		deleted, err := gc.Collect(ctx)
+		if err != nil {
+			logger.Error(err, "ImageScan garbage collection failed")
+			return
+		}
+		if deleted > 0 {
+			logger.Info("Garbage-collected unreferenced ImageScans", "deleted", deleted)
+		}
+	}, gc.Interval)
+	return nil
+}
+
+// NeedLeaderElection ensures only the leader deletes ImageScans.
+func (gc *ImageScanGarbageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Collect runs a single garbage collection pass and returns the number of ImageScans deleted.
+// Unreferenced ImageScans are first marked with AnnotationUnreferencedSince and deleted
+// once the mark is older than TTL; ImageScans annotated with AnnotationKeep are skipped.
+func (gc *ImageScanGarbageCollector) Collect(ctx context.Context) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "ImageScanGarbageCollector.Collect")
+	defer span.End()
+
+	logger := log.FromContext(ctx)
+	now := time.Now()
+	if gc.now != nil {
+		now = gc.now()
+	}
+
+	refs, err := gc.references(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to collect image references")
+		return 0, err
+	}
+
+	var imageScans securityv1alpha1.ImageScanList
+	if err := gc.List(ctx, &imageScans); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list ImageScans")
+		return 0, fmt.Errorf("listing ImageScans: %w", err)
+	}
+	scans := make([]scanObject, 0, len(imageScans.Items))
+	for i := range imageScans.Items {
+		scans = append(scans, &imageScans.Items[i])
+	}
+
+	if gc.ClusterScans {
+		var clusterScans securityv1alpha1.ClusterImageScanList
+		if err := gc.List(ctx, &clusterScans); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to list ClusterImageScans")
+			return 0, fmt.Errorf("listing ClusterImageScans: %w", err)
+		}
+		for i := range clusterScans.Items {
+			scans = append(scans, &clusterScans.Items[i])
+		}
+	}
+
+	deleted := 0
+	for _, imageScan := range scans {
+		annotations := imageScan.GetAnnotations()
+		if annotations[AnnotationKeep] == "true" {
+			continue
+		}
+
+		since, marked := annotations[AnnotationUnreferencedSince]
+		if refs.has(imageScan) {
+			if marked {
+				patch := client.MergeFrom(imageScan.DeepCopyObject().(client.Object))
+				delete(annotations, AnnotationUnreferencedSince)
+				imageScan.SetAnnotations(annotations)
+				if err := gc.Patch(ctx, imageScan, patch); client.IgnoreNotFound(err) != nil {
+					return deleted, fmt.Errorf("unmarking ImageScan %s/%s: %w", imageScan.GetNamespace(), imageScan.GetName(), err)
+				}
+			}
+			continue
+		}
+
+		if !marked {
+			patch := client.MergeFrom(imageScan.DeepCopyObject().(client.Object))
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[AnnotationUnreferencedSince] = now.UTC().Format(time.RFC3339)
+			imageScan.SetAnnotations(annotations)
+			if err := gc.Patch(ctx, imageScan, patch); client.IgnoreNotFound(err) != nil {
+				return deleted, fmt.Errorf("marking ImageScan %s/%s: %w", imageScan.GetNamespace(), imageScan.GetName(), err)
+			}
+			continue
+		}
+
+		sinceTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			logger.Info("Ignoring invalid unreferenced-since annotation",
+				"imageScan", imageScan.GetName(), "namespace", imageScan.GetNamespace(), "value", since)
+			continue
+		}
+		if now.Sub(sinceTime) < gc.TTL {
+			continue
+		}
+
+		logger.Info("Deleting unreferenced ImageScan",
+			"imageScan", imageScan.GetName(),
+			"namespace", imageScan.GetNamespace(),
+			"image", imageScan.ScanSpec().Image,
+			"unreferencedSince", since)
+		if err := gc.Delete(ctx, imageScan); client.IgnoreNotFound(err) != nil {
+			return deleted, fmt.Errorf("deleting ImageScan %s/%s: %w", imageScan.GetNamespace(), imageScan.GetName(), err)
+		}
+		imageScansCollected.Inc()
+		deleted++
+	}
+
+	span.SetAttributes(
+		attribute.Int("imagescan_count", len(scans)),
+		attribute.Int("deleted_count", deleted),
+	)
+	return deleted, nil
+}
+
+// imageReferences holds the digests and image references in use, per ImageScan namespace.
+// The empty namespace holds the references of all namespaces, for ClusterImageScans.
+type imageReferences map[string]map[string]bool
+
+func (refs imageReferences) add(namespace, key string) {
+	if key == "" {
+		return
+	}
+	for _, ns := range []string{namespace, ""} {
+		if refs[ns] == nil {
+			refs[ns] = map[string]bool{}
+		}
+		refs[ns][key] = true
+	}
+}
+
+// has returns true if the scan's digest or image reference is in use in its namespace.
+func (refs imageReferences) has(scan scanObject) bool {
+	keys := refs[scan.GetNamespace()]
+	spec := scan.ScanSpec()
+	return keys[spec.Digest] || keys[spec.Image]
+}
+
+// references collects the images used by pods and, if enabled, workload templates.
+func (gc *ImageScanGarbageCollector) references(ctx context.Context) (imageReferences, error) {
+	refs := imageReferences{}
+
+	var pods corev1.PodList
+	if err := gc.List(ctx, &pods); err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		namespace := gc.scanNamespace(pod.Namespace)
+		gc.addPodSpec(refs, namespace, &pod.Spec)
+
+		// Running containers report the digest they were pulled by
+		for _, statuses := range [][]corev1.ContainerStatus{
+			pod.Status.InitContainerStatuses,
+			pod.Status.ContainerStatuses,
+			pod.Status.EphemeralContainerStatuses,
+		} {
+			for _, status := range statuses {
+				if idx := strings.Index(status.ImageID, "@sha256:"); idx != -1 {
+					refs.add(namespace, status.ImageID[idx+1:])
+				}
+			}
+		}
+	}
+
+	if !gc.IncludeWorkloads {
+		return refs, nil
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := gc.List(ctx, &deployments); err != nil {
+		return nil, fmt.Errorf("listing deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		gc.addPodSpec(refs, gc.scanNamespace(deployments.Items[i].Namespace), &deployments.Items[i].Spec.Template.Spec)
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := gc.List(ctx, &statefulSets); err != nil {
+		return nil, fmt.Errorf("listing statefulsets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		gc.addPodSpec(refs, gc.scanNamespace(statefulSets.Items[i].Namespace), &statefulSets.Items[i].Spec.Template.Spec)
+	}
+
+	var daemonSets appsv1.DaemonSetList
+	if err := gc.List(ctx, &daemonSets); err != nil {
+		return nil, fmt.Errorf("listing daemonsets: %w", err)
+	}
+	for i := range daemonSets.Items {
+		gc.addPodSpec(refs, gc.scanNamespace(daemonSets.Items[i].Namespace), &daemonSets.Items[i].Spec.Template.Spec)
+	}
+
+	var replicaSets appsv1.ReplicaSetList
+	if err := gc.List(ctx, &replicaSets); err != nil {
+		return nil, fmt.Errorf("listing replicasets: %w", err)
+	}
+	for i := range replicaSets.Items {
+		gc.addPodSpec(refs, gc.scanNamespace(replicaSets.Items[i].Namespace), &replicaSets.Items[i].Spec.Template.Spec)
+	}
+
+	var jobs batchv1.JobList
+	if err := gc.List(ctx, &jobs); err != nil {
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+	for i := range jobs.Items {
+		gc.addPodSpec(refs, gc.scanNamespace(jobs.Items[i].Namespace), &jobs.Items[i].Spec.Template.Spec)
+	}
+
+	var cronJobs batchv1.CronJobList
+	if err := gc.List(ctx, &cronJobs); err != nil {
+		return nil, fmt.Errorf("listing cronjobs: %w", err)
+	}
+	for i := range cronJobs.Items {
+		gc.addPodSpec(refs, gc.scanNamespace(cronJobs.Items[i].Namespace), &cronJobs.Items[i].Spec.JobTemplate.Spec.Template.Spec)
+	}
+
+	return refs, nil
+}
+
+// addPodSpec records the digests of a pod spec's images. Tag-only images use the cached
+// digest; when none is cached, the image reference itself is recorded instead.
+func (gc *ImageScanGarbageCollector) addPodSpec(refs imageReferences, namespace string, spec *corev1.PodSpec) {
+	for _, img := range imageref.ExtractFromPodSpec(spec) {
+		if img.Digest == "" && gc.Resolver != nil {
+			img.Digest, _ = gc.Resolver.Lookup(img.Image)
+		}
+		if img.Digest == "" {
+			refs.add(namespace, img.Image)
+			continue
+		}
+		refs.add(namespace, img.Digest)
+	}
+}
+
+// scanNamespace returns the namespace holding ImageScans for a workload namespace.
+func (gc *ImageScanGarbageCollector) scanNamespace(namespace string) string {
+	if gc.ScanNamespace != "" {
+		return gc.ScanNamespace
+	}
+	return namespace
+}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+var _ = Describe("ImageScanGarbageCollector", func() {
+	const otherDigest = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+
+	var (
+		scheme *runtime.Scheme
+		ctx    context.Context
+		now    time.Time
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		ctx = context.Background()
+		now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	newScan := func(name, digest string, annotations map[string]string) *securityv1alpha1.ImageScan {
+		return &securityv1alpha1.ImageScan{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+			Spec: securityv1alpha1.ImageScanSpec{
+				Image:  "registry.example.com/app:v1",
+				Digest: digest,
+			},
+		}
+	}
+
+	unreferencedSince := func(ago time.Duration) map[string]string {
+		return map[string]string{AnnotationUnreferencedSince: now.Add(-ago).Format(time.RFC3339)}
+	}
+
+	newCollector := func(objs ...client.Object) (*ImageScanGarbageCollector, client.Client) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		return &ImageScanGarbageCollector{
+			Client: fakeClient,
+			TTL:    time.Hour,
+			now:    func() time.Time { return now },
+		}, fakeClient
+	}
+
+	getScan := func(c client.Client, name string) (*securityv1alpha1.ImageScan, error) {
+		var imageScan securityv1alpha1.ImageScan
+		err := c.Get(ctx, client.ObjectKey{Name: name, Namespace: "default"}, &imageScan)
+		return &imageScan, err
+	}
+
+	It("should mark unreferenced ImageScans before deleting them", func() {
+		gc, c := newCollector(newScan("unreferenced", testDigest, nil))
+
+		deleted, err := gc.Collect(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeZero())
+
+		imageScan, err := getScan(c, "unreferenced")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imageScan.Annotations).To(HaveKeyWithValue(AnnotationUnreferencedSince, now.Format(time.RFC3339)))
+	})
+
+	It("should delete ImageScans unreferenced for longer than the TTL", func() {
+		before := testutil.ToFloat64(imageScansCollected)
+		gc, c := newCollector(
+			newScan("expired", testDigest, unreferencedSince(2*time.Hour)),
+			newScan("recent", otherDigest, unreferencedSince(10*time.Minute)),
+		)
+
+		deleted, err := gc.Collect(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal(1))
+		Expect(testutil.ToFloat64(imageScansCollected)).To(Equal(before + 1))
+
+		_, err = getScan(c, "expired")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		_, err = getScan(c, "recent")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should keep ImageScans annotated with keep", func() {
+		annotations := unreferencedSince(48 * time.Hour)
+		annotations[AnnotationKeep] = "true"
+		gc, c := newCollector(newScan("kept", testDigest, annotations))
+
+		deleted, err := gc.Collect(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeZero())
+		_, err = getScan(c, "kept")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should unmark ImageScans that are referenced again", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/app:v1"}},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:    "app",
+					ImageID: "registry.example.com/app@" + testDigest,
+				}},
+			},
+		}
+		gc, c := newCollector(pod, newScan("running", testDigest, unreferencedSince(2*time.Hour)))
+
+		deleted, err := gc.Collect(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeZero())
+
+		imageScan, err := getScan(c, "running")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imageScan.Annotations).NotTo(HaveKey(AnnotationUnreferencedSince))
+	})
+
+	It("should only count workload templates as references when enabled", func() {
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/app@" + testDigest}},
+					},
+				},
+			},
+		}
+
+		gc, c := newCollector(deployment, newScan("templated", testDigest, unreferencedSince(2*time.Hour)))
+		gc.IncludeWorkloads = true
+		deleted, err := gc.Collect(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeZero())
+		_, err = getScan(c, "templated")
+		Expect(err).NotTo(HaveOccurred())
+
+		gc, c = newCollector(deployment, newScan("templated", testDigest, unreferencedSince(2*time.Hour)))
+		deleted, err = gc.Collect(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal(1))
+		_, err = getScan(c, "templated")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,259 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+// DefaultReportChunkSize is the default number of vulnerabilities stored per ImageScanReport.
+// It keeps each report well below the etcd object size limit.
+const DefaultReportChunkSize = 1000
+
+// DefaultVulnerabilityCacheSize is the default number of images whose per-CVE details are
+// kept in memory for scans without ImageScanReports
+const DefaultVulnerabilityCacheSize = 256
+
+// VulnerabilityCache keeps the per-CVE details fetched from Aqua by image digest, so
+// re-evaluating the policies of scans without ImageScanReports (ClusterImageScans, or
+// every scan when reports are disabled) doesn't fetch them again until Aqua rescans the
+// image. The oldest entry is evicted when it is full. A nil VulnerabilityCache caches
+// nothing.
+type VulnerabilityCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]vulnerabilityCacheEntry
+}
+
+// NewVulnerabilityCache returns a VulnerabilityCache of size images. A zero size uses
+// DefaultVulnerabilityCacheSize.
+func NewVulnerabilityCache(size int) *VulnerabilityCache {
+	if size <= 0 {
+		size = DefaultVulnerabilityCacheSize
+	}
+	return &VulnerabilityCache{size: size, entries: make(map[string]vulnerabilityCacheEntry)}
+}
+
+type vulnerabilityCacheEntry struct {
+	// scanned is when Aqua scanned the image the vulnerabilities were found in
+	scanned         time.Time
+	cached          time.Time
+	vulnerabilities []securityv1alpha1.Vulnerability
+}
+
+// get returns the vulnerabilities cached for key, if they are from the Aqua scan at scanned.
+func (c *VulnerabilityCache) get(key string, scanned time.Time) ([]securityv1alpha1.Vulnerability, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !entry.scanned.Equal(scanned) {
+		return nil, false
+	}
+	return entry.vulnerabilities, true
+}
+
+func (c *VulnerabilityCache) put(key string, scanned time.Time, vulnerabilities []securityv1alpha1.Vulnerability) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		oldest := ""
+		for k, entry := range c.entries {
+			if oldest == "" || entry.cached.Before(c.entries[oldest].cached) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = vulnerabilityCacheEntry{scanned: scanned, cached: time.Now(), vulnerabilities: vulnerabilities}
+}
+
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=imagescanreports,verbs=get;list;watch;create;update;patch;delete
+
+// syncReports stores the per-vulnerability details of a scanned image in ImageScanReports
+// owned by the ImageScan, reportChunkSize vulnerabilities per report, and deletes reports
+// left over from a previous, larger scan. It returns the stored vulnerabilities, or nil if
+// reports are disabled. ClusterImageScans have no
+// namespace to hold reports, so they are skipped.
+func (r *ImageScanReconciler) syncReports(ctx context.Context, scan scanObject) ([]securityv1alpha1.Vulnerability, error) {
+	if !r.Reports || scan.GetNamespace() == "" {
+		return nil, nil
+	}
+
+	spec, status := scan.ScanSpec(), scan.ScanStatus()
+	ctx, span := tracing.StartSpan(ctx, "ImageScanReconciler.syncReports",
+		trace.WithAttributes(
+			tracing.AttrImageName.String(spec.Image),
+			tracing.AttrImageDigest.String(spec.Digest),
+		),
+	)
+	defer span.End()
+
+	vulnerabilities, err := r.AquaClient.GetVulnerabilities(ctx, spec.Image, spec.Digest)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get vulnerabilities from Aqua")
+		return nil, fmt.Errorf("getting vulnerabilities: %w", err)
+	}
+
+	chunks := chunkVulnerabilities(vulnerabilities, r.reportChunkSize())
+	span.SetAttributes(
+		attribute.Int("vulnerability_count", len(vulnerabilities)),
+		attribute.Int("report_chunks", len(chunks)),
+	)
+
+	for i, chunk := range chunks {
+		report := &securityv1alpha1.ImageScanReport{}
+		report.Name = reportName(scan.GetName(), i)
+		report.Namespace = scan.GetNamespace()
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, report, func() error {
+			if report.Labels == nil {
+				report.Labels = map[string]string{}
+			}
+			report.Labels[securityv1alpha1.LabelImageScan] = scan.GetName()
+			report.Report = securityv1alpha1.ImageScanReportData{
+				Image:           spec.Image,
+				Digest:          spec.Digest,
+				Chunk:           i,
+				Chunks:          len(chunks),
+				Vulnerabilities: chunk,
+			}
+			return controllerutil.SetControllerReference(scan, report, r.Scheme)
+		}); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to write ImageScanReport")
+			return nil, fmt.Errorf("writing ImageScanReport %s: %w", report.Name, err)
+		}
+	}
+
+	// Delete chunks beyond the current report
+	var reports securityv1alpha1.ImageScanReportList
+	if err := r.List(ctx, &reports,
+		client.InNamespace(scan.GetNamespace()),
+		client.MatchingLabels{securityv1alpha1.LabelImageScan: scan.GetName()},
+	); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("listing ImageScanReports: %w", err)
+	}
+	for i := range reports.Items {
+		if reports.Items[i].Report.Chunk < len(chunks) {
+			continue
+		}
+		if err := r.Delete(ctx, &reports.Items[i]); client.IgnoreNotFound(err) != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("deleting ImageScanReport %s: %w", reports.Items[i].Name, err)
+		}
+	}
+
+	status.ReportChunks = len(chunks)
+	stored := make([]securityv1alpha1.Vulnerability, 0, len(vulnerabilities))
+	for _, chunk := range chunks {
+		stored = append(stored, chunk...)
+	}
+	return stored, nil
+}
+
+// reportChunkSize returns the number of vulnerabilities stored per ImageScanReport.
+func (r *ImageScanReconciler) reportChunkSize() int {
+	if r.ReportChunkSize > 0 {
+		return r.ReportChunkSize
+	}
+	return DefaultReportChunkSize
+}
+
+// reportName returns the name of an ImageScan's report chunk.
+func reportName(scanName string, chunk int) string {
+	return scanName + "-" + strconv.Itoa(chunk)
+}
+
+// chunkVulnerabilities converts Aqua vulnerabilities and splits them into chunks of at
+// most size entries. An image without vulnerabilities still gets one empty chunk, so
+// that its report shows it was checked.
+func chunkVulnerabilities(vulnerabilities []aqua.Vulnerability, size int) [][]securityv1alpha1.Vulnerability {
+	chunks := [][]securityv1alpha1.Vulnerability{nil}
+	for _, v := range vulnerabilities {
+		last := len(chunks) - 1
+		if len(chunks[last]) == size {
+			chunks = append(chunks, nil)
+			last++
+		}
+		chunks[last] = append(chunks[last], reportVulnerability(v))
+	}
+	return chunks
+}
+
+// reportVulnerability converts an Aqua vulnerability to its ImageScanReport form.
+func reportVulnerability(v aqua.Vulnerability) securityv1alpha1.Vulnerability {
+	return securityv1alpha1.Vulnerability{
+		ID:               v.Name,
+		Package:          v.Package,
+		PackageType:      v.PackageType,
+		InstalledVersion: v.InstalledVersion,
+		FixedVersion:     v.FixedVersion,
+		Severity:         v.Severity,
+		Score:            strconv.FormatFloat(v.Score, 'f', -1, 64),
+		Acknowledged:     v.Acknowledged,
+	}
+}
+
+// scanVulnerabilities returns the per-CVE details of a scan from its ImageScanReports,
+// or from Aqua when it has none, caching them until Aqua rescans the image.
+func (r *ImageScanReconciler) scanVulnerabilities(ctx context.Context, scan scanObject) ([]securityv1alpha1.Vulnerability, error) {
+	if scan.GetNamespace() != "" && scan.ScanStatus().ReportChunks > 0 {
+		var reports securityv1alpha1.ImageScanReportList
+		if err := r.List(ctx, &reports,
+			client.InNamespace(scan.GetNamespace()),
+			client.MatchingLabels{securityv1alpha1.LabelImageScan: scan.GetName()},
+		); err != nil {
+			return nil, fmt.Errorf("listing ImageScanReports: %w", err)
+		}
+		var vulnerabilities []securityv1alpha1.Vulnerability
+		for _, report := range reports.Items {
+			vulnerabilities = append(vulnerabilities, report.Report.Vulnerabilities...)
+		}
+		return vulnerabilities, nil
+	}
+
+	spec, status := scan.ScanSpec(), scan.ScanStatus()
+	key := spec.Digest
+	if key == "" {
+		key = spec.Image
+	}
+	// API round trips keep whole seconds
+	var scanned time.Time
+	if status.LastScanTime != nil {
+		scanned = status.LastScanTime.Truncate(time.Second)
+	}
+	if cached, ok := r.VulnerabilityCache.get(key, scanned); ok {
+		return cached, nil
+	}
+
+	vulnerabilities, err := r.AquaClient.GetVulnerabilities(ctx, spec.Image, spec.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("getting vulnerabilities: %w", err)
+	}
+	converted := make([]securityv1alpha1.Vulnerability, 0, len(vulnerabilities))
+	for _, v := range vulnerabilities {
+		converted = append(converted, reportVulnerability(v))
+	}
+	r.VulnerabilityCache.put(key, scanned, converted)
+	return converted, nil
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+)
+
+var _ = Describe("ImageScanReports", func() {
+	const scanName = "sha256-0123456789abcdef"
+
+	var (
+		scheme *runtime.Scheme
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		ctx = context.Background()
+	})
+
+	vulnerabilities := func(n int) []aqua.Vulnerability {
+		vulns := make([]aqua.Vulnerability, n)
+		for i := range vulns {
+			vulns[i] = aqua.Vulnerability{
+				Name:     fmt.Sprintf("CVE-2024-%04d", i),
+				Package:  "openssl",
+				Severity: "high",
+				Score:    7.5,
+			}
+		}
+		return vulns
+	}
+
+	newClient := func(objs ...client.Object) client.Client {
+		imageScan := &securityv1alpha1.ImageScan{
+			ObjectMeta: metav1.ObjectMeta{Name: scanName, Namespace: "default", UID: "scan-uid"},
+			Spec: securityv1alpha1.ImageScanSpec{
+				Image:  "nginx:1.25",
+				Digest: testDigest,
+			},
+			Status: securityv1alpha1.ImageScanStatus{Phase: securityv1alpha1.ScanPhasePending},
+		}
+		return fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(append(objs, imageScan)...).
+			WithStatusSubresource(imageScan).
+			Build()
+	}
+
+	reconcileScan := func(c client.Client, aquaClient *fakeAquaClient) *securityv1alpha1.ImageScan {
+		r := &ImageScanReconciler{
+			Client:          c,
+			Scheme:          scheme,
+			AquaClient:      aquaClient,
+			Reports:         true,
+			ReportChunkSize: 2,
+		}
+		key := types.NamespacedName{Name: scanName, Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated securityv1alpha1.ImageScan
+		Expect(c.Get(ctx, key, &updated)).To(Succeed())
+		return &updated
+	}
+
+	listReports := func(c client.Client) []securityv1alpha1.ImageScanReport {
+		var reports securityv1alpha1.ImageScanReportList
+		Expect(c.List(ctx, &reports, client.MatchingLabels{
+			securityv1alpha1.LabelImageScan: scanName,
+		})).To(Succeed())
+		return reports.Items
+	}
+
+	It("should split the vulnerabilities across reports owned by the ImageScan", func() {
+		c := newClient()
+		updated := reconcileScan(c, &fakeAquaClient{
+			result:          &aqua.ScanResult{Status: aqua.StatusFound},
+			vulnerabilities: vulnerabilities(5),
+		})
+		Expect(updated.Status.ReportChunks).To(Equal(3))
+
+		reports := listReports(c)
+		Expect(reports).To(HaveLen(3))
+		total := 0
+		for _, report := range reports {
+			Expect(report.Report.Chunks).To(Equal(3))
+			Expect(report.Report.Digest).To(Equal(testDigest))
+			Expect(metav1.IsControlledBy(&report, updated)).To(BeTrue())
+			total += len(report.Report.Vulnerabilities)
+		}
+		Expect(total).To(Equal(5))
+		Expect(reports[0].Report.Vulnerabilities[0]).To(Equal(securityv1alpha1.Vulnerability{
+			ID:       "CVE-2024-0000",
+			Package:  "openssl",
+			Severity: "high",
+			Score:    "7.5",
+		}))
+	})
+
+	It("should delete reports left over from a larger scan", func() {
+		stale := &securityv1alpha1.ImageScanReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      reportName(scanName, 4),
+				Namespace: "default",
+				Labels:    map[string]string{securityv1alpha1.LabelImageScan: scanName},
+			},
+			Report: securityv1alpha1.ImageScanReportData{Chunk: 4, Chunks: 5},
+		}
+		c := newClient(stale)
+		updated := reconcileScan(c, &fakeAquaClient{
+			result:          &aqua.ScanResult{Status: aqua.StatusFound},
+			vulnerabilities: vulnerabilities(1),
+		})
+		Expect(updated.Status.ReportChunks).To(Equal(1))
+		Expect(listReports(c)).To(HaveLen(1))
+	})
+
+	It("should keep the verdict when the vulnerabilities cannot be fetched", func() {
+		c := newClient()
+		updated := reconcileScan(c, &fakeAquaClient{
+			result:             &aqua.ScanResult{Status: aqua.StatusFound},
+			vulnerabilitiesErr: errors.New("connection refused"),
+		})
+		Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseRegistered))
+		Expect(updated.Status.ReportChunks).To(BeZero())
+		Expect(listReports(c)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/vex"
+)
+
+// LabelOpenVEX marks ConfigMaps in the VEX namespace whose data values are OpenVEX documents
+const LabelOpenVEX = "scans.aquasec.community/openvex"
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// vexDocuments returns the OpenVEX documents loaded from files and from the ConfigMaps
+// labelled LabelOpenVEX in VEXNamespace. A ConfigMap value that isn't a valid document
+// is logged and skipped, so one bad document doesn't block every evaluation.
+func (r *ImageScanReconciler) vexDocuments(ctx context.Context) ([]vex.Document, error) {
+	documents := r.VEXDocuments
+	if r.VEXNamespace == "" {
+		return documents, nil
+	}
+
+	var configMaps corev1.ConfigMapList
+	if err := r.List(ctx, &configMaps,
+		client.InNamespace(r.VEXNamespace),
+		client.MatchingLabels{LabelOpenVEX: "true"},
+	); err != nil {
+		return nil, fmt.Errorf("listing OpenVEX ConfigMaps: %w", err)
+	}
+
+	documents = append([]vex.Document(nil), documents...)
+	for _, cm := range configMaps.Items {
+		for key, data := range cm.Data {
+			doc, err := vex.Parse([]byte(data))
+			if err != nil {
+				log.FromContext(ctx).Error(err, "Skipping invalid OpenVEX document",
+					"configMap", cm.Name, "key", key)
+				continue
+			}
+			documents = append(documents, *doc)
+		}
+	}
+	return documents, nil
+}
+
+// isVEXConfigMap selects the ConfigMaps vexDocuments reads.
+func (r *ImageScanReconciler) isVEXConfigMap() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == r.VEXNamespace && obj.GetLabels()[LabelOpenVEX] == "true"
+	})
+}
+
+// mapVEXToImageScans re-evaluates every ImageScan with results when an OpenVEX ConfigMap changes.
+func (r *ImageScanReconciler) mapVEXToImageScans(ctx context.Context, _ client.Object) []reconcile.Request {
+	var imageScans securityv1alpha1.ImageScanList
+	if err := r.List(ctx, &imageScans); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ImageScans for OpenVEX mapping")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, imageScan := range imageScans.Items {
+		if !hasScanResults(&imageScan) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      imageScan.Name,
+				Namespace: imageScan.Namespace,
+			},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+)
+
+var _ = Describe("OpenVEX", func() {
+	const (
+		scanName     = "sha256-0123456789abcdef"
+		vexNamespace = "aqua-scan-gate-system"
+		vexDocument  = `{
+  "@context": "https://openvex.dev/ns/v0.2.0",
+  "@id": "https://example.com/vex/nginx-1",
+  "author": "Platform Team",
+  "version": 1,
+  "statements": [{
+    "vulnerability": {"name": "CVE-2024-0001"},
+    "products": [{"@id": "pkg:oci/nginx@sha256%3A0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}],
+    "status": "not_affected",
+    "justification": "vulnerable_code_not_in_execute_path"
+  }]
+}`
+	)
+
+	var (
+		scheme *runtime.Scheme
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		ctx = context.Background()
+	})
+
+	vexConfigMap := func(namespace string, labels map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx-vex", Namespace: namespace, Labels: labels},
+			Data: map[string]string{
+				"nginx.json":  vexDocument,
+				"broken.json": "{",
+			},
+		}
+	}
+
+	reconcileScan := func(objs ...client.Object) *securityv1alpha1.ImageScan {
+		imageScan := &securityv1alpha1.ImageScan{
+			ObjectMeta: metav1.ObjectMeta{Name: scanName, Namespace: "default"},
+			Spec:       securityv1alpha1.ImageScanSpec{Image: "nginx:1.25", Digest: testDigest},
+			Status:     securityv1alpha1.ImageScanStatus{Phase: securityv1alpha1.ScanPhasePending},
+		}
+		clusterPolicy := &securityv1alpha1.ClusterScanPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "baseline"},
+			Spec:       securityv1alpha1.ScanPolicySpec{MaxCritical: intPtr(0)},
+		}
+		c := fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(append(objs, imageScan, clusterPolicy)...).
+			WithStatusSubresource(imageScan).
+			Build()
+		r := &ImageScanReconciler{
+			Client: c,
+			Scheme: scheme,
+			AquaClient: &fakeAquaClient{
+				result: &aqua.ScanResult{
+					Status:          aqua.StatusFound,
+					ScanStatus:      aqua.AquaScanStatusFinished,
+					Vulnerabilities: aqua.VulnerabilityCounts{Critical: 1, Total: 1},
+				},
+				vulnerabilities: []aqua.Vulnerability{
+					{Name: "CVE-2024-0001", Package: "openssl", Severity: "critical"},
+				},
+			},
+			Reports:      true,
+			VEXNamespace: vexNamespace,
+		}
+		key := types.NamespacedName{Name: scanName, Namespace: "default"}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated securityv1alpha1.ImageScan
+		Expect(c.Get(ctx, key, &updated)).To(Succeed())
+		return &updated
+	}
+
+	It("should suppress not_affected findings and list them in the status", func() {
+		updated := reconcileScan(vexConfigMap(vexNamespace, map[string]string{LabelOpenVEX: "true"}))
+		Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePassed))
+		Expect(updated.Status.Suppressed).To(Equal([]securityv1alpha1.SuppressedVulnerability{{
+			ID:            "CVE-2024-0001",
+			Package:       "openssl",
+			Justification: "vulnerable_code_not_in_execute_path",
+			Document:      "https://example.com/vex/nginx-1",
+		}}))
+		Expect(updated.Status.Message).To(ContainSubstring("not affected per OpenVEX"))
+	})
+
+	It("should ignore ConfigMaps outside the VEX namespace or without the label", func() {
+		updated := reconcileScan(
+			vexConfigMap("default", map[string]string{LabelOpenVEX: "true"}),
+		)
+		Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+		Expect(updated.Status.Suppressed).To(BeEmpty())
+
+		updated = reconcileScan(vexConfigMap(vexNamespace, nil))
+		Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+	})
+})
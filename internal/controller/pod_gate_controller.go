@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
@@ -15,6 +16,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -22,7 +24,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/enforcement"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageexclude"
 	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/policy"
+	"github.com/richardmsong/aqua-scan-gate/pkg/scope"
 	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
 )
 
@@ -36,6 +42,9 @@ const (
 	// AnnotationBypassScan allows bypassing the scan gate
 	AnnotationBypassScan = "scans.aquasec.community/bypass-scan"
 
+	// AnnotationBypassedBy records the user who created a pod with the bypass annotation
+	AnnotationBypassedBy = "scans.aquasec.community/bypassed-by"
+
 	// LabelManagedBy identifies pods managed by this controller
 	LabelManagedBy = "scans.aquasec.community/managed-by"
 
@@ -50,13 +59,32 @@ type PodGateReconciler struct {
 	Recorder record.EventRecorder
 	// Namespace where ImageScan CRs are created (empty = same as pod)
 	ScanNamespace string
-	// Namespaces to exclude from scanning
-	ExcludedNamespaces map[string]bool
+	// Namespaces decides which namespaces are gated, shared with the webhooks (nil = all)
+	Namespaces *scope.Namespaces
+	// Resolver resolves tag-only images to digests (nil = use image references as-is)
+	Resolver *imageref.CachingResolver
+	// APIReader reads pull secrets and service accounts without caching them
+	// cluster-wide (nil = use Client)
+	APIReader client.Reader
+	// ClusterScans gates pods on a ClusterImageScan per digest instead of an ImageScan
+	// per namespace, so each image is looked up in Aqua once
+	ClusterScans bool
+	// ClusterScanReferences also creates an ImageScan referencing the ClusterImageScan in
+	// ScanNamespace (or the pod's namespace), and gates on it so namespace ScanPolicies apply
+	ClusterScanReferences bool
+	// Enforcement releases gated pods at once in warn and audit mode namespaces (nil = enforce)
+	Enforcement *enforcement.Modes
+	// Exclusions are the images exempt from scanning, shared with the webhooks. Pods
+	// only wait on their other images.
+	Exclusions *imageexclude.Exclusions
 }
 
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets;serviceaccounts,verbs=get
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=scans.aquasec.community,resources=imagescans,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=clusterimagescans,verbs=get;list;watch;create
 
 func (r *PodGateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	ctx, span := tracing.StartSpan(ctx, "PodGateReconciler.Reconcile",
@@ -69,27 +97,39 @@ func (r *PodGateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	logger := log.FromContext(ctx)
 
-	// Skip excluded namespaces
-	if r.ExcludedNamespaces[req.Namespace] {
-		span.SetAttributes(attribute.Bool("excluded_namespace", true))
-		return ctrl.Result{}, nil
-	}
-
 	var pod corev1.Pod
 	if err := r.Get(ctx, req.NamespacedName, &pod); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// Skip if pod doesn't have our gate
-	if !hasSchedulingGate(&pod, SchedulingGateName) {
-		span.SetAttributes(attribute.Bool("has_scheduling_gate", false))
+	// Skip excluded namespaces, releasing pods gated before their namespace opted out
+	if r.Namespaces.IsExcluded(ctx, req.Namespace) {
+		span.SetAttributes(attribute.Bool("excluded_namespace", true))
+		if !hasSchedulingGate(&pod, SchedulingGateName) {
+			return ctrl.Result{}, nil
+		}
+		logger.Info("Namespace excluded, removing gate", "pod", pod.Name)
+		removeSchedulingGate(&pod, SchedulingGateName)
+		if err := r.Update(ctx, &pod); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to update pod")
+			return ctrl.Result{}, err
+		}
+		if r.Recorder != nil {
+			r.Recorder.Event(&pod, corev1.EventTypeNormal, "NamespaceExcluded", "Scan gate removed because the namespace is excluded from scanning")
+		}
 		return ctrl.Result{}, nil
 	}
 
-	span.SetAttributes(attribute.Bool("has_scheduling_gate", true))
+	// Skip if pod doesn't have our gate and isn't being audited
+	gated := hasSchedulingGate(&pod, SchedulingGateName)
+	span.SetAttributes(attribute.Bool("has_scheduling_gate", gated))
+	if !gated && !isAuditPending(&pod) {
+		return ctrl.Result{}, nil
+	}
 
 	// Check for bypass annotation
-	if pod.Annotations != nil && pod.Annotations[AnnotationBypassScan] == "true" {
+	if gated && pod.Annotations[AnnotationBypassScan] == "true" {
 		span.SetAttributes(attribute.Bool("bypassed", true))
 		logger.Info("Bypass annotation found, removing gate", "pod", pod.Name)
 		removeSchedulingGate(&pod, SchedulingGateName)
@@ -99,24 +139,89 @@ func (r *PodGateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			return ctrl.Result{}, err
 		}
 		if r.Recorder != nil {
-			r.Recorder.Event(&pod, corev1.EventTypeWarning, "ScanBypassed", "Security scan bypassed via annotation")
+			message := "Security scan bypassed via annotation"
+			if user := pod.Annotations[AnnotationBypassedBy]; user != "" {
+				message += " by " + user
+			}
+			r.Recorder.Event(&pod, corev1.EventTypeWarning, "ScanBypassed", message)
 		}
 		return ctrl.Result{}, nil
 	}
 
-	// Extract all images from pod spec
-	images := imageref.ExtractFromPod(&pod)
+	// Gated pods in warn or audit mode namespaces (e.g., gated before the mode changed)
+	// are released at once and audited instead
+	if gated {
+		if mode := r.Enforcement.Mode(ctx, pod.Namespace); mode != enforcement.Enforce {
+			span.SetAttributes(attribute.String("enforcement_mode", string(mode)))
+			logger.Info("Releasing gate for audit", "pod", pod.Name, "mode", mode)
+			removeSchedulingGate(&pod, SchedulingGateName)
+			if pod.Labels == nil {
+				pod.Labels = map[string]string{}
+			}
+			pod.Labels[enforcement.LabelAudit] = enforcement.AuditPending
+			if pod.Annotations == nil {
+				pod.Annotations = map[string]string{}
+			}
+			pod.Annotations[enforcement.AnnotationMode] = string(mode)
+			if err := r.Update(ctx, &pod); err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "Failed to update pod")
+				return ctrl.Result{}, err
+			}
+			if r.Recorder != nil {
+				r.Recorder.Eventf(&pod, corev1.EventTypeNormal, "GateReleased",
+					"Scan gate released in %s mode; the pod will be audited", mode)
+			}
+			gated = false
+		}
+	}
+
+	// Extract the images to scan from pod spec, skipping images exempt from scanning
+	images := r.Exclusions.Filter(imageref.ExtractFromPod(&pod))
 	span.SetAttributes(attribute.Int("image_count", len(images)))
 
 	if len(images) == 0 {
-		logger.Info("No images found in pod, removing gate", "pod", pod.Name)
+		if !gated {
+			return ctrl.Result{}, r.recordAudit(ctx, &pod, nil, true)
+		}
+		logger.Info("No images to scan in pod, removing gate", "pod", pod.Name)
 		removeSchedulingGate(&pod, SchedulingGateName)
 		return ctrl.Result{}, r.Update(ctx, &pod)
 	}
 
-	// Check/create ImageScan for each image
+	// Resolve tag-only images to digests; ImageScans require a digest
+	images, err := r.scannedImages(ctx, &pod, images)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to resolve image digests")
+		logger.Error(err, "Failed to resolve image digests", "pod", pod.Name)
+		if r.Recorder != nil && gated {
+			r.Recorder.Eventf(&pod, corev1.EventTypeWarning, "DigestResolutionFailed",
+				"Failed to resolve image digest: %s", err.Error())
+		}
+		return ctrl.Result{RequeueAfter: calculateBackoff(0)}, nil
+	}
+
+	// Scan policy rules depend on the pod, so they are evaluated here rather than on the
+	// ImageScan, which is shared by every pod running the image
+	rules, namespace, err := r.ruleContext(ctx, &pod)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to load scan policy rules")
+		return ctrl.Result{}, err
+	}
+	span.SetAttributes(attribute.Int("rule_policy_count", len(rules)))
+
+	// Check/create ImageScan for each image. Audited pods only get events once their
+	// verdict is known, from recordAudit.
 	allPassed := true
 	var pendingImages []string
+	var failedImages []string
+	var violations []string
+	recorder := r.Recorder
+	if !gated {
+		recorder = nil
+	}
 
 	for _, img := range images {
 		imageCtx, imageSpan := tracing.StartSpan(ctx, "CheckImageScan",
@@ -126,68 +231,56 @@ func (r *PodGateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			),
 		)
 
-		scanName := imageref.ScanName(img)
-		scanNamespace := r.ScanNamespace
-		if scanNamespace == "" {
-			scanNamespace = pod.Namespace
-		}
-
-		var imageScan securityv1alpha1.ImageScan
-		err := r.Get(imageCtx, types.NamespacedName{
-			Name:      scanName,
-			Namespace: scanNamespace,
-		}, &imageScan)
-
-		if apierrors.IsNotFound(err) {
-			// Create ImageScan CR
-			imageSpan.SetAttributes(attribute.Bool("created_new_scan", true))
-			logger.Info("Creating ImageScan", "image", img.Image, "name", scanName)
-			imageScan = securityv1alpha1.ImageScan{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      scanName,
-					Namespace: scanNamespace,
-					Labels: map[string]string{
-						"security.example.com/image-hash": imageref.HashString(img.Image)[:16],
-					},
-				},
-				Spec: securityv1alpha1.ImageScanSpec{
-					Image:  img.Image,
-					Digest: img.Digest,
-				},
-			}
-			if err := r.Create(imageCtx, &imageScan); err != nil {
-				if !apierrors.IsAlreadyExists(err) {
-					imageSpan.RecordError(err)
-					imageSpan.SetStatus(codes.Error, "Failed to create ImageScan")
-					logger.Error(err, "Failed to create ImageScan")
-					imageSpan.End()
-					return ctrl.Result{}, err
-				}
-			}
-			allPassed = false
-			pendingImages = append(pendingImages, img.Image)
-			imageSpan.End()
-			continue
-		} else if err != nil {
+		status, err := r.scanStatus(imageCtx, &pod, img)
+		if err != nil {
 			imageSpan.RecordError(err)
 			imageSpan.SetStatus(codes.Error, "Failed to get ImageScan")
-			logger.Error(err, "Failed to get ImageScan")
+			logger.Error(err, "Failed to get ImageScan", "image", img.Image)
 			imageSpan.End()
 			return ctrl.Result{}, err
 		}
 
 		// Check scan status
-		imageSpan.SetAttributes(tracing.AttrScanPhase.String(string(imageScan.Status.Phase)))
-		switch imageScan.Status.Phase {
-		case securityv1alpha1.ScanPhaseRegistered:
-			// Good, continue checking other images
-			imageSpan.End()
-			continue
+		imageSpan.SetAttributes(tracing.AttrScanPhase.String(string(status.Phase)))
+		switch status.Phase {
+		case securityv1alpha1.ScanPhaseRegistered, securityv1alpha1.ScanPhasePassed:
+			if len(rules) == 0 {
+				// Good, continue checking other images
+				imageSpan.End()
+				continue
+			}
+			input := policy.NewRuleInput(&pod, namespace, img.Image, img.Digest, status)
+			result := policy.EvaluateRules(rules, input)
+			imageSpan.SetAttributes(attribute.Bool("rules_passed", result.Passed))
+			if result.Passed {
+				imageSpan.End()
+				continue
+			}
+			// Rule violated - keep the gate and say why
+			violation := fmt.Sprintf("Image %s violates %s: %s", img.Image, result.Policy, result.Message)
+			if recorder != nil {
+				recorder.Event(&pod, corev1.EventTypeWarning, "RuleViolation", violation)
+			}
+			allPassed = false
+			failedImages = append(failedImages, img.Image)
+			violations = append(violations, violation)
+		case securityv1alpha1.ScanPhaseFailed:
+			// Policy violated or retries exhausted - keep the gate and say why
+			violation := fmt.Sprintf("Image %s scan failed: %s", img.Image, status.Message)
+			if status.Policy != "" {
+				violation = fmt.Sprintf("Image %s violates %s: %s", img.Image, status.Policy, status.Message)
+			}
+			if recorder != nil {
+				recorder.Event(&pod, corev1.EventTypeWarning, "ScanFailed", violation)
+			}
+			allPassed = false
+			failedImages = append(failedImages, img.Image)
+			violations = append(violations, violation)
 		case securityv1alpha1.ScanPhaseError:
 			// Error occurred - don't remove gate, emit event
-			if r.Recorder != nil {
-				r.Recorder.Eventf(&pod, corev1.EventTypeWarning, "ScanError",
-					"Image %s scan error: %s", img.Image, imageScan.Status.Message)
+			if recorder != nil {
+				recorder.Eventf(&pod, corev1.EventTypeWarning, "ScanError",
+					"Image %s scan error: %s", img.Image, status.Message)
 			}
 			allPassed = false
 			pendingImages = append(pendingImages, img.Image)
@@ -202,8 +295,13 @@ func (r *PodGateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	span.SetAttributes(
 		attribute.Bool("all_passed", allPassed),
 		attribute.Int("pending_images_count", len(pendingImages)),
+		attribute.Int("failed_images_count", len(failedImages)),
 	)
 
+	if !gated {
+		return ctrl.Result{}, r.recordAudit(ctx, &pod, violations, allPassed)
+	}
+
 	if allPassed {
 		logger.Info("All images passed scan, removing gate", "pod", pod.Name)
 		removeSchedulingGate(&pod, SchedulingGateName)
@@ -227,6 +325,249 @@ func (r *PodGateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	return ctrl.Result{}, nil
 }
 
+// recordAudit labels an audited pod with its verdict once it is known: would-block,
+// with an event and a metric, if any image failed, or passed once every image passed.
+func (r *PodGateReconciler) recordAudit(ctx context.Context, pod *corev1.Pod, violations []string, allPassed bool) error {
+	verdict := enforcement.AuditPassed
+	if len(violations) > 0 {
+		verdict = enforcement.AuditWouldBlock
+	} else if !allPassed {
+		// Wait for the pending scans
+		return nil
+	}
+
+	patch := client.MergeFrom(pod.DeepCopy())
+	pod.Labels[enforcement.LabelAudit] = verdict
+	if err := r.Patch(ctx, pod, patch); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if verdict != enforcement.AuditWouldBlock {
+		return nil
+	}
+
+	mode := enforcement.Mode(pod.Annotations[enforcement.AnnotationMode])
+	if mode == "" {
+		mode = enforcement.Audit
+	}
+	enforcement.RecordWouldBlock(mode, pod.Namespace, "scan")
+	log.FromContext(ctx).Info("Pod would have been blocked", "pod", pod.Name, "mode", mode, "violations", violations)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(pod, corev1.EventTypeWarning, "WouldHaveBlocked",
+			"Pod would have been blocked in enforce mode: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// isAuditPending returns true for pods admitted ungated whose audit verdict isn't known yet.
+func isAuditPending(pod *corev1.Pod) bool {
+	return pod.Labels[enforcement.LabelAudit] == enforcement.AuditPending
+}
+
+// trackedPods lists the gated pods and the pods still being audited.
+func (r *PodGateReconciler) trackedPods(ctx context.Context, opts ...client.ListOption) ([]corev1.Pod, error) {
+	var gated corev1.PodList
+	if err := r.List(ctx, &gated, append([]client.ListOption{
+		client.MatchingFields{IndexFieldSchedulingGate: SchedulingGateName},
+	}, opts...)...); err != nil {
+		return nil, err
+	}
+	var audited corev1.PodList
+	if err := r.List(ctx, &audited, append([]client.ListOption{
+		client.MatchingLabels{enforcement.LabelAudit: enforcement.AuditPending},
+	}, opts...)...); err != nil {
+		return nil, err
+	}
+
+	pods := gated.Items
+	for _, pod := range audited.Items {
+		if !hasSchedulingGate(&pod, SchedulingGateName) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// StartScans resolves the pod's images and creates the scans that will gate it, without
+// waiting for their verdicts. The webhook calls it at admission, so Aqua is asked about
+// the images before the gated pod is first reconciled.
+func (r *PodGateReconciler) StartScans(ctx context.Context, pod *corev1.Pod) error {
+	ctx, span := tracing.StartSpan(ctx, "PodGateReconciler.StartScans",
+		trace.WithAttributes(
+			tracing.AttrPodName.String(pod.Name),
+			tracing.AttrPodNamespace.String(pod.Namespace),
+		),
+	)
+	defer span.End()
+
+	images, err := r.scannedImages(ctx, pod, imageref.ExtractFromPod(pod))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to resolve image digests")
+		return err
+	}
+	span.SetAttributes(attribute.Int("image_count", len(images)))
+	for _, img := range images {
+		if _, err := r.scanStatus(ctx, pod, img); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to create ImageScan")
+			return fmt.Errorf("creating scan for %s: %w", img.Image, err)
+		}
+	}
+	return nil
+}
+
+// ruleContext returns the ClusterScanPolicies and the pod's namespace ScanPolicies that
+// have CEL rules, and the pod's namespace if there are any. A namespace that can't be
+// found is left nil, so rules see no namespace labels.
+func (r *PodGateReconciler) ruleContext(ctx context.Context, pod *corev1.Pod) ([]policy.Policy, *corev1.Namespace, error) {
+	var clusterPolicies securityv1alpha1.ClusterScanPolicyList
+	if err := r.List(ctx, &clusterPolicies); err != nil {
+		return nil, nil, fmt.Errorf("listing cluster scan policies: %w", err)
+	}
+	var policies securityv1alpha1.ScanPolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(pod.Namespace)); err != nil {
+		return nil, nil, fmt.Errorf("listing scan policies: %w", err)
+	}
+
+	rules := policy.WithRules(policy.FromScanPolicies(clusterPolicies.Items, policies.Items))
+	if len(rules) == 0 {
+		return nil, nil, nil
+	}
+
+	var namespace corev1.Namespace
+	if err := r.Get(ctx, types.NamespacedName{Name: pod.Namespace}, &namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return rules, nil, nil
+		}
+		return nil, nil, fmt.Errorf("getting namespace %s: %w", pod.Namespace, err)
+	}
+	return rules, &namespace, nil
+}
+
+// scanStatus returns the scan status gating img in the pod, creating the ImageScan (or
+// ClusterImageScan and namespaced reference) if it does not exist yet. A newly created
+// scan has an empty status, which counts as pending.
+func (r *PodGateReconciler) scanStatus(ctx context.Context, pod *corev1.Pod, img imageref.ImageRef) (*securityv1alpha1.ImageScanStatus, error) {
+	scanName := imageref.ScanName(img)
+	spec := securityv1alpha1.ImageScanSpec{
+		Image:  img.Image,
+		Digest: img.Digest,
+	}
+
+	if r.ClusterScans {
+		clusterScan := &securityv1alpha1.ClusterImageScan{
+			ObjectMeta: r.scanObjectMeta(scanName, "", img),
+			Spec:       spec,
+		}
+		if err := r.getOrCreateScan(ctx, clusterScan); err != nil {
+			return nil, err
+		}
+		if !r.ClusterScanReferences {
+			return &clusterScan.Status, nil
+		}
+		spec.ClusterScanRef = scanName
+	}
+
+	scanNamespace := r.ScanNamespace
+	if scanNamespace == "" {
+		scanNamespace = pod.Namespace
+	}
+	imageScan := &securityv1alpha1.ImageScan{
+		ObjectMeta: r.scanObjectMeta(scanName, scanNamespace, img),
+		Spec:       spec,
+	}
+	if err := r.getOrCreateScan(ctx, imageScan); err != nil {
+		return nil, err
+	}
+	return &imageScan.Status, nil
+}
+
+func (r *PodGateReconciler) scanObjectMeta(name, namespace string, img imageref.ImageRef) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels: map[string]string{
+			"security.example.com/image-hash": imageref.HashString(img.Image)[:16],
+		},
+	}
+}
+
+// getOrCreateScan loads scan by name, creating it from the given object if it does not exist.
+func (r *PodGateReconciler) getOrCreateScan(ctx context.Context, scan scanObject) error {
+	desired := scan.DeepCopyObject().(client.Object)
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), scan)
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("created_new_scan", true))
+	kind := "ImageScan"
+	if desired.GetNamespace() == "" {
+		kind = "ClusterImageScan"
+	}
+	log.FromContext(ctx).Info("Creating "+kind, "image", scan.ScanSpec().Image, "name", desired.GetName())
+	if err := r.Create(ctx, desired); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// scannedImages returns the images that aren't exempt from scanning, with digests filled
+// in for tag-only references. Exempt images aren't resolved, and tags are checked again
+// once resolved, so digest exclusions also exempt the tags that resolve to them.
+func (r *PodGateReconciler) scannedImages(ctx context.Context, pod *corev1.Pod, images []imageref.ImageRef) ([]imageref.ImageRef, error) {
+	images, err := r.resolveImages(ctx, pod, r.Exclusions.Filter(images))
+	if err != nil {
+		return nil, err
+	}
+	return r.Exclusions.Filter(images), nil
+}
+
+// resolveImages returns the pod's images with digests filled in for tag-only references.
+// Pull credentials are only looked up when an image is not already in the digest cache.
+func (r *PodGateReconciler) resolveImages(ctx context.Context, pod *corev1.Pod, images []imageref.ImageRef) ([]imageref.ImageRef, error) {
+	if r.Resolver == nil {
+		return images, nil
+	}
+
+	var opts []remote.Option
+	resolved := make([]imageref.ImageRef, 0, len(images))
+	for _, img := range images {
+		if img.Digest == "" && opts == nil {
+			if _, ok := r.Resolver.Lookup(img.Image); !ok {
+				reader := r.APIReader
+				if reader == nil {
+					reader = r.Client
+				}
+				keychain, err := imageref.PodKeychain(ctx, reader, pod)
+				if err != nil {
+					return nil, err
+				}
+				opts = []remote.Option{remote.WithAuthFromKeychain(keychain)}
+			}
+		}
+
+		ref, err := r.Resolver.ResolveImageRef(ctx, img, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("resolving digest for %s: %w", img.Image, err)
+		}
+		resolved = append(resolved, ref)
+	}
+	return resolved, nil
+}
+
+// cachedImageRef fills in the digest of a tag-only image from the resolver cache, if present.
+// It never contacts the registry, so it is safe to use from watch map functions.
+func (r *PodGateReconciler) cachedImageRef(img imageref.ImageRef) imageref.ImageRef {
+	if img.Digest != "" || r.Resolver == nil {
+		return img
+	}
+	if digest, ok := r.Resolver.Lookup(img.Image); ok {
+		img.Digest = digest
+	}
+	return img
+}
+
 func hasSchedulingGate(pod *corev1.Pod, gateName string) bool {
 	for _, gate := range pod.Spec.SchedulingGates {
 		if gate.Name == gateName {
@@ -267,68 +608,135 @@ func (r *PodGateReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return fmt.Errorf("failed to set up field indexer: %w", err)
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Pod{}).
-		WithEventFilter(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		// The predicate only applies to pods: an event filter would also drop the events of
+		// the watches below
+		For(&corev1.Pod{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
 			pod, ok := obj.(*corev1.Pod)
 			if !ok {
 				return false
 			}
-			// Only reconcile pods with our gate
-			return hasSchedulingGate(pod, SchedulingGateName)
-		})).
+			// Only reconcile pods with our gate, or being audited
+			return hasSchedulingGate(pod, SchedulingGateName) || isAuditPending(pod)
+		}))).
 		Watches(
 			&securityv1alpha1.ImageScan{},
 			handler.EnqueueRequestsFromMapFunc(r.mapImageScanToPods),
 		).
-		Complete(r)
+		Watches(
+			&securityv1alpha1.ClusterScanPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToPods),
+		).
+		Watches(
+			&securityv1alpha1.ScanPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.mapPolicyToPods),
+		)
+	if r.ClusterScans {
+		bldr = bldr.Watches(
+			&securityv1alpha1.ClusterImageScan{},
+			handler.EnqueueRequestsFromMapFunc(r.mapImageScanToPods),
+		)
+	}
+	// Namespace labels decide selector-based exclusions and enforcement modes
+	bldr = bldr.Watches(
+		&corev1.Namespace{},
+		handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToPods),
+		builder.WithPredicates(predicate.LabelChangedPredicate{}),
+	)
+	return bldr.Complete(r)
+}
+
+// mapNamespaceToPods re-evaluates the gated and audited pods of a namespace when its
+// labels change, so opting out releases them and switching enforcement mode applies.
+func (r *PodGateReconciler) mapNamespaceToPods(ctx context.Context, obj client.Object) []reconcile.Request {
+	pods, err := r.trackedPods(ctx, client.InNamespace(obj.GetName()))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list pods for namespace mapping")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(pods))
+	for _, pod := range pods {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace},
+		})
+	}
+	return requests
+}
+
+// mapPolicyToPods re-evaluates the rules of gated pods when a scan policy changes: every
+// gated pod for a ClusterScanPolicy, and those in its namespace for a ScanPolicy.
+func (r *PodGateReconciler) mapPolicyToPods(ctx context.Context, obj client.Object) []reconcile.Request {
+	var opts []client.ListOption
+	if obj.GetNamespace() != "" {
+		opts = append(opts, client.InNamespace(obj.GetNamespace()))
+	}
+	pods, err := r.trackedPods(ctx, opts...)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list pods for scan policy mapping")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, pod := range pods {
+		if r.Namespaces.IsExcluded(ctx, pod.Namespace) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace},
+		})
+	}
+	return requests
 }
 
-// mapImageScanToPods maps ImageScan changes to pods that reference the same image.
-// This enables efficient event-driven reconciliation instead of polling.
+// mapImageScanToPods maps ImageScan and ClusterImageScan changes to pods that reference
+// the same image. This enables efficient event-driven reconciliation instead of polling.
 func (r *PodGateReconciler) mapImageScanToPods(ctx context.Context, obj client.Object) []reconcile.Request {
-	imageScan, ok := obj.(*securityv1alpha1.ImageScan)
+	scan, ok := obj.(scanObject)
 	if !ok {
 		return nil
 	}
 
 	logger := log.FromContext(ctx)
+	spec, status := scan.ScanSpec(), scan.ScanStatus()
 
-	// Only trigger reconciliation for terminal states (Registered or Error)
-	if imageScan.Status.Phase != securityv1alpha1.ScanPhaseRegistered &&
-		imageScan.Status.Phase != securityv1alpha1.ScanPhaseError {
+	// Only trigger reconciliation for terminal states (Registered, Passed, Failed or Error)
+	if !isCompletedPhase(status.Phase) &&
+		status.Phase != securityv1alpha1.ScanPhaseError {
 		return nil
 	}
 
-	// List only pods with our scheduling gate using the field indexer
-	var podList corev1.PodList
-	if err := r.List(ctx, &podList, client.MatchingFields{
-		IndexFieldSchedulingGate: SchedulingGateName,
-	}); err != nil {
+	// List only pods with our scheduling gate (using the field indexer) or being audited
+	pods, err := r.trackedPods(ctx)
+	if err != nil {
 		logger.Error(err, "Failed to list pods for ImageScan mapping")
 		return nil
 	}
 
 	var requests []reconcile.Request
-	for _, pod := range podList.Items {
+	for _, pod := range pods {
 		// Skip excluded namespaces
-		if r.ExcludedNamespaces[pod.Namespace] {
+		if r.Namespaces.IsExcluded(ctx, pod.Namespace) {
 			continue
 		}
 
 		// Check if this pod references the image from this ImageScan
 		images := imageref.ExtractFromPod(&pod)
 		for _, img := range images {
+			img = r.cachedImageRef(img)
 			scanName := imageref.ScanName(img)
 			scanNamespace := r.ScanNamespace
 			if scanNamespace == "" {
 				scanNamespace = pod.Namespace
 			}
 
-			// Match by name and namespace
-			if scanName == imageScan.Name && scanNamespace == imageScan.Namespace {
+			// Match by name and namespace (ClusterImageScans match pods in any namespace).
+			// Fall back to the image reference in case the resolved digest has already
+			// expired from the cache.
+			if (scanName == scan.GetName() || img.Image == spec.Image) &&
+				(scan.GetNamespace() == "" || scanNamespace == scan.GetNamespace()) {
 				logger.V(1).Info("Mapping ImageScan to pod",
-					"imageScan", imageScan.Name,
+					"imageScan", scan.GetName(),
 					"pod", pod.Name,
 					"namespace", pod.Namespace)
 				requests = append(requests, reconcile.Request{
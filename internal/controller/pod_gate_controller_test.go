@@ -2,19 +2,30 @@ package controller
 
 import (
 	"context"
+	"net/http/httptest"
+	"strings"
+	"time"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/enforcement"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageexclude"
 	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/scope"
 )
 
 var _ = Describe("PodGateReconciler", func() {
@@ -90,6 +101,498 @@ var _ = Describe("PodGateReconciler", func() {
 				Expect(hasSchedulingGate(&updatedPod, SchedulingGateName)).To(BeFalse())
 			})
 		})
+
+		Context("when an image failed a scan policy", func() {
+			It("should keep the gate and emit an event naming the policy", func() {
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-pod",
+						Namespace: "default",
+					},
+					Spec: corev1.PodSpec{
+						SchedulingGates: []corev1.PodSchedulingGate{
+							{Name: SchedulingGateName},
+						},
+						Containers: []corev1.Container{
+							{Name: "app", Image: "nginx:latest"},
+						},
+					},
+				}
+
+				imageScan := &securityv1alpha1.ImageScan{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      imageref.ScanName(imageref.ImageRef{Image: "nginx:latest"}),
+						Namespace: "default",
+					},
+					Spec: securityv1alpha1.ImageScanSpec{
+						Image: "nginx:latest",
+					},
+					Status: securityv1alpha1.ImageScanStatus{
+						Phase:   securityv1alpha1.ScanPhaseFailed,
+						Policy:  "ClusterScanPolicy/baseline",
+						Message: "2 critical vulnerabilities exceed the maximum of 0",
+					},
+				}
+
+				fakeClient := fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(pod, imageScan).
+					Build()
+
+				recorder := record.NewFakeRecorder(10)
+				r := &PodGateReconciler{
+					Client:   fakeClient,
+					Scheme:   scheme,
+					Recorder: recorder,
+				}
+
+				_, err := r.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      "test-pod",
+						Namespace: "default",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				var updatedPod corev1.Pod
+				Expect(fakeClient.Get(ctx, types.NamespacedName{
+					Name: "test-pod", Namespace: "default",
+				}, &updatedPod)).To(Succeed())
+				Expect(hasSchedulingGate(&updatedPod, SchedulingGateName)).To(BeTrue())
+
+				Expect(recorder.Events).To(Receive(And(
+					ContainSubstring("ScanFailed"),
+					ContainSubstring("ClusterScanPolicy/baseline"),
+				)))
+			})
+		})
+
+		Context("when a registered image violates a scan policy rule", func() {
+			It("should keep the gate in matching namespaces only", func() {
+				newPod := func(namespace string) *corev1.Pod {
+					return &corev1.Pod{
+						ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: namespace},
+						Spec: corev1.PodSpec{
+							SchedulingGates: []corev1.PodSchedulingGate{{Name: SchedulingGateName}},
+							Containers:      []corev1.Container{{Name: "app", Image: "nginx:latest"}},
+						},
+					}
+				}
+				newScan := func(namespace string) *securityv1alpha1.ImageScan {
+					return &securityv1alpha1.ImageScan{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      imageref.ScanName(imageref.ImageRef{Image: "nginx:latest"}),
+							Namespace: namespace,
+						},
+						Spec: securityv1alpha1.ImageScanSpec{Image: "nginx:latest"},
+						Status: securityv1alpha1.ImageScanStatus{
+							Phase:           securityv1alpha1.ScanPhaseRegistered,
+							Vulnerabilities: &securityv1alpha1.VulnerabilitySummary{High: 3},
+							Fixable:         &securityv1alpha1.VulnerabilitySummary{High: 1},
+						},
+					}
+				}
+				clusterPolicy := &securityv1alpha1.ClusterScanPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "prod"},
+					Spec: securityv1alpha1.ScanPolicySpec{
+						Rules: []securityv1alpha1.Rule{{
+							Name:       "no-fixable-high-in-prod",
+							Expression: "namespaceObject.labels.?env.orValue('') != 'prod' || scan.fixable.high == 0",
+							Message:    "fixable high vulnerabilities are not allowed in production",
+						}},
+					},
+				}
+
+				fakeClient := fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(
+						newPod("prod"), newScan("prod"),
+						newPod("dev"), newScan("dev"),
+						clusterPolicy,
+						&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+							Name: "prod", Labels: map[string]string{"env": "prod"},
+						}},
+						&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev"}},
+					).
+					Build()
+
+				recorder := record.NewFakeRecorder(10)
+				r := &PodGateReconciler{
+					Client:   fakeClient,
+					Scheme:   scheme,
+					Recorder: recorder,
+				}
+
+				for _, namespace := range []string{"prod", "dev"} {
+					_, err := r.Reconcile(ctx, reconcile.Request{
+						NamespacedName: types.NamespacedName{Name: "test-pod", Namespace: namespace},
+					})
+					Expect(err).NotTo(HaveOccurred())
+				}
+
+				var updatedPod corev1.Pod
+				Expect(fakeClient.Get(ctx, types.NamespacedName{
+					Name: "test-pod", Namespace: "prod",
+				}, &updatedPod)).To(Succeed())
+				Expect(hasSchedulingGate(&updatedPod, SchedulingGateName)).To(BeTrue())
+				Expect(recorder.Events).To(Receive(And(
+					ContainSubstring("RuleViolation"),
+					ContainSubstring("ClusterScanPolicy/prod"),
+					ContainSubstring("not allowed in production"),
+				)))
+
+				Expect(fakeClient.Get(ctx, types.NamespacedName{
+					Name: "test-pod", Namespace: "dev",
+				}, &updatedPod)).To(Succeed())
+				Expect(hasSchedulingGate(&updatedPod, SchedulingGateName)).To(BeFalse())
+			})
+		})
+
+		Context("when the pod uses a tag-only image", func() {
+			It("should create an ImageScan with the resolved digest", func() {
+				server := httptest.NewServer(registry.New())
+				defer server.Close()
+
+				image := strings.TrimPrefix(server.URL, "http://") + "/app:v1"
+				img, err := random.Image(64, 1)
+				Expect(err).NotTo(HaveOccurred())
+				ref, err := name.ParseReference(image)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(remote.Write(ref, img)).To(Succeed())
+				digest, err := img.Digest()
+				Expect(err).NotTo(HaveOccurred())
+
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-pod",
+						Namespace: "default",
+					},
+					Spec: corev1.PodSpec{
+						SchedulingGates: []corev1.PodSchedulingGate{
+							{Name: SchedulingGateName},
+						},
+						ImagePullSecrets: []corev1.LocalObjectReference{
+							{Name: "missing-secret"},
+						},
+						Containers: []corev1.Container{
+							{Name: "app", Image: image},
+						},
+					},
+				}
+
+				fakeClient := fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(pod).
+					Build()
+
+				r := &PodGateReconciler{
+					Client:   fakeClient,
+					Scheme:   scheme,
+					Resolver: imageref.NewCachingResolver(imageref.NewResolver(), time.Minute),
+				}
+
+				_, err = r.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      "test-pod",
+						Namespace: "default",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				resolved := imageref.ImageRef{Image: image, Digest: digest.String()}
+				var imageScan securityv1alpha1.ImageScan
+				Expect(fakeClient.Get(ctx, types.NamespacedName{
+					Name: imageref.ScanName(resolved), Namespace: "default",
+				}, &imageScan)).To(Succeed())
+				Expect(imageScan.Spec.Image).To(Equal(image))
+				Expect(imageScan.Spec.Digest).To(Equal(digest.String()))
+
+				// The gate stays until the scan completes
+				var updatedPod corev1.Pod
+				Expect(fakeClient.Get(ctx, types.NamespacedName{
+					Name: "test-pod", Namespace: "default",
+				}, &updatedPod)).To(Succeed())
+				Expect(hasSchedulingGate(&updatedPod, SchedulingGateName)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("enforcement modes", func() {
+		const image = "nginx:1.25"
+
+		scanWithPhase := func(phase securityv1alpha1.ScanPhase) *securityv1alpha1.ImageScan {
+			return &securityv1alpha1.ImageScan{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      imageref.ScanName(imageref.ImageRef{Image: image}),
+					Namespace: "default",
+				},
+				Spec: securityv1alpha1.ImageScanSpec{Image: image},
+				Status: securityv1alpha1.ImageScanStatus{
+					Phase:   phase,
+					Policy:  "ClusterScanPolicy/baseline",
+					Message: "2 critical vulnerabilities exceed the maximum of 0",
+				},
+			}
+		}
+
+		reconcilePod := func(pod *corev1.Pod, objs ...client.Object) (*corev1.Pod, *record.FakeRecorder) {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "default",
+				Labels: map[string]string{enforcement.LabelMode: "audit"},
+			}}
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objs, pod, namespace)...).Build()
+			recorder := record.NewFakeRecorder(10)
+			r := &PodGateReconciler{
+				Client:      fakeClient,
+				Scheme:      scheme,
+				Recorder:    recorder,
+				Enforcement: &enforcement.Modes{Reader: fakeClient, Default: enforcement.Enforce},
+			}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated corev1.Pod
+			Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(pod), &updated)).To(Succeed())
+			return &updated, recorder
+		}
+
+		It("should release gated pods at once in audit mode namespaces", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+				Spec: corev1.PodSpec{
+					SchedulingGates: []corev1.PodSchedulingGate{{Name: SchedulingGateName}},
+					Containers:      []corev1.Container{{Name: "app", Image: image}},
+				},
+			}
+			updated, recorder := reconcilePod(pod)
+			Expect(hasSchedulingGate(updated, SchedulingGateName)).To(BeFalse())
+			Expect(updated.Labels).To(HaveKeyWithValue(enforcement.LabelAudit, enforcement.AuditPending))
+			Expect(updated.Annotations).To(HaveKeyWithValue(enforcement.AnnotationMode, "audit"))
+			Expect(recorder.Events).To(Receive(ContainSubstring("GateReleased")))
+		})
+
+		It("should record audited pods that would have been blocked", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-pod",
+					Namespace:   "default",
+					Labels:      map[string]string{enforcement.LabelAudit: enforcement.AuditPending},
+					Annotations: map[string]string{enforcement.AnnotationMode: "warn"},
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+			}
+			updated, recorder := reconcilePod(pod, scanWithPhase(securityv1alpha1.ScanPhaseFailed))
+			Expect(updated.Labels).To(HaveKeyWithValue(enforcement.LabelAudit, enforcement.AuditWouldBlock))
+			Expect(recorder.Events).To(Receive(And(
+				ContainSubstring("WouldHaveBlocked"),
+				ContainSubstring("ClusterScanPolicy/baseline"),
+			)))
+			Expect(recorder.Events).NotTo(Receive())
+
+			updated, _ = reconcilePod(pod, scanWithPhase(securityv1alpha1.ScanPhasePassed))
+			Expect(updated.Labels).To(HaveKeyWithValue(enforcement.LabelAudit, enforcement.AuditPassed))
+
+			updated, _ = reconcilePod(pod, scanWithPhase(securityv1alpha1.ScanPhasePending))
+			Expect(updated.Labels).To(HaveKeyWithValue(enforcement.LabelAudit, enforcement.AuditPending))
+		})
+	})
+
+	Describe("namespace selectors", func() {
+		It("should release gated pods once their namespace opts out", func() {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "default",
+				Labels: map[string]string{"scans.aquasec.community/opt-out": "true"},
+			}}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+				Spec: corev1.PodSpec{
+					SchedulingGates: []corev1.PodSchedulingGate{{Name: SchedulingGateName}},
+					Containers:      []corev1.Container{{Name: "app", Image: "nginx:1.25"}},
+				},
+			}
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod, namespace).Build()
+			namespaces, err := scope.NewNamespaces(fakeClient, nil, "", "scans.aquasec.community/opt-out=true")
+			Expect(err).NotTo(HaveOccurred())
+			recorder := record.NewFakeRecorder(10)
+			r := &PodGateReconciler{Client: fakeClient, Scheme: scheme, Recorder: recorder, Namespaces: namespaces}
+
+			_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated corev1.Pod
+			Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(pod), &updated)).To(Succeed())
+			Expect(hasSchedulingGate(&updated, SchedulingGateName)).To(BeFalse())
+			Expect(recorder.Events).To(Receive(ContainSubstring("NamespaceExcluded")))
+
+			var imageScans securityv1alpha1.ImageScanList
+			Expect(fakeClient.List(ctx, &imageScans)).To(Succeed())
+			Expect(imageScans.Items).To(BeEmpty())
+		})
+	})
+
+	Describe("image exclusions", func() {
+		It("should only wait on images that aren't exempt from scanning", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+				Spec: corev1.PodSpec{
+					SchedulingGates: []corev1.PodSchedulingGate{{Name: SchedulingGateName}},
+					Containers: []corev1.Container{
+						{Name: "app", Image: "nginx:1.25"},
+						{Name: "proxy", Image: "registry.example.com/mesh/proxy:v2"},
+					},
+				},
+			}
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).
+				WithStatusSubresource(&securityv1alpha1.ImageScan{}).Build()
+			list, err := imageexclude.ParseList("registry.example.com/mesh/*")
+			Expect(err).NotTo(HaveOccurred())
+			exclusions, err := imageexclude.New(list, "", 0)
+			Expect(err).NotTo(HaveOccurred())
+			r := &PodGateReconciler{Client: fakeClient, Scheme: scheme, Exclusions: exclusions}
+
+			_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+			Expect(err).NotTo(HaveOccurred())
+
+			var imageScans securityv1alpha1.ImageScanList
+			Expect(fakeClient.List(ctx, &imageScans)).To(Succeed())
+			Expect(imageScans.Items).To(HaveLen(1))
+			Expect(imageScans.Items[0].Spec.Image).To(Equal("nginx:1.25"))
+
+			imageScan := imageScans.Items[0]
+			imageScan.Status.Phase = securityv1alpha1.ScanPhaseRegistered
+			Expect(fakeClient.Status().Update(ctx, &imageScan)).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated corev1.Pod
+			Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(pod), &updated)).To(Succeed())
+			Expect(hasSchedulingGate(&updated, SchedulingGateName)).To(BeFalse())
+		})
+	})
+
+	Describe("StartScans", func() {
+		It("should create the scans of a pod that doesn't exist yet", func() {
+			// Pods are seen by the webhook before they are persisted, possibly without a name
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{GenerateName: "app-", Namespace: "default"},
+				Spec: corev1.PodSpec{
+					Containers:     []corev1.Container{{Name: "app", Image: "nginx:1.25"}},
+					InitContainers: []corev1.Container{{Name: "init", Image: "busybox:1.36"}},
+				},
+			}
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+			r := &PodGateReconciler{Client: fakeClient, Scheme: scheme}
+
+			Expect(r.StartScans(ctx, pod)).To(Succeed())
+			// Starting again finds the existing scans
+			Expect(r.StartScans(ctx, pod)).To(Succeed())
+
+			var imageScans securityv1alpha1.ImageScanList
+			Expect(fakeClient.List(ctx, &imageScans)).To(Succeed())
+			images := []string{}
+			for _, imageScan := range imageScans.Items {
+				images = append(images, imageScan.Spec.Image)
+			}
+			Expect(images).To(ConsistOf("nginx:1.25", "busybox:1.36"))
+		})
+	})
+
+	Describe("cluster scans", func() {
+		newPod := func(namespace string) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod",
+					Namespace: namespace,
+				},
+				Spec: corev1.PodSpec{
+					SchedulingGates: []corev1.PodSchedulingGate{
+						{Name: SchedulingGateName},
+					},
+					Containers: []corev1.Container{
+						{Name: "app", Image: "nginx:latest"},
+					},
+				},
+			}
+		}
+
+		scanName := imageref.ScanName(imageref.ImageRef{Image: "nginx:latest"})
+
+		reconcilePod := func(r *PodGateReconciler, namespace string) *corev1.Pod {
+			key := types.NamespacedName{Name: "test-pod", Namespace: namespace}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updatedPod corev1.Pod
+			Expect(r.Get(ctx, key, &updatedPod)).To(Succeed())
+			return &updatedPod
+		}
+
+		It("should create one ClusterImageScan for pods in different namespaces", func() {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(newPod("team-a"), newPod("team-b")).
+				Build()
+			r := &PodGateReconciler{Client: fakeClient, Scheme: scheme, ClusterScans: true}
+
+			Expect(hasSchedulingGate(reconcilePod(r, "team-a"), SchedulingGateName)).To(BeTrue())
+			Expect(hasSchedulingGate(reconcilePod(r, "team-b"), SchedulingGateName)).To(BeTrue())
+
+			var clusterScans securityv1alpha1.ClusterImageScanList
+			Expect(fakeClient.List(ctx, &clusterScans)).To(Succeed())
+			Expect(clusterScans.Items).To(HaveLen(1))
+			Expect(clusterScans.Items[0].Name).To(Equal(scanName))
+
+			var imageScans securityv1alpha1.ImageScanList
+			Expect(fakeClient.List(ctx, &imageScans)).To(Succeed())
+			Expect(imageScans.Items).To(BeEmpty())
+		})
+
+		It("should remove the gate once the ClusterImageScan passes", func() {
+			clusterScan := &securityv1alpha1.ClusterImageScan{
+				ObjectMeta: metav1.ObjectMeta{Name: scanName},
+				Spec:       securityv1alpha1.ImageScanSpec{Image: "nginx:latest"},
+				Status: securityv1alpha1.ImageScanStatus{
+					Phase: securityv1alpha1.ScanPhasePassed,
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(newPod("team-a"), clusterScan).
+				Build()
+			r := &PodGateReconciler{Client: fakeClient, Scheme: scheme, ClusterScans: true}
+
+			Expect(hasSchedulingGate(reconcilePod(r, "team-a"), SchedulingGateName)).To(BeFalse())
+		})
+
+		It("should gate on the namespaced reference when references are enabled", func() {
+			clusterScan := &securityv1alpha1.ClusterImageScan{
+				ObjectMeta: metav1.ObjectMeta{Name: scanName},
+				Spec:       securityv1alpha1.ImageScanSpec{Image: "nginx:latest"},
+				Status: securityv1alpha1.ImageScanStatus{
+					Phase: securityv1alpha1.ScanPhaseRegistered,
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(newPod("team-a"), clusterScan).
+				Build()
+			r := &PodGateReconciler{
+				Client:                fakeClient,
+				Scheme:                scheme,
+				ClusterScans:          true,
+				ClusterScanReferences: true,
+			}
+
+			// The new reference has no verdict yet, whatever the ClusterImageScan says
+			Expect(hasSchedulingGate(reconcilePod(r, "team-a"), SchedulingGateName)).To(BeTrue())
+
+			var reference securityv1alpha1.ImageScan
+			Expect(fakeClient.Get(ctx, types.NamespacedName{
+				Name: scanName, Namespace: "team-a",
+			}, &reference)).To(Succeed())
+			Expect(reference.Spec.ClusterScanRef).To(Equal(scanName))
+			Expect(reference.Spec.Image).To(Equal("nginx:latest"))
+		})
 	})
 
 	Describe("mapImageScanToPods", func() {
@@ -256,9 +759,9 @@ var _ = Describe("PodGateReconciler", func() {
 					Build()
 
 				r = &PodGateReconciler{
-					Client:             fakeClient,
-					Scheme:             scheme,
-					ExcludedNamespaces: map[string]bool{"kube-system": true},
+					Client:     fakeClient,
+					Scheme:     scheme,
+					Namespaces: &scope.Namespaces{Excluded: map[string]bool{"kube-system": true}},
 				}
 
 				requests := r.mapImageScanToPods(ctx, imageScan)
@@ -380,6 +883,43 @@ var _ = Describe("PodGateReconciler", func() {
 			})
 		})
 
+		Context("when a ClusterImageScan completes", func() {
+			It("should return requests for matching pods in every namespace", func() {
+				podA := createPodWithGate("pod-a", "team-a", "nginx:latest")
+				podB := createPodWithGate("pod-b", "team-b", "nginx:latest")
+				other := createPodWithGate("pod-c", "team-c", "redis:latest")
+				clusterScan := &securityv1alpha1.ClusterImageScan{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: imageref.ScanName(imageref.ImageRef{Image: "nginx:latest"}),
+					},
+					Spec: securityv1alpha1.ImageScanSpec{
+						Image: "nginx:latest",
+					},
+					Status: securityv1alpha1.ImageScanStatus{
+						Phase: securityv1alpha1.ScanPhasePassed,
+					},
+				}
+
+				fakeClient = fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(podA, podB, other, clusterScan).
+					WithIndex(&corev1.Pod{}, IndexFieldSchedulingGate, indexerFunc).
+					Build()
+
+				r = &PodGateReconciler{
+					Client:       fakeClient,
+					Scheme:       scheme,
+					ClusterScans: true,
+				}
+
+				requests := r.mapImageScanToPods(ctx, clusterScan)
+				Expect(requests).To(ConsistOf(
+					reconcile.Request{NamespacedName: types.NamespacedName{Name: "pod-a", Namespace: "team-a"}},
+					reconcile.Request{NamespacedName: types.NamespacedName{Name: "pod-b", Namespace: "team-b"}},
+				))
+			})
+		})
+
 		Context("when passed a non-ImageScan object", func() {
 			It("should return nil", func() {
 				pod := createPodWithGate("test-pod", "default", "nginx:latest")
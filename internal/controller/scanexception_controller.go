@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/policy"
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+// ScanExceptionReconciler marks ScanExceptions Expired once their expiry passes. The
+// status change re-evaluates the ImageScans the exception covered, which watch ScanExceptions.
+type ScanExceptionReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=scanexceptions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=scans.aquasec.community,resources=scanexceptions/status,verbs=get;update;patch
+
+func (r *ScanExceptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.StartSpan(ctx, "ScanExceptionReconciler.Reconcile",
+		trace.WithAttributes(
+			attribute.String("scanexception.name", req.Name),
+		),
+	)
+	defer span.End()
+
+	logger := log.FromContext(ctx)
+
+	var exception securityv1alpha1.ScanException
+	if err := r.Get(ctx, req.NamespacedName, &exception); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+
+	// Not expired yet - come back when it is
+	if remaining := exception.Spec.Expires.Sub(now); remaining > 0 {
+		if exception.Status.Expired {
+			// The expiry was extended
+			exception.Status.Expired = false
+			if err := r.Status().Update(ctx, &exception); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	if exception.Status.Expired {
+		return ctrl.Result{}, nil
+	}
+
+	span.SetAttributes(attribute.Bool("expired", true))
+	logger.Info("ScanException expired", "scanException", exception.Name, "expires", exception.Spec.Expires.Time)
+	exception.Status.Expired = true
+	if err := r.Status().Update(ctx, &exception); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(&exception, corev1.EventTypeWarning, "ExceptionExpired",
+			"ScanException approved by %s expired at %s", exception.Spec.ApprovedBy,
+			exception.Spec.Expires.UTC().Format(time.RFC3339))
+
+		affected, err := coveredImageScans(ctx, r.Client, &exception)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		for i := range affected {
+			r.Recorder.Eventf(&affected[i], corev1.EventTypeWarning, "ExceptionExpired",
+				"ScanException %s expired; re-evaluating scan policies", exception.Name)
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ScanExceptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&securityv1alpha1.ScanException{}).
+		Complete(r)
+}
+
+// coveredImageScans returns the ImageScans with scan results that an exception covers.
+func coveredImageScans(ctx context.Context, c client.Client, exception *securityv1alpha1.ScanException) ([]securityv1alpha1.ImageScan, error) {
+	var imageScans securityv1alpha1.ImageScanList
+	if err := c.List(ctx, &imageScans); err != nil {
+		return nil, fmt.Errorf("listing ImageScans: %w", err)
+	}
+
+	var covered []securityv1alpha1.ImageScan
+	for _, imageScan := range imageScans.Items {
+		if hasScanResults(&imageScan) &&
+			policy.Covers(exception, imageScan.Namespace, imageScan.Spec.Image, imageScan.Spec.Digest) {
+			covered = append(covered, imageScan)
+		}
+	}
+	return covered, nil
+}
+
+// mapExceptionToImageScans re-evaluates the ImageScans a ScanException covers when it changes.
+func (r *ImageScanReconciler) mapExceptionToImageScans(ctx context.Context, obj client.Object) []reconcile.Request {
+	exception, ok := obj.(*securityv1alpha1.ScanException)
+	if !ok {
+		return nil
+	}
+
+	covered, err := coveredImageScans(ctx, r.Client, exception)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ImageScans for ScanException mapping")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(covered))
+	for _, imageScan := range covered {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      imageScan.Name,
+				Namespace: imageScan.Namespace,
+			},
+		})
+	}
+	return requests
+}
+
+// mapExceptionToClusterImageScans re-evaluates the ClusterImageScans a ScanException covers
+// when it changes. Only exceptions for every namespace cover ClusterImageScans.
+func (r *ClusterImageScanReconciler) mapExceptionToClusterImageScans(ctx context.Context, obj client.Object) []reconcile.Request {
+	exception, ok := obj.(*securityv1alpha1.ScanException)
+	if !ok || len(exception.Spec.Namespaces) > 0 {
+		return nil
+	}
+
+	var clusterScans securityv1alpha1.ClusterImageScanList
+	if err := r.List(ctx, &clusterScans); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ClusterImageScans for ScanException mapping")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range clusterScans.Items {
+		clusterScan := &clusterScans.Items[i]
+		if hasScanResults(clusterScan) &&
+			policy.Covers(exception, "", clusterScan.Spec.Image, clusterScan.Spec.Digest) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: clusterScan.Name},
+			})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,241 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+)
+
+var _ = Describe("ScanException", func() {
+	const scanName = "sha256-0123456789abcdef"
+
+	var (
+		scheme *runtime.Scheme
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		ctx = context.Background()
+	})
+
+	newException := func(expires time.Time, spec securityv1alpha1.ScanExceptionSpec) *securityv1alpha1.ScanException {
+		spec.Expires = metav1.NewTime(expires)
+		spec.ApprovedBy = "security-team"
+		spec.Reason = "No fix available upstream"
+		return &securityv1alpha1.ScanException{
+			ObjectMeta: metav1.ObjectMeta{Name: "openssl"},
+			Spec:       spec,
+		}
+	}
+
+	Context("Policy evaluation", func() {
+		clusterPolicy := func() *securityv1alpha1.ClusterScanPolicy {
+			return &securityv1alpha1.ClusterScanPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "baseline"},
+				Spec:       securityv1alpha1.ScanPolicySpec{MaxCritical: intPtr(0)},
+			}
+		}
+
+		reconcileScan := func(aquaClient *fakeAquaClient, objs ...client.Object) *securityv1alpha1.ImageScan {
+			imageScan := &securityv1alpha1.ImageScan{
+				ObjectMeta: metav1.ObjectMeta{Name: scanName, Namespace: "default"},
+				Spec: securityv1alpha1.ImageScanSpec{
+					Image:  "nginx:1.25",
+					Digest: testDigest,
+				},
+				Status: securityv1alpha1.ImageScanStatus{Phase: securityv1alpha1.ScanPhasePending},
+			}
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(append(objs, imageScan, clusterPolicy())...).
+				WithStatusSubresource(imageScan).
+				Build()
+			r := &ImageScanReconciler{Client: c, Scheme: scheme, AquaClient: aquaClient}
+			key := types.NamespacedName{Name: scanName, Namespace: "default"}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated securityv1alpha1.ImageScan
+			Expect(c.Get(ctx, key, &updated)).To(Succeed())
+			return &updated
+		}
+
+		criticalFinding := func() *fakeAquaClient {
+			return &fakeAquaClient{
+				result: &aqua.ScanResult{
+					Status:          aqua.StatusFound,
+					ScanStatus:      aqua.AquaScanStatusFinished,
+					Vulnerabilities: aqua.VulnerabilityCounts{Critical: 1, Total: 1},
+				},
+				vulnerabilities: []aqua.Vulnerability{
+					{Name: "CVE-2024-0001", Package: "openssl", Severity: "critical"},
+				},
+			}
+		}
+
+		It("should pass an image whose only violation is a waived CVE", func() {
+			updated := reconcileScan(criticalFinding(), newException(time.Now().Add(time.Hour),
+				securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}}))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePassed))
+			Expect(updated.Status.Exceptions).To(Equal([]string{"openssl"}))
+			Expect(updated.Status.Message).To(ContainSubstring("ScanException openssl"))
+			// The summary keeps the real counts
+			Expect(updated.Status.Vulnerabilities.Critical).To(Equal(1))
+		})
+
+		It("should waive every finding for an image exception without fetching CVEs", func() {
+			aquaClient := criticalFinding()
+			updated := reconcileScan(aquaClient, newException(time.Now().Add(time.Hour),
+				securityv1alpha1.ScanExceptionSpec{Images: []string{"docker.io/library/nginx"}}))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePassed))
+			Expect(aquaClient.vulnerabilityCalls).To(BeZero())
+		})
+
+		It("should fetch CVEs once per Aqua scan of images without reports", func() {
+			aquaClient := criticalFinding()
+			imageScan := &securityv1alpha1.ImageScan{
+				ObjectMeta: metav1.ObjectMeta{Name: scanName, Namespace: "default"},
+				Spec:       securityv1alpha1.ImageScanSpec{Image: "nginx:1.25", Digest: testDigest},
+				Status:     securityv1alpha1.ImageScanStatus{Phase: securityv1alpha1.ScanPhasePending},
+			}
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(imageScan, clusterPolicy(), newException(time.Now().Add(time.Hour),
+					securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}})).
+				WithStatusSubresource(imageScan).
+				Build()
+			r := &ImageScanReconciler{
+				Client:             c,
+				Scheme:             scheme,
+				AquaClient:         aquaClient,
+				VulnerabilityCache: NewVulnerabilityCache(0),
+			}
+			key := types.NamespacedName{Name: scanName, Namespace: "default"}
+
+			// Scanned, then re-evaluated against the stored results
+			for range 3 {
+				_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(aquaClient.vulnerabilityCalls).To(Equal(1))
+
+			var updated securityv1alpha1.ImageScan
+			Expect(c.Get(ctx, key, &updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhasePassed))
+
+			// Results of a later Aqua scan are fetched again
+			rescanned := metav1.NewTime(updated.Status.LastScanTime.Add(time.Hour))
+			updated.Status.LastScanTime = &rescanned
+			Expect(c.Status().Update(ctx, &updated)).To(Succeed())
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(aquaClient.vulnerabilityCalls).To(Equal(2))
+		})
+
+		It("should record the counts rules see without waived findings, even without reports", func() {
+			aquaClient := &fakeAquaClient{
+				result: &aqua.ScanResult{
+					Status:          aqua.StatusFound,
+					ScanStatus:      aqua.AquaScanStatusFinished,
+					Vulnerabilities: aqua.VulnerabilityCounts{Critical: 1, High: 2, Total: 3},
+				},
+				vulnerabilities: []aqua.Vulnerability{
+					{Name: "CVE-2024-0001", Package: "openssl", Severity: "critical", FixedVersion: "3.0.14"},
+					{Name: "CVE-2024-0002", Package: "zlib", Severity: "high", FixedVersion: "1.3.1"},
+					{Name: "CVE-2024-0003", Package: "curl", Severity: "high"},
+				},
+			}
+			rules := &securityv1alpha1.ScanPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "fixable", Namespace: "other"},
+				Spec: securityv1alpha1.ScanPolicySpec{Rules: []securityv1alpha1.Rule{
+					{Name: "no-fixable-highs", Expression: "scan.fixable.high == 0"},
+				}},
+			}
+			updated := reconcileScan(aquaClient, rules, newException(time.Now().Add(time.Hour),
+				securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001", "CVE-2024-0002"}}))
+			Expect(updated.Status.ReportChunks).To(BeZero())
+			Expect(updated.Status.EffectiveVulnerabilities).To(Equal(&securityv1alpha1.VulnerabilitySummary{High: 1}))
+			Expect(updated.Status.Fixable).To(Equal(&securityv1alpha1.VulnerabilitySummary{}))
+			Expect(updated.Status.Vulnerabilities.High).To(Equal(2))
+		})
+
+		It("should ignore expired exceptions", func() {
+			updated := reconcileScan(criticalFinding(), newException(time.Now().Add(-time.Hour),
+				securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}}))
+			Expect(updated.Status.Phase).To(Equal(securityv1alpha1.ScanPhaseFailed))
+			Expect(updated.Status.Exceptions).To(BeEmpty())
+		})
+	})
+
+	Context("Expiry", func() {
+		now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		reconcileException := func(exception *securityv1alpha1.ScanException, objs ...client.Object) (reconcile.Result, *securityv1alpha1.ScanException, *record.FakeRecorder) {
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(append(objs, exception)...).
+				WithStatusSubresource(exception).
+				Build()
+			recorder := record.NewFakeRecorder(10)
+			r := &ScanExceptionReconciler{
+				Client:   c,
+				Scheme:   scheme,
+				Recorder: recorder,
+				now:      func() time.Time { return now },
+			}
+			key := types.NamespacedName{Name: exception.Name}
+			result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated securityv1alpha1.ScanException
+			Expect(c.Get(ctx, key, &updated)).To(Succeed())
+			return result, &updated, recorder
+		}
+
+		It("should requeue an active exception at its expiry", func() {
+			result, updated, recorder := reconcileException(newException(now.Add(time.Hour),
+				securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}}))
+			Expect(result.RequeueAfter).To(Equal(time.Hour))
+			Expect(updated.Status.Expired).To(BeFalse())
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("should mark an expired exception and warn on the scans it covered", func() {
+			imageScan := &securityv1alpha1.ImageScan{
+				ObjectMeta: metav1.ObjectMeta{Name: scanName, Namespace: "default"},
+				Spec:       securityv1alpha1.ImageScanSpec{Image: "nginx:1.25", Digest: testDigest},
+				Status: securityv1alpha1.ImageScanStatus{
+					Phase:           securityv1alpha1.ScanPhasePassed,
+					Vulnerabilities: &securityv1alpha1.VulnerabilitySummary{Critical: 1},
+				},
+			}
+			result, updated, recorder := reconcileException(newException(now.Add(-time.Minute),
+				securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}}), imageScan)
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(updated.Status.Expired).To(BeTrue())
+			Expect(recorder.Events).To(HaveLen(2))
+			Expect(<-recorder.Events).To(ContainSubstring("ExceptionExpired"))
+		})
+
+		It("should clear Expired when the expiry is extended", func() {
+			exception := newException(now.Add(time.Hour), securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}})
+			exception.Status.Expired = true
+			_, updated, _ := reconcileException(exception)
+			Expect(updated.Status.Expired).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,354 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/policy"
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+const (
+	// AnnotationTemplateApproval records on a workload whether the images of its pod
+	// template are approved: TemplateApproved, TemplatePending or TemplateBlocked
+	AnnotationTemplateApproval = "scans.aquasec.community/template-approval"
+
+	TemplateApproved = "approved"
+	TemplatePending  = "pending"
+	TemplateBlocked  = "blocked"
+
+	// IndexFieldTemplateImage indexes workloads by the image references and digests of
+	// their pod template
+	IndexFieldTemplateImage = "spec.template.images"
+)
+
+// workloadKind describes how to read the pod template of a kind of workload
+type workloadKind struct {
+	kind string
+	// podOwnerKind is the kind of the controller that owns the workload's pods
+	podOwnerKind string
+	newObject    func() client.Object
+	newList      func() client.ObjectList
+	template     func(client.Object) *corev1.PodTemplateSpec
+}
+
+var workloadKinds = []workloadKind{
+	{
+		kind:         "Deployment",
+		podOwnerKind: "ReplicaSet",
+		newObject:    func() client.Object { return &appsv1.Deployment{} },
+		newList:      func() client.ObjectList { return &appsv1.DeploymentList{} },
+		template:     func(obj client.Object) *corev1.PodTemplateSpec { return &obj.(*appsv1.Deployment).Spec.Template },
+	},
+	{
+		kind:         "StatefulSet",
+		podOwnerKind: "StatefulSet",
+		newObject:    func() client.Object { return &appsv1.StatefulSet{} },
+		newList:      func() client.ObjectList { return &appsv1.StatefulSetList{} },
+		template:     func(obj client.Object) *corev1.PodTemplateSpec { return &obj.(*appsv1.StatefulSet).Spec.Template },
+	},
+	{
+		kind:         "DaemonSet",
+		podOwnerKind: "DaemonSet",
+		newObject:    func() client.Object { return &appsv1.DaemonSet{} },
+		newList:      func() client.ObjectList { return &appsv1.DaemonSetList{} },
+		template:     func(obj client.Object) *corev1.PodTemplateSpec { return &obj.(*appsv1.DaemonSet).Spec.Template },
+	},
+	{
+		kind:         "Job",
+		podOwnerKind: "Job",
+		newObject:    func() client.Object { return &batchv1.Job{} },
+		newList:      func() client.ObjectList { return &batchv1.JobList{} },
+		template:     func(obj client.Object) *corev1.PodTemplateSpec { return &obj.(*batchv1.Job).Spec.Template },
+	},
+	{
+		kind:         "CronJob",
+		podOwnerKind: "Job",
+		newObject:    func() client.Object { return &batchv1.CronJob{} },
+		newList:      func() client.ObjectList { return &batchv1.CronJobList{} },
+		template: func(obj client.Object) *corev1.PodTemplateSpec {
+			return &obj.(*batchv1.CronJob).Spec.JobTemplate.Spec.Template
+		},
+	},
+}
+
+// templateImages returns the image references of the workload's pod template, and the
+// digests of those referenced by digest, for IndexFieldTemplateImage.
+func (k workloadKind) templateImages(obj client.Object) []string {
+	var values []string
+	for _, img := range imageref.ExtractFromPodSpec(&k.template(obj).Spec) {
+		values = append(values, img.Image)
+		if img.Digest != "" {
+			values = append(values, img.Digest)
+		}
+	}
+	return values
+}
+
+// WorkloadScanReconciler creates the scans of workload pod templates as soon as their
+// images change, before the workload creates pods, and annotates each workload with
+// whether its template's images are approved.
+type WorkloadScanReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+	// Gate creates and looks up scans the way gated pods do, with its scan namespace,
+	// digest resolver, cluster scan and excluded namespace settings
+	Gate *PodGateReconciler
+}
+
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get;list;watch;patch
+
+// workloadReconciler reconciles the workloads of one kind
+type workloadReconciler struct {
+	*WorkloadScanReconciler
+	kind workloadKind
+}
+
+func (r *workloadReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.StartSpan(ctx, "WorkloadScanReconciler.Reconcile",
+		trace.WithAttributes(
+			attribute.String("workload.kind", r.kind.kind),
+			attribute.String("workload.name", req.Name),
+			attribute.String("workload.namespace", req.Namespace),
+		),
+	)
+	defer span.End()
+
+	if r.Gate.Namespaces.IsExcluded(ctx, req.Namespace) {
+		span.SetAttributes(attribute.Bool("excluded_namespace", true))
+		return ctrl.Result{}, nil
+	}
+
+	workload := r.kind.newObject()
+	if err := r.Get(ctx, req.NamespacedName, workload); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	template := r.kind.template(workload)
+	if template.Annotations[AnnotationBypassScan] == "true" {
+		span.SetAttributes(attribute.Bool("bypassed", true))
+		return ctrl.Result{}, nil
+	}
+
+	// The pods the workload will create, as far as scans and rules can tell
+	pod := r.templatePod(workload, template)
+	images, err := r.Gate.scannedImages(ctx, pod, imageref.ExtractFromPod(pod))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to resolve image digests")
+		log.FromContext(ctx).Error(err, "Failed to resolve template image digests",
+			"kind", r.kind.kind, "workload", req.Name)
+		return ctrl.Result{RequeueAfter: calculateBackoff(0)}, nil
+	}
+	span.SetAttributes(attribute.Int("image_count", len(images)))
+
+	rules, namespace, err := r.Gate.ruleContext(ctx, pod)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to load scan policy rules")
+		return ctrl.Result{}, err
+	}
+
+	approval := TemplateApproved
+	statuses := make([]string, 0, len(images))
+	var blocked []string
+	for _, img := range images {
+		status, err := r.Gate.scanStatus(ctx, pod, img)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to get ImageScan")
+			return ctrl.Result{}, err
+		}
+
+		phase := status.Phase
+		if phase == "" {
+			phase = securityv1alpha1.ScanPhasePending
+		}
+		switch phase {
+		case securityv1alpha1.ScanPhaseRegistered, securityv1alpha1.ScanPhasePassed:
+			if len(rules) > 0 {
+				result := policy.EvaluateRules(rules, policy.NewRuleInput(pod, namespace, img.Image, img.Digest, status))
+				if !result.Passed {
+					phase = securityv1alpha1.ScanPhaseFailed
+					blocked = append(blocked, fmt.Sprintf("%s violates %s: %s", img.Image, result.Policy, result.Message))
+				}
+			}
+		case securityv1alpha1.ScanPhaseFailed:
+			blocked = append(blocked, fmt.Sprintf("%s violates %s: %s", img.Image, status.Policy, status.Message))
+		default:
+			if approval == TemplateApproved {
+				approval = TemplatePending
+			}
+		}
+		statuses = append(statuses, img.Image+"="+string(phase))
+	}
+	if len(blocked) > 0 {
+		approval = TemplateBlocked
+	}
+	span.SetAttributes(attribute.String("template_approval", approval))
+
+	annotations := workload.GetAnnotations()
+	scanStatus := strings.Join(statuses, ",")
+	if annotations[AnnotationTemplateApproval] == approval && annotations[AnnotationScanStatus] == scanStatus {
+		return ctrl.Result{}, nil
+	}
+
+	// Annotation-only patches leave the template unchanged, so they don't trigger a rollout
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	previous := annotations[AnnotationTemplateApproval]
+	annotations[AnnotationTemplateApproval] = approval
+	annotations[AnnotationScanStatus] = scanStatus
+	workload.SetAnnotations(annotations)
+	if err := r.Patch(ctx, workload, patch); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to annotate workload")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if r.Recorder != nil && previous != approval {
+		switch approval {
+		case TemplateBlocked:
+			r.Recorder.Eventf(workload, corev1.EventTypeWarning, "TemplateBlocked",
+				"New pods will stay gated: %s", strings.Join(blocked, "; "))
+		case TemplateApproved:
+			r.Recorder.Event(workload, corev1.EventTypeNormal, "TemplateApproved",
+				"All pod template images passed security scan")
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// templatePod returns a pod as the workload would create it from its template.
+func (r *workloadReconciler) templatePod(workload client.Object, template *corev1.PodTemplateSpec) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       *template.Spec.DeepCopy(),
+	}
+	pod.Name = workload.GetName()
+	pod.Namespace = workload.GetNamespace()
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{{
+		Kind:       r.kind.podOwnerKind,
+		Name:       workload.GetName(),
+		Controller: &controller,
+	}}
+	return pod
+}
+
+// templateImagesChanged selects created workloads and updates that change the
+// template's images, skipping status updates and the controller's own annotations.
+func (r *workloadReconciler) templateImagesChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return !slices.Equal(
+				imageref.ExtractFromPodSpec(&r.kind.template(e.ObjectOld).Spec),
+				imageref.ExtractFromPodSpec(&r.kind.template(e.ObjectNew).Spec),
+			)
+		},
+		DeleteFunc: func(event.DeleteEvent) bool { return false },
+	}
+}
+
+// mapImageScanToWorkloads updates the approval of workloads using an image when its
+// scan completes, looking them up by the scan's image reference and digest.
+func (r *workloadReconciler) mapImageScanToWorkloads(ctx context.Context, obj client.Object) []reconcile.Request {
+	scan, ok := obj.(scanObject)
+	if !ok {
+		return nil
+	}
+	spec, status := scan.ScanSpec(), scan.ScanStatus()
+	if !isCompletedPhase(status.Phase) && status.Phase != securityv1alpha1.ScanPhaseError {
+		return nil
+	}
+
+	keys := []string{spec.Image}
+	if spec.Digest != "" && spec.Digest != spec.Image {
+		keys = append(keys, spec.Digest)
+	}
+	seen := map[types.NamespacedName]bool{}
+	var requests []reconcile.Request
+	for _, key := range keys {
+		list := r.kind.newList()
+		opts := []client.ListOption{client.MatchingFields{IndexFieldTemplateImage: key}}
+		if scan.GetNamespace() != "" && r.Gate.ScanNamespace == "" {
+			opts = append(opts, client.InNamespace(scan.GetNamespace()))
+		}
+		if err := r.List(ctx, list, opts...); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list workloads for ImageScan mapping", "kind", r.kind.kind)
+			return nil
+		}
+		objects, err := meta.ExtractList(list)
+		if err != nil {
+			return nil
+		}
+		for _, o := range objects {
+			workload := o.(client.Object)
+			name := types.NamespacedName{Name: workload.GetName(), Namespace: workload.GetNamespace()}
+			if seen[name] || r.Gate.Namespaces.IsExcluded(ctx, workload.GetNamespace()) {
+				continue
+			}
+			seen[name] = true
+			requests = append(requests, reconcile.Request{NamespacedName: name})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up a controller per workload kind.
+func (r *WorkloadScanReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	for _, kind := range workloadKinds {
+		if err := mgr.GetFieldIndexer().IndexField(
+			context.Background(),
+			kind.newObject(),
+			IndexFieldTemplateImage,
+			kind.templateImages,
+		); err != nil {
+			return fmt.Errorf("failed to set up %s template image indexer: %w", kind.kind, err)
+		}
+
+		kr := &workloadReconciler{WorkloadScanReconciler: r, kind: kind}
+		bldr := ctrl.NewControllerManagedBy(mgr).
+			Named("workloadscan-"+strings.ToLower(kind.kind)).
+			For(kind.newObject(), builder.WithPredicates(kr.templateImagesChanged())).
+			Watches(
+				&securityv1alpha1.ImageScan{},
+				handler.EnqueueRequestsFromMapFunc(kr.mapImageScanToWorkloads),
+			)
+		if r.Gate.ClusterScans {
+			bldr = bldr.Watches(
+				&securityv1alpha1.ClusterImageScan{},
+				handler.EnqueueRequestsFromMapFunc(kr.mapImageScanToWorkloads),
+			)
+		}
+		if err := bldr.Complete(kr); err != nil {
+			return fmt.Errorf("setting up %s scan controller: %w", kind.kind, err)
+		}
+	}
+	return nil
+}
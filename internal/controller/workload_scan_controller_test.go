@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+)
+
+var _ = Describe("WorkloadScanReconciler", func() {
+	const (
+		approvedImage = "registry.example.com/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		newImage      = "registry.example.com/app@sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	)
+
+	var (
+		scheme *runtime.Scheme
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		Expect(appsv1.AddToScheme(scheme)).To(Succeed())
+		Expect(batchv1.AddToScheme(scheme)).To(Succeed())
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		ctx = context.Background()
+	})
+
+	deployment := func(images ...string) *appsv1.Deployment {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		for _, image := range images {
+			d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers,
+				corev1.Container{Name: "app", Image: image})
+		}
+		return d
+	}
+
+	scan := func(image string, status securityv1alpha1.ImageScanStatus) *securityv1alpha1.ImageScan {
+		img := imageref.ExtractFromPodSpec(&corev1.PodSpec{Containers: []corev1.Container{{Image: image}}})[0]
+		return &securityv1alpha1.ImageScan{
+			ObjectMeta: metav1.ObjectMeta{Name: imageref.ScanName(img), Namespace: "default"},
+			Spec:       securityv1alpha1.ImageScanSpec{Image: img.Image, Digest: img.Digest},
+			Status:     status,
+		}
+	}
+
+	newClient := func(objs ...client.Object) client.Client {
+		builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...)
+		for _, k := range workloadKinds {
+			builder = builder.WithIndex(k.newObject(), IndexFieldTemplateImage, k.templateImages)
+		}
+		return builder.Build()
+	}
+
+	reconcileWorkload := func(kind string, workload client.Object, objs ...client.Object) (client.Client, *record.FakeRecorder) {
+		c := newClient(append(objs, workload)...)
+		recorder := record.NewFakeRecorder(10)
+		parent := &WorkloadScanReconciler{Client: c, Recorder: recorder, Gate: &PodGateReconciler{Client: c, Scheme: scheme}}
+		for _, k := range workloadKinds {
+			if k.kind != kind {
+				continue
+			}
+			r := &workloadReconciler{WorkloadScanReconciler: parent, kind: k}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(workload)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(workload), workload)).To(Succeed())
+		return c, recorder
+	}
+
+	It("should create scans for new template images and mark the workload pending", func() {
+		d := deployment(approvedImage, newImage)
+		c, _ := reconcileWorkload("Deployment", d,
+			scan(approvedImage, securityv1alpha1.ImageScanStatus{Phase: securityv1alpha1.ScanPhasePassed}))
+
+		Expect(d.Annotations).To(HaveKeyWithValue(AnnotationTemplateApproval, TemplatePending))
+		Expect(d.Annotations).To(HaveKeyWithValue(AnnotationScanStatus,
+			approvedImage+"=Passed,"+newImage+"=Pending"))
+
+		created := scan(newImage, securityv1alpha1.ImageScanStatus{})
+		Expect(c.Get(ctx, types.NamespacedName{Name: created.Name, Namespace: "default"},
+			&securityv1alpha1.ImageScan{})).To(Succeed())
+	})
+
+	It("should mark workloads approved once every image passed", func() {
+		d := deployment(approvedImage)
+		_, recorder := reconcileWorkload("Deployment", d,
+			scan(approvedImage, securityv1alpha1.ImageScanStatus{Phase: securityv1alpha1.ScanPhaseRegistered}))
+		Expect(d.Annotations).To(HaveKeyWithValue(AnnotationTemplateApproval, TemplateApproved))
+		Expect(recorder.Events).To(Receive(ContainSubstring("TemplateApproved")))
+	})
+
+	It("should mark CronJobs blocked with the violated policy", func() {
+		cronJob := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default"}}
+		cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{Name: "job", Image: newImage}}
+		_, recorder := reconcileWorkload("CronJob", cronJob,
+			scan(newImage, securityv1alpha1.ImageScanStatus{
+				Phase:   securityv1alpha1.ScanPhaseFailed,
+				Policy:  "ClusterScanPolicy/baseline",
+				Message: "2 critical vulnerabilities",
+			}))
+
+		Expect(cronJob.Annotations).To(HaveKeyWithValue(AnnotationTemplateApproval, TemplateBlocked))
+		Expect(recorder.Events).To(Receive(And(
+			ContainSubstring("TemplateBlocked"),
+			ContainSubstring("ClusterScanPolicy/baseline"),
+		)))
+	})
+
+	It("should skip templates with the bypass annotation", func() {
+		d := deployment(newImage)
+		d.Spec.Template.Annotations = map[string]string{AnnotationBypassScan: "true"}
+		c, _ := reconcileWorkload("Deployment", d)
+		Expect(d.Annotations).NotTo(HaveKey(AnnotationTemplateApproval))
+
+		var scans securityv1alpha1.ImageScanList
+		Expect(c.List(ctx, &scans)).To(Succeed())
+		Expect(scans.Items).To(BeEmpty())
+	})
+
+	It("should map completed scans to the workloads whose templates use the image", func() {
+		const tagged = "registry.example.com/app:1.0"
+		web := deployment(approvedImage)
+		worker := deployment(tagged)
+		worker.Name = "worker"
+		other := deployment(newImage)
+		other.Name = "other"
+		c := newClient(web, worker, other)
+		r := &workloadReconciler{
+			WorkloadScanReconciler: &WorkloadScanReconciler{Client: c, Gate: &PodGateReconciler{Client: c, Scheme: scheme}},
+			kind:                   workloadKinds[0],
+		}
+
+		passed := scan(approvedImage, securityv1alpha1.ImageScanStatus{Phase: securityv1alpha1.ScanPhasePassed})
+		Expect(r.mapImageScanToWorkloads(ctx, passed)).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"}},
+		))
+
+		byTag := scan(approvedImage, securityv1alpha1.ImageScanStatus{Phase: securityv1alpha1.ScanPhasePassed})
+		byTag.Spec.Image = tagged
+		Expect(r.mapImageScanToWorkloads(ctx, byTag)).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"}},
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: "worker", Namespace: "default"}},
+		))
+
+		Expect(r.mapImageScanToWorkloads(ctx, scan(newImage, securityv1alpha1.ImageScanStatus{}))).To(BeEmpty())
+	})
+
+	It("should only react to template image changes", func() {
+		r := &workloadReconciler{WorkloadScanReconciler: &WorkloadScanReconciler{}, kind: workloadKinds[0]}
+		changed := r.templateImagesChanged()
+
+		old, updated := deployment(approvedImage), deployment(approvedImage)
+		updated.Annotations = map[string]string{AnnotationTemplateApproval: TemplateApproved}
+		updated.Status.ReadyReplicas = 3
+		Expect(changed.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated})).To(BeFalse())
+		Expect(changed.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: deployment(newImage)})).To(BeTrue())
+	})
+})
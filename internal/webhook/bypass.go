@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+const (
+	// AnnotationBypassedBy records the user who created a pod with the bypass annotation
+	AnnotationBypassedBy = "scans.aquasec.community/bypassed-by"
+
+	// BypassVerb is the verb on imagescans a user needs to bypass scanning in a namespace
+	BypassVerb = "bypass"
+)
+
+// bypasses counts bypass annotations checked at admission, by namespace and result
+var bypasses = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "aqua_scan_gate_bypasses_total",
+	Help: "Number of scan bypass requests at admission, by namespace and result (allowed or denied)",
+}, []string{"namespace", "result"})
+
+func init() {
+	metrics.Registry.MustRegister(bypasses)
+}
+
+// workloadTemplatePaths locate the pod template of the workloads whose controllers
+// create pods, or other workloads, from it
+var workloadTemplatePaths = map[schema.GroupKind][]string{
+	{Group: "apps", Kind: "Deployment"}:  {"spec", "template"},
+	{Group: "apps", Kind: "ReplicaSet"}:  {"spec", "template"},
+	{Group: "apps", Kind: "StatefulSet"}: {"spec", "template"},
+	{Group: "apps", Kind: "DaemonSet"}:   {"spec", "template"},
+	{Group: "batch", Kind: "Job"}:        {"spec", "template"},
+	{Group: "batch", Kind: "CronJob"}:    {"spec", "jobTemplate", "spec", "template"},
+}
+
+// BypassAuthorizer decides whether the user making an admission request may bypass
+// scanning, with a SubjectAccessReview for BypassVerb on imagescans in the pod's namespace
+type BypassAuthorizer struct {
+	// Client creates SubjectAccessReviews
+	Client client.Client
+
+	// Reader reads the workloads that own pods and workloads with the bypass annotation,
+	// normally without caching them (nil = use Client)
+	Reader client.Reader
+
+	// ExemptServiceAccounts may always bypass, as namespace/name
+	ExemptServiceAccounts map[string]bool
+
+	// ExemptGroups may always bypass, e.g., a break-glass group
+	ExemptGroups map[string]bool
+}
+
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+// +kubebuilder:rbac:groups=apps,resources=deployments;replicasets;statefulsets;daemonsets,verbs=get
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get
+
+// Authorize returns whether the user may bypass scanning in namespace, and why.
+func (a *BypassAuthorizer) Authorize(ctx context.Context, namespace string, user authenticationv1.UserInfo) (bool, string, error) {
+	if sa, ok := strings.CutPrefix(user.Username, "system:serviceaccount:"); ok {
+		if a.ExemptServiceAccounts[strings.Replace(sa, ":", "/", 1)] {
+			return true, "exempt service account", nil
+		}
+	}
+	for _, group := range user.Groups {
+		if a.ExemptGroups[group] {
+			return true, "exempt group " + group, nil
+		}
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, values := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      BypassVerb,
+				Group:     securityv1alpha1.GroupVersion.Group,
+				Resource:  "imagescans",
+			},
+		},
+	}
+	if err := a.Client.Create(ctx, review); err != nil {
+		return false, "", fmt.Errorf("creating SubjectAccessReview: %w", err)
+	}
+	if !review.Status.Allowed {
+		return false, review.Status.Reason, nil
+	}
+	return true, "authorized to " + BypassVerb + " imagescans", nil
+}
+
+// OwnerBypassedBy returns who bypassed scanning on the pod template of the workload
+// controlling an object, when a Kubernetes workload controller creates the object from
+// that template. The bypass was authorized when that workload was admitted, so the
+// object inherits it. It returns "" when the object doesn't inherit an authorized bypass.
+func (a *BypassAuthorizer) OwnerBypassedBy(ctx context.Context, namespace string, owners []metav1.OwnerReference, user authenticationv1.UserInfo) (string, error) {
+	if !isWorkloadController(user) {
+		return "", nil
+	}
+	var owner *metav1.OwnerReference
+	for i := range owners {
+		if owners[i].Controller != nil && *owners[i].Controller {
+			owner = &owners[i]
+		}
+	}
+	if owner == nil {
+		return "", nil
+	}
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return "", nil
+	}
+	path, ok := workloadTemplatePaths[gv.WithKind(owner.Kind).GroupKind()]
+	if !ok {
+		return "", nil
+	}
+
+	reader := a.Reader
+	if reader == nil {
+		reader = a.Client
+	}
+	workload := &unstructured.Unstructured{}
+	workload.SetGroupVersionKind(gv.WithKind(owner.Kind))
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner.Name}, workload); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	if workload.GetUID() != owner.UID {
+		return "", nil
+	}
+	annotations := templateAnnotations(workload, path)
+	if annotations[AnnotationBypassScan] != "true" {
+		return "", nil
+	}
+	return annotations[AnnotationBypassedBy], nil
+}
+
+// bypassDenied denies a user the bypass annotation, saying which permission it requires.
+func bypassDenied(namespace string, user authenticationv1.UserInfo, reason string) admission.Response {
+	message := fmt.Sprintf("user %q may not bypass image scanning in namespace %s: %s requires %q on imagescans.%s",
+		user.Username, namespace, AnnotationBypassScan, BypassVerb, securityv1alpha1.GroupVersion.Group)
+	if reason != "" {
+		message += " (" + reason + ")"
+	}
+	return admission.Denied(message)
+}
+
+// isWorkloadController returns true for the kube-controller-manager, which creates
+// workload pods and ReplicaSets and Jobs from the templates of their owners.
+func isWorkloadController(user authenticationv1.UserInfo) bool {
+	return user.Username == "system:kube-controller-manager" ||
+		strings.HasPrefix(user.Username, "system:serviceaccount:kube-system:")
+}
+
+// templateAnnotations returns the annotations of the pod template at path in a workload.
+func templateAnnotations(workload *unstructured.Unstructured, path []string) map[string]string {
+	annotations, _, _ := unstructured.NestedStringMap(workload.Object, slices.Concat(path, []string{"metadata", "annotations"})...)
+	return annotations
+}
+
+// ParseExemptions parses a comma-separated list into a set, skipping empty entries.
+func ParseExemptions(list string) map[string]bool {
+	set := map[string]bool{}
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			set[entry] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,324 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("Bypass governance", func() {
+	const image = "registry.example.com/app:v1"
+
+	var (
+		ctx     context.Context
+		reviews []authorizationv1.SubjectAccessReviewSpec
+		sarErr  error
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		reviews = nil
+		sarErr = nil
+	})
+
+	// newAuthorizer answers SubjectAccessReviews by allowing only the user "sre", and
+	// reads workloads from objs
+	newAuthorizer := func(objs ...client.Object) *BypassAuthorizer {
+		scheme := runtime.NewScheme()
+		Expect(authorizationv1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+				if sarErr != nil {
+					return sarErr
+				}
+				review := obj.(*authorizationv1.SubjectAccessReview)
+				reviews = append(reviews, review.Spec)
+				review.Status.Allowed = review.Spec.User == "sre"
+				if !review.Status.Allowed {
+					review.Status.Reason = "no RBAC policy matched"
+				}
+				return nil
+			},
+		}).Build()
+		appsScheme := runtime.NewScheme()
+		Expect(appsv1.AddToScheme(appsScheme)).To(Succeed())
+		return &BypassAuthorizer{
+			Client:                c,
+			Reader:                fake.NewClientBuilder().WithScheme(appsScheme).WithObjects(objs...).Build(),
+			ExemptServiceAccounts: ParseExemptions("ci/deployer"),
+			ExemptGroups:          ParseExemptions("break-glass, "),
+		}
+	}
+
+	bypassPod := func() *corev1.Pod {
+		return testPod(map[string]string{AnnotationBypassScan: "true"}, image)
+	}
+	asUser := func(req admission.Request, username string, groups ...string) admission.Request {
+		req.UserInfo = authenticationv1.UserInfo{Username: username, Groups: groups}
+		return req
+	}
+	updateRequest := func(oldPod, pod *corev1.Pod) admission.Request {
+		req := podRequest(admissionv1.Update, pod)
+		raw, err := json.Marshal(oldPod)
+		Expect(err).NotTo(HaveOccurred())
+		req.OldObject = runtime.RawExtension{Raw: raw}
+		return req
+	}
+
+	It("should check bypasses with a SubjectAccessReview for the bypass verb", func() {
+		v := &PodValidator{Bypass: newAuthorizer()}
+
+		resp := v.Handle(ctx, asUser(podRequest(admissionv1.Create, bypassPod()), "dev"))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring(`user "dev" may not bypass image scanning in namespace default`))
+		Expect(reviews).To(HaveLen(1))
+		Expect(reviews[0].ResourceAttributes).To(Equal(&authorizationv1.ResourceAttributes{
+			Namespace: "default",
+			Verb:      BypassVerb,
+			Group:     "scans.aquasec.community",
+			Resource:  "imagescans",
+		}))
+
+		resp = v.Handle(ctx, asUser(podRequest(admissionv1.Create, bypassPod()), "sre"))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.AuditAnnotations).To(HaveKeyWithValue("bypassed-by", "sre"))
+	})
+
+	It("should allow exempt service accounts and groups without a review", func() {
+		v := &PodValidator{Bypass: newAuthorizer()}
+
+		resp := v.Handle(ctx, asUser(podRequest(admissionv1.Create, bypassPod()),
+			"system:serviceaccount:ci:deployer"))
+		Expect(resp.Allowed).To(BeTrue())
+		resp = v.Handle(ctx, asUser(podRequest(admissionv1.Create, bypassPod()), "oncall", "break-glass"))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(reviews).To(BeEmpty())
+	})
+
+	It("should check bypasses added to existing pods only", func() {
+		v := &PodValidator{Bypass: newAuthorizer()}
+
+		added := bypassPod()
+		added.Annotations[AnnotationBypassedBy] = "dev"
+		resp := v.Handle(ctx, asUser(updateRequest(testPod(nil, image), added), "dev"))
+		Expect(resp.Allowed).To(BeFalse())
+
+		// Updating an already bypassed pod, e.g., by the Pod gate controller
+		resp = v.Handle(ctx, asUser(updateRequest(bypassPod(), bypassPod()), "dev"))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(reviews).To(HaveLen(1))
+	})
+
+	It("should not let users change who bypassed scanning", func() {
+		v := &PodValidator{Bypass: newAuthorizer()}
+		forged := bypassPod()
+		forged.Annotations[AnnotationBypassedBy] = "sre"
+
+		resp := v.Handle(ctx, asUser(updateRequest(bypassPod(), forged), "dev"))
+		Expect(resp.Allowed).To(BeFalse())
+	})
+
+	It("should reject bypasses when access can't be reviewed", func() {
+		v := &PodValidator{Bypass: newAuthorizer()}
+		sarErr = errors.New("apiserver unavailable")
+
+		resp := v.Handle(ctx, asUser(podRequest(admissionv1.Create, bypassPod()), "sre"))
+		Expect(resp.Allowed).To(BeFalse())
+	})
+
+	It("should record the requester on bypassed pods at admission", func() {
+		m := &PodMutator{}
+		Expect(m.InjectDecoder(admission.NewDecoder(runtime.NewScheme()))).To(Succeed())
+
+		original := bypassPod()
+		pod := patchedPod(original, m.Handle(ctx, asUser(podRequest(admissionv1.Create, original), "sre")))
+		Expect(pod.Annotations).To(HaveKeyWithValue(AnnotationBypassedBy, "sre"))
+		Expect(pod.Spec.SchedulingGates).To(BeEmpty())
+
+		// Users can't pre-set the annotation on pods that aren't bypassed
+		forged := testPod(map[string]string{AnnotationBypassedBy: "sre"}, image)
+		pod = patchedPod(forged, m.Handle(ctx, asUser(podRequest(admissionv1.Create, forged), "dev")))
+		Expect(pod.Annotations).NotTo(HaveKey(AnnotationBypassedBy))
+	})
+
+	It("should record and authorize the user adding the bypass to an existing pod", func() {
+		m := &PodMutator{}
+		Expect(m.InjectDecoder(admission.NewDecoder(runtime.NewScheme()))).To(Succeed())
+		v := &PodValidator{Bypass: newAuthorizer()}
+		gated := testPod(nil, image)
+
+		pod := patchedPod(bypassPod(), m.Handle(ctx, asUser(updateRequest(gated, bypassPod()), "sre")))
+		Expect(pod.Annotations).To(HaveKeyWithValue(AnnotationBypassedBy, "sre"))
+		resp := v.Handle(ctx, asUser(updateRequest(gated, pod), "sre"))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.AuditAnnotations).To(HaveKeyWithValue("bypassed-by", "sre"))
+
+		// Only the requester can be recorded
+		forged := bypassPod()
+		forged.Annotations[AnnotationBypassedBy] = "admin"
+		Expect(v.Handle(ctx, asUser(updateRequest(gated, forged), "sre")).Allowed).To(BeFalse())
+
+		// Later updates leave the annotation alone
+		resp = m.Handle(ctx, asUser(updateRequest(pod, pod), "dev"))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+
+	Describe("workload pod templates", func() {
+		const replicaSetController = "system:serviceaccount:kube-system:replicaset-controller"
+
+		deployment := func(templateAnnotations map[string]string) *appsv1.Deployment {
+			return &appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Annotations: templateAnnotations},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+				}},
+			}
+		}
+		workloadRequest := func(operation admissionv1.Operation, obj, oldObj client.Object, username string) admission.Request {
+			raw, err := json.Marshal(obj)
+			Expect(err).NotTo(HaveOccurred())
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: operation,
+				Namespace: "default",
+				Name:      obj.GetName(),
+				Kind:      metav1.GroupVersionKind(obj.GetObjectKind().GroupVersionKind()),
+				Object:    runtime.RawExtension{Raw: raw},
+				UserInfo:  authenticationv1.UserInfo{Username: username},
+			}}
+			if oldObj != nil {
+				raw, err := json.Marshal(oldObj)
+				Expect(err).NotTo(HaveOccurred())
+				req.OldObject = runtime.RawExtension{Raw: raw}
+			}
+			return req
+		}
+		mutateWorkload := func(req admission.Request, obj *appsv1.Deployment) *appsv1.Deployment {
+			resp := (&WorkloadMutator{}).Handle(ctx, req)
+			Expect(resp.Allowed).To(BeTrue())
+			if len(resp.Patches) == 0 {
+				return obj
+			}
+			original, err := json.Marshal(obj)
+			Expect(err).NotTo(HaveOccurred())
+			ops, err := json.Marshal(resp.Patches)
+			Expect(err).NotTo(HaveOccurred())
+			patch, err := jsonpatch.DecodePatch(ops)
+			Expect(err).NotTo(HaveOccurred())
+			patched, err := patch.Apply(original)
+			Expect(err).NotTo(HaveOccurred())
+			result := &appsv1.Deployment{}
+			Expect(json.Unmarshal(patched, result)).To(Succeed())
+			return result
+		}
+
+		It("should authorize the user admitting the workload and record them on the template", func() {
+			v := &WorkloadValidator{Bypass: newAuthorizer()}
+			bypassed := map[string]string{AnnotationBypassScan: "true"}
+
+			resp := v.Handle(ctx, workloadRequest(admissionv1.Create,
+				mutateWorkload(workloadRequest(admissionv1.Create, deployment(bypassed), nil, "dev"), deployment(bypassed)), nil, "dev"))
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(resp.Result.Message).To(ContainSubstring(`user "dev" may not bypass image scanning`))
+
+			mutated := mutateWorkload(workloadRequest(admissionv1.Create, deployment(bypassed), nil, "sre"), deployment(bypassed))
+			Expect(mutated.Spec.Template.Annotations).To(HaveKeyWithValue(AnnotationBypassedBy, "sre"))
+			resp = v.Handle(ctx, workloadRequest(admissionv1.Create, mutated, nil, "sre"))
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.AuditAnnotations).To(HaveKeyWithValue("bypassed-by", "sre"))
+
+			// Later updates keep who bypassed scanning, whoever applies them
+			updated := mutateWorkload(workloadRequest(admissionv1.Update, deployment(bypassed), mutated, "dev"), deployment(bypassed))
+			Expect(updated.Spec.Template.Annotations).To(HaveKeyWithValue(AnnotationBypassedBy, "sre"))
+			Expect(v.Handle(ctx, workloadRequest(admissionv1.Update, updated, mutated, "dev")).Allowed).To(BeTrue())
+			Expect(reviews).To(HaveLen(2))
+		})
+
+		It("should authorize the user changing the pod template of a bypassed workload", func() {
+			v := &WorkloadValidator{Bypass: newAuthorizer()}
+			bypassed := map[string]string{AnnotationBypassScan: "true", AnnotationBypassedBy: "sre"}
+			original := deployment(bypassed)
+			changed := func() *appsv1.Deployment {
+				d := deployment(map[string]string{AnnotationBypassScan: "true", AnnotationBypassedBy: "sre"})
+				d.Spec.Template.Spec.Containers[0].Image = "registry.example.com/app:v2"
+				return d
+			}
+
+			mutated := mutateWorkload(workloadRequest(admissionv1.Update, changed(), original, "dev"), changed())
+			Expect(mutated.Spec.Template.Annotations).To(HaveKeyWithValue(AnnotationBypassedBy, "dev"))
+			resp := v.Handle(ctx, workloadRequest(admissionv1.Update, mutated, original, "dev"))
+			Expect(resp.Allowed).To(BeFalse())
+
+			// Keeping the approver's name doesn't help either
+			Expect(v.Handle(ctx, workloadRequest(admissionv1.Update, changed(), original, "dev")).Allowed).To(BeFalse())
+
+			mutated = mutateWorkload(workloadRequest(admissionv1.Update, changed(), original, "sre"), changed())
+			resp = v.Handle(ctx, workloadRequest(admissionv1.Update, mutated, original, "sre"))
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.AuditAnnotations).To(HaveKeyWithValue("bypassed-by", "sre"))
+		})
+
+		It("should let pods inherit the bypass authorized on their workload", func() {
+			controller := true
+			replicaSet := &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-abc", Namespace: "default", UID: types.UID("rs-uid")},
+				Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+						AnnotationBypassScan: "true", AnnotationBypassedBy: "sre",
+					}},
+				}},
+			}
+			v := &PodValidator{Bypass: newAuthorizer(replicaSet)}
+			ownedPod := func(bypassedBy string) *corev1.Pod {
+				pod := bypassPod()
+				pod.Annotations[AnnotationBypassedBy] = bypassedBy
+				pod.OwnerReferences = []metav1.OwnerReference{{
+					APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-abc", UID: "rs-uid", Controller: &controller,
+				}}
+				return pod
+			}
+
+			resp := v.Handle(ctx, asUser(podRequest(admissionv1.Create, ownedPod("sre")), replicaSetController))
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.AuditAnnotations).To(HaveKeyWithValue("bypassed-by", "sre"))
+			Expect(reviews).To(BeEmpty())
+
+			// Users can't claim a workload's bypass for pods they create themselves
+			resp = v.Handle(ctx, asUser(podRequest(admissionv1.Create, ownedPod("sre")), "dev"))
+			Expect(resp.Allowed).To(BeFalse())
+
+			// Nor can the controller's pods claim someone else authorized them
+			resp = v.Handle(ctx, asUser(podRequest(admissionv1.Create, ownedPod("admin")), replicaSetController))
+			Expect(resp.Allowed).To(BeFalse())
+		})
+
+		It("should keep the template's bypassed-by on pods created by workload controllers", func() {
+			m := &PodMutator{}
+			Expect(m.InjectDecoder(admission.NewDecoder(runtime.NewScheme()))).To(Succeed())
+			original := bypassPod()
+			original.Annotations[AnnotationBypassedBy] = "sre"
+
+			pod := patchedPod(original, m.Handle(ctx, asUser(podRequest(admissionv1.Create, original), replicaSetController)))
+			Expect(pod.Annotations).To(HaveKeyWithValue(AnnotationBypassedBy, "sre"))
+		})
+	})
+})
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+)
+
+// AnnotationOriginalImages records the references digest pinning replaced, as a JSON
+// object of container name to image
+const AnnotationOriginalImages = "scans.aquasec.community/original-images"
+
+// containerImage points at the image of a container or init container
+type containerImage struct {
+	name  string
+	image *string
+}
+
+// podContainerImages returns the images of the pod's containers and init containers.
+// Ephemeral containers aren't pinned: they are added to running pods through the
+// ephemeralcontainers subresource, which can't update imageref.AnnotationScannedDigests,
+// so their index digests would be scanned instead of their platform digests.
+func podContainerImages(spec *corev1.PodSpec) []containerImage {
+	var images []containerImage
+	for i := range spec.InitContainers {
+		images = append(images, containerImage{spec.InitContainers[i].Name, &spec.InitContainers[i].Image})
+	}
+	for i := range spec.Containers {
+		images = append(images, containerImage{spec.Containers[i].Name, &spec.Containers[i].Image})
+	}
+	return images
+}
+
+// pinDigests rewrites tag references to repo:tag@digest, using the same resolver cache
+// and pull credentials as the Pod gate controller, so a tag moved after admission can't
+// make the kubelet pull another image. Multi-arch images are pinned to their index
+// digest, so nodes of every platform can pull them. The replaced references are recorded
+// in AnnotationOriginalImages, and the platform digests the Pod gate controller scans, as
+// it does for unpinned images, in imageref.AnnotationScannedDigests.
+func (m *PodMutator) pinDigests(ctx context.Context, pod *corev1.Pod, containers []containerImage) (int, error) {
+	if !m.PinDigests || m.Resolver == nil {
+		return 0, nil
+	}
+
+	original := map[string]string{}
+	if existing := pod.Annotations[AnnotationOriginalImages]; existing != "" {
+		_ = json.Unmarshal([]byte(existing), &original)
+	}
+	scanned := map[string]string{}
+
+	pinned, err := m.resolveImages(ctx, pod, containers, original, scanned)
+	if pinned > 0 {
+		// Record what was pinned even if a later image failed to resolve
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		for annotation, value := range map[string]map[string]string{
+			AnnotationOriginalImages:          original,
+			imageref.AnnotationScannedDigests: scanned,
+		} {
+			data, marshalErr := json.Marshal(value)
+			if marshalErr != nil {
+				return pinned, marshalErr
+			}
+			pod.Annotations[annotation] = string(data)
+		}
+	}
+	return pinned, err
+}
+
+// resolveImages pins the tag references of containers, recording each replaced reference
+// in original and the platform digest of each pinned reference in scanned, until an image
+// fails to resolve.
+func (m *PodMutator) resolveImages(ctx context.Context, pod *corev1.Pod, containers []containerImage, original, scanned map[string]string) (int, error) {
+	var opts []remote.Option
+	pinned := 0
+	for _, c := range containers {
+		if *c.image == "" || strings.Contains(*c.image, "@") {
+			continue
+		}
+		// Pull credentials are only looked up when a digest is not already cached
+		_, manifestCached := m.Resolver.LookupManifest(*c.image)
+		_, platformCached := m.Resolver.Lookup(*c.image)
+		if (!manifestCached || !platformCached) && opts == nil {
+			reader := m.APIReader
+			if reader == nil {
+				reader = m.Client
+			}
+			keychain, err := imageref.PodKeychain(ctx, reader, pod)
+			if err != nil {
+				return pinned, err
+			}
+			opts = []remote.Option{remote.WithAuthFromKeychain(keychain)}
+		}
+
+		// The platform digest is resolved as the Pod gate controller resolves tags
+		platform, err := m.Resolver.ResolveImageRef(ctx, imageref.ImageRef{Image: *c.image}, opts...)
+		if err != nil {
+			return pinned, fmt.Errorf("resolving digest for %s: %w", *c.image, err)
+		}
+		digest, err := m.Resolver.ResolveManifestDigest(ctx, *c.image, opts...)
+		if err != nil {
+			return pinned, fmt.Errorf("resolving digest for %s: %w", *c.image, err)
+		}
+		original[c.name] = *c.image
+		*c.image += "@" + digest
+		scanned[*c.image] = platform.Digest
+		pinned++
+	}
+	return pinned, nil
+}
+
+// pinImages pins the containers' images, returning an admission warning if some could
+// not be pinned. The pod is still admitted, and gated on the digests the Pod gate
+// controller resolves.
+func (m *PodMutator) pinImages(ctx context.Context, pod *corev1.Pod, containers []containerImage) []string {
+	pinned, err := m.pinDigests(ctx, pod, containers)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("pinned_images_count", pinned))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to pin image digests", "pod", pod.Name, "namespace", pod.Namespace)
+		return []string{fmt.Sprintf("image digests not pinned: %v", err)}
+	}
+	return nil
+}
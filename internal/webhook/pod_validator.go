@@ -0,0 +1,307 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+	"github.com/richardmsong/aqua-scan-gate/pkg/enforcement"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageexclude"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imagepolicy"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/scope"
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+// PodValidator rejects pods whose images violate the image reference policy, new pods
+// with an image whose scan has already failed, and bypasses by unauthorized users
+type PodValidator struct {
+	// Scans looks up existing scan verdicts (nil = don't check verdicts)
+	Scans *ScanLookup
+
+	// ImagePolicy holds the current image reference policy (nil = allow every image)
+	ImagePolicy *imagepolicy.FileSource
+
+	// RegistryMirrors are the mirrors images must be pulled through when the policy requires it
+	RegistryMirrors []aqua.RegistryMirror
+
+	// Namespaces decides which namespaces are validated, shared with the mutating webhook
+	// (nil = all)
+	Namespaces *scope.Namespaces
+
+	// Enforcement decides per namespace whether failed images are rejected or only
+	// reported (nil = reject)
+	Enforcement *enforcement.Modes
+
+	// Bypass authorizes the users setting the bypass annotation (nil = anyone may bypass)
+	Bypass *BypassAuthorizer
+
+	// Exclusions are the images exempt from scanning, whose verdicts aren't checked
+	Exclusions *imageexclude.Exclusions
+}
+
+// +kubebuilder:webhook:path=/validate-v1-pod,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create;update,versions=v1,name=vpod.scans.aquasec.community,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-v1-pod,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=pods/ephemeralcontainers,verbs=update,versions=v1,name=vpodephemeral.scans.aquasec.community,admissionReviewVersions=v1
+
+func (v *PodValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ctx, span := tracing.StartSpan(ctx, "PodValidator.Handle",
+		trace.WithAttributes(
+			tracing.AttrPodName.String(req.Name),
+			tracing.AttrPodNamespace.String(req.Namespace),
+			attribute.String("operation", string(req.Operation)),
+		),
+	)
+	defer span.End()
+
+	logger := log.FromContext(ctx)
+
+	if v.Namespaces.IsExcluded(ctx, req.Namespace) {
+		span.SetAttributes(attribute.Bool("excluded_namespace", true))
+		return admission.Allowed("excluded namespace")
+	}
+
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		span.RecordError(err)
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if denial := scannedDigestsChanged(req, pod); denial != nil {
+		return *denial
+	}
+
+	bypassedBy, denial := v.authorizeBypass(ctx, req, pod)
+	if denial != nil {
+		return *denial
+	}
+
+	if denied := v.imagePolicyViolations(req, pod); len(denied) > 0 {
+		span.SetAttributes(attribute.Int("policy_denied_images_count", len(denied)))
+		logger.Info("Rejecting pod violating the image policy",
+			"pod", pod.Name, "namespace", req.Namespace, "images", denied)
+		return admission.Denied("image policy violated: " + strings.Join(denied, "; "))
+	}
+
+	// Existing pods are gated instead; bypassed pods aren't gated at all
+	if bypassedBy != "" {
+		// The API server audit log records who bypassed scanning
+		resp := admission.Allowed("")
+		resp.AuditAnnotations = map[string]string{"bypassed-by": bypassedBy}
+		return resp
+	}
+	if req.Operation != admissionv1.Create || pod.Annotations[AnnotationBypassScan] == "true" {
+		return admission.Allowed("")
+	}
+
+	// Images whose scan already failed would keep the pod gated forever, so say so now.
+	// Images without a verdict yet are left to the scheduling gate.
+	failed := v.failedImages(ctx, req.Namespace, pod)
+	span.SetAttributes(attribute.Int("failed_images_count", len(failed)))
+	if len(failed) == 0 {
+		return admission.Allowed("")
+	}
+
+	mode := v.Enforcement.Mode(ctx, req.Namespace)
+	span.SetAttributes(attribute.String("enforcement_mode", string(mode)))
+	switch mode {
+	case enforcement.Warn, enforcement.Audit:
+		logger.Info("Admitting pod with images that failed scanning",
+			"pod", pod.Name, "namespace", req.Namespace, "images", failed, "mode", mode)
+		if req.DryRun == nil || !*req.DryRun {
+			enforcement.RecordWouldBlock(mode, req.Namespace, "admission")
+		}
+		if mode == enforcement.Audit {
+			return admission.Allowed("")
+		}
+		return admission.Allowed("").WithWarnings("image scan failed, pod would be blocked in enforce mode: " + strings.Join(failed, "; "))
+	}
+	logger.Info("Rejecting pod with images that failed scanning",
+		"pod", pod.Name, "namespace", req.Namespace, "images", failed)
+	return admission.Denied("image scan failed: " + strings.Join(failed, "; "))
+}
+
+// authorizeBypass checks that the user creating a pod with the bypass annotation, or
+// adding it to an existing pod, may bypass scanning. Pods created by workload controllers
+// inherit the bypass authorized on their workload's pod template. It returns who bypassed
+// scanning if a bypass was honoured, or the response denying the request.
+func (v *PodValidator) authorizeBypass(ctx context.Context, req admission.Request, pod *corev1.Pod) (string, *admission.Response) {
+	if v.Bypass == nil {
+		return "", nil
+	}
+
+	user := req.UserInfo
+	requested := pod.Annotations[AnnotationBypassScan] == "true"
+	if req.Operation == admissionv1.Update {
+		oldPod := &corev1.Pod{}
+		if err := json.Unmarshal(req.OldObject.Raw, oldPod); err != nil {
+			resp := admission.Errored(http.StatusBadRequest, err)
+			return "", &resp
+		}
+		// Only the mutating webhook records who bypassed scanning, when the bypass is added
+		requested = bypassAdded(oldPod, pod)
+		if requested && pod.Annotations[AnnotationBypassedBy] != user.Username ||
+			!requested && oldPod.Annotations[AnnotationBypassedBy] != pod.Annotations[AnnotationBypassedBy] {
+			resp := admission.Denied(AnnotationBypassedBy + " is set at admission and can't be changed")
+			return "", &resp
+		}
+	}
+	if !requested {
+		return "", nil
+	}
+
+	logger := log.FromContext(ctx)
+	inherited, err := v.Bypass.OwnerBypassedBy(ctx, req.Namespace, pod.OwnerReferences, user)
+	if err != nil {
+		logger.Error(err, "Failed to read the pod's workload", "pod", pod.Name, "namespace", req.Namespace)
+		resp := admission.Errored(http.StatusInternalServerError, err)
+		return "", &resp
+	}
+	if inherited != "" && inherited == pod.Annotations[AnnotationBypassedBy] {
+		bypasses.WithLabelValues(req.Namespace, "allowed").Inc()
+		logger.Info("Honouring scan bypass authorized on the pod's workload", "pod", pod.Name,
+			"namespace", req.Namespace, "user", user.Username, "bypassedBy", inherited)
+		return inherited, nil
+	}
+
+	allowed, reason, err := v.Bypass.Authorize(ctx, req.Namespace, user)
+	if err != nil {
+		logger.Error(err, "Failed to authorize scan bypass", "user", user.Username, "namespace", req.Namespace)
+		resp := admission.Errored(http.StatusInternalServerError, err)
+		return "", &resp
+	}
+	if !allowed {
+		bypasses.WithLabelValues(req.Namespace, "denied").Inc()
+		logger.Info("Rejecting unauthorized scan bypass",
+			"pod", pod.Name, "namespace", req.Namespace, "user", user.Username, "groups", user.Groups)
+		resp := bypassDenied(req.Namespace, user, reason)
+		return "", &resp
+	}
+
+	bypasses.WithLabelValues(req.Namespace, "allowed").Inc()
+	logger.Info("Honouring scan bypass", "pod", pod.Name, "namespace", req.Namespace,
+		"operation", req.Operation, "user", user.Username, "groups", user.Groups, "reason", reason)
+	return user.Username, nil
+}
+
+// bypassAdded returns true if an update adds the bypass annotation to an existing pod.
+func bypassAdded(oldPod, pod *corev1.Pod) bool {
+	return pod.Annotations[AnnotationBypassScan] == "true" && oldPod.Annotations[AnnotationBypassScan] != "true"
+}
+
+// scannedDigestsChanged denies updates changing the digests recorded for images pinned at
+// admission, which decide what is scanned in their place.
+func scannedDigestsChanged(req admission.Request, pod *corev1.Pod) *admission.Response {
+	if req.Operation != admissionv1.Update || len(req.OldObject.Raw) == 0 {
+		return nil
+	}
+	oldPod := &corev1.Pod{}
+	if err := json.Unmarshal(req.OldObject.Raw, oldPod); err != nil {
+		resp := admission.Errored(http.StatusBadRequest, err)
+		return &resp
+	}
+	if oldPod.Annotations[imageref.AnnotationScannedDigests] != pod.Annotations[imageref.AnnotationScannedDigests] {
+		resp := admission.Denied(imageref.AnnotationScannedDigests + " is set at admission and can't be changed")
+		return &resp
+	}
+	return nil
+}
+
+// imagePolicyViolations checks the pod's images against the image reference policy. On
+// update only new images are checked, so pods admitted before a policy change can still
+// be updated (e.g., to remove the scheduling gate). Ephemeral containers, e.g., added by
+// kubectl debug, are checked when the ephemeralcontainers subresource is updated.
+func (v *PodValidator) imagePolicyViolations(req admission.Request, pod *corev1.Pod) []string {
+	config := v.ImagePolicy.Config()
+	if config == nil {
+		return nil
+	}
+
+	existing := map[string]bool{}
+	if req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+		oldPod := &corev1.Pod{}
+		if err := json.Unmarshal(req.OldObject.Raw, oldPod); err == nil {
+			for _, img := range imageref.ExtractFromPodSpec(&oldPod.Spec) {
+				existing[img.Image] = true
+			}
+		}
+	}
+
+	// References are checked as written, including the digests pinned at admission
+	images := imageref.ExtractFromPodSpec(&pod.Spec)
+	if req.SubResource == "ephemeralcontainers" {
+		images = imageref.ExtractFromPodSpec(&corev1.PodSpec{EphemeralContainers: pod.Spec.EphemeralContainers})
+	}
+	var denied []string
+	for _, img := range images {
+		if existing[img.Image] {
+			continue
+		}
+		if violations := config.Check(req.Namespace, img.Image, v.RegistryMirrors); len(violations) > 0 {
+			denied = append(denied, fmt.Sprintf("%s: %s", img.Image, strings.Join(violations, ", ")))
+		}
+	}
+	return denied
+}
+
+// failedImages describes the pod's images whose scan Failed a scan policy or Aqua's
+// assurance policies, skipping images exempt from scanning. Scans that failed for other
+// reasons, such as exhausted retries, are left to the scheduling gate. Lookup errors are
+// logged and the image is treated as not scanned yet.
+func (v *PodValidator) failedImages(ctx context.Context, namespace string, pod *corev1.Pod) []string {
+	if v.Scans == nil {
+		return nil
+	}
+
+	var failed []string
+	for _, img := range v.Exclusions.Filter(imageref.ExtractFromPod(pod)) {
+		img, ok := v.Scans.ImageRef(img)
+		if !ok || v.Exclusions.Excluded(img) {
+			continue
+		}
+		status, err := v.Scans.Status(ctx, namespace, img)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Failed to look up ImageScan verdict", "image", img.Image)
+			continue
+		}
+		if !failedPolicy(status) {
+			continue
+		}
+		failed = append(failed, describeFailure(img.Image, status))
+	}
+	return failed
+}
+
+// failedPolicy returns true if the scan is Failed with a scan policy or assurance policy verdict.
+func failedPolicy(status *securityv1alpha1.ImageScanStatus) bool {
+	if status == nil || status.Phase != securityv1alpha1.ScanPhaseFailed {
+		return false
+	}
+	ready := meta.FindStatusCondition(status.Conditions, securityv1alpha1.ConditionReady)
+	return ready != nil && (ready.Reason == securityv1alpha1.ReasonPolicyViolation ||
+		ready.Reason == securityv1alpha1.ReasonAssurancePolicyFailed)
+}
+
+// describeFailure explains a failed scan with its policy and vulnerability counts.
+func describeFailure(image string, status *securityv1alpha1.ImageScanStatus) string {
+	description := fmt.Sprintf("%s: %s", image, status.Message)
+	if status.Policy != "" {
+		description = fmt.Sprintf("%s violates %s: %s", image, status.Policy, status.Message)
+	}
+	if counts := status.Vulnerabilities; counts != nil {
+		description += fmt.Sprintf(" (critical: %d, high: %d, medium: %d, low: %d)",
+			counts.Critical, counts.High, counts.Medium, counts.Low)
+	}
+	return description
+}
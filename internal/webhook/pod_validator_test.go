@@ -0,0 +1,232 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/enforcement"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageexclude"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imagepolicy"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func podRequest(operation admissionv1.Operation, pod *corev1.Pod) admission.Request {
+	raw, err := json.Marshal(pod)
+	Expect(err).NotTo(HaveOccurred())
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func testPod(annotations map[string]string, images ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default", Annotations: annotations},
+	}
+	for i, image := range images {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: fmt.Sprintf("c%d", i), Image: image})
+	}
+	return pod
+}
+
+var _ = Describe("PodValidator", func() {
+	var (
+		scheme *runtime.Scheme
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		ctx = context.Background()
+	})
+
+	Describe("scan verdicts", func() {
+		failedImage := "registry.example.com/app@" + testDigest
+
+		newValidator := func(objs ...client.Object) *PodValidator {
+			return &PodValidator{Scans: &ScanLookup{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+			}}
+		}
+		failedScan := func(namespace string) *securityv1alpha1.ImageScan {
+			return &securityv1alpha1.ImageScan{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      imageref.ScanName(imageref.ImageRef{Image: failedImage, Digest: testDigest}),
+					Namespace: namespace,
+				},
+				Spec: securityv1alpha1.ImageScanSpec{Image: failedImage, Digest: testDigest},
+				Status: securityv1alpha1.ImageScanStatus{
+					Phase:           securityv1alpha1.ScanPhaseFailed,
+					Policy:          "ClusterScanPolicy/baseline",
+					Message:         "2 critical vulnerabilities exceed the maximum of 0",
+					Vulnerabilities: &securityv1alpha1.VulnerabilitySummary{Critical: 2, High: 1},
+					Conditions: []metav1.Condition{{
+						Type:   securityv1alpha1.ConditionReady,
+						Status: metav1.ConditionFalse,
+						Reason: securityv1alpha1.ReasonPolicyViolation,
+					}},
+				},
+			}
+		}
+
+		It("should deny new pods with a failed image and list the counts", func() {
+			v := newValidator(failedScan("default"))
+			resp := v.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, failedImage, "nginx:1.25")))
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(resp.Result.Message).To(ContainSubstring(failedImage + " violates ClusterScanPolicy/baseline"))
+			Expect(resp.Result.Message).To(ContainSubstring("critical: 2, high: 1"))
+			Expect(resp.Result.Message).NotTo(ContainSubstring("nginx"))
+		})
+
+		It("should leave scans that exhausted their retries to the gate", func() {
+			scan := failedScan("default")
+			scan.Status.Policy = ""
+			scan.Status.Message = "Aqua returned 503"
+			scan.Status.Conditions[0].Reason = securityv1alpha1.ReasonRetriesExhausted
+			v := newValidator(scan)
+			resp := v.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, failedImage)))
+			Expect(resp.Allowed).To(BeTrue())
+
+			scan.Status.Conditions[0].Reason = securityv1alpha1.ReasonAssurancePolicyFailed
+			v = newValidator(scan)
+			resp = v.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, failedImage)))
+			Expect(resp.Allowed).To(BeFalse())
+		})
+
+		It("should allow failed images exempt from scanning", func() {
+			list, err := imageexclude.ParseList(testDigest)
+			Expect(err).NotTo(HaveOccurred())
+			v := newValidator(failedScan("default"))
+			v.Exclusions, err = imageexclude.New(list, "", 0)
+			Expect(err).NotTo(HaveOccurred())
+			resp := v.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, failedImage)))
+			Expect(resp.Allowed).To(BeTrue())
+		})
+
+		It("should allow pods whose images have no verdict yet", func() {
+			v := newValidator(failedScan("other"))
+			resp := v.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, failedImage)))
+			Expect(resp.Allowed).To(BeTrue())
+		})
+
+		It("should allow updates and bypassed pods", func() {
+			v := newValidator(failedScan("default"))
+			resp := v.Handle(ctx, podRequest(admissionv1.Update, testPod(nil, failedImage)))
+			Expect(resp.Allowed).To(BeTrue())
+
+			resp = v.Handle(ctx, podRequest(admissionv1.Create,
+				testPod(map[string]string{AnnotationBypassScan: "true"}, failedImage)))
+			Expect(resp.Allowed).To(BeTrue())
+		})
+
+		It("should only warn in warn mode and stay silent in audit mode", func() {
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "default",
+				Labels: map[string]string{enforcement.LabelMode: "warn"},
+			}}
+			v := newValidator(failedScan("default"), namespace)
+			v.Enforcement = &enforcement.Modes{Reader: v.Scans.Client, Default: enforcement.Audit}
+
+			resp := v.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, failedImage)))
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.Warnings).To(ConsistOf(And(
+				ContainSubstring("would be blocked in enforce mode"),
+				ContainSubstring("ClusterScanPolicy/baseline"),
+			)))
+
+			v.Enforcement.Reader = fake.NewClientBuilder().WithScheme(scheme).Build()
+			resp = v.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, failedImage)))
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.Warnings).To(BeEmpty())
+		})
+
+		It("should look up ClusterImageScans with cluster scans", func() {
+			scan := failedScan("")
+			clusterScan := &securityv1alpha1.ClusterImageScan{
+				ObjectMeta: metav1.ObjectMeta{Name: scan.Name},
+				Spec:       scan.Spec,
+				Status:     scan.Status,
+			}
+			v := newValidator(clusterScan)
+			v.Scans.ClusterScans = true
+			resp := v.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, failedImage)))
+			Expect(resp.Allowed).To(BeFalse())
+		})
+	})
+
+	It("should deny changes to the digests scanned for pinned images", func() {
+		pinned := testPod(map[string]string{
+			imageref.AnnotationScannedDigests: `{"nginx:1.27@` + testDigest + `": "` + testDigest + `"}`,
+		}, "nginx:1.27@"+testDigest)
+		forged := pinned.DeepCopy()
+		forged.Annotations[imageref.AnnotationScannedDigests] = `{}`
+
+		req := podRequest(admissionv1.Update, forged)
+		raw, err := json.Marshal(pinned)
+		Expect(err).NotTo(HaveOccurred())
+		req.OldObject = runtime.RawExtension{Raw: raw}
+		resp := (&PodValidator{}).Handle(ctx, req)
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring(imageref.AnnotationScannedDigests))
+	})
+
+	Describe("image reference policy", func() {
+		newValidator := func() *PodValidator {
+			path := filepath.Join(GinkgoT().TempDir(), "policy.yaml")
+			Expect(os.WriteFile(path, []byte("allowedRegistries: [registry.example.com]"), 0o600)).To(Succeed())
+			source, err := imagepolicy.NewFileSource(path, time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			return &PodValidator{ImagePolicy: source}
+		}
+		debugRequest := func(oldPod, pod *corev1.Pod) admission.Request {
+			req := podRequest(admissionv1.Update, pod)
+			req.SubResource = "ephemeralcontainers"
+			raw, err := json.Marshal(oldPod)
+			Expect(err).NotTo(HaveOccurred())
+			req.OldObject = runtime.RawExtension{Raw: raw}
+			return req
+		}
+		withDebugContainer := func(pod *corev1.Pod, image string) *corev1.Pod {
+			pod = pod.DeepCopy()
+			pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: image},
+			})
+			return pod
+		}
+
+		It("should deny ephemeral containers from registries that aren't allowed", func() {
+			v := newValidator()
+			// Running pods admitted before the policy aren't rejected for their own images
+			running := testPod(nil, "docker.io/library/nginx:1.27")
+
+			resp := v.Handle(ctx, debugRequest(running, withDebugContainer(running, "docker.io/library/busybox:1.36")))
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(resp.Result.Message).To(ContainSubstring("docker.io/library/busybox:1.36"))
+			Expect(resp.Result.Message).NotTo(ContainSubstring("nginx"))
+
+			resp = v.Handle(ctx, debugRequest(running, withDebugContainer(running, "registry.example.com/debug:1.0")))
+			Expect(resp.Allowed).To(BeTrue())
+		})
+	})
+})
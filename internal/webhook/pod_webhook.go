@@ -4,21 +4,35 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"github.com/richardmsong/aqua-scan-gate/pkg/enforcement"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageexclude"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/scope"
 	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
 )
 
 const (
 	SchedulingGateName   = "scans.aquasec.community/aqua-scan"
 	AnnotationBypassScan = "scans.aquasec.community/bypass-scan"
+
+	// AnnotationAdmissionDecision records whether the pod was gated, admitted ungated
+	// because every image was already approved, or admitted ungated to be audited
+	AnnotationAdmissionDecision = "scans.aquasec.community/admission-decision"
+
+	DecisionGated    = "gated"
+	DecisionApproved = "approved"
+	DecisionAudited  = "audited"
 )
 
 // PodMutator adds scheduling gate to pods
@@ -26,14 +40,40 @@ type PodMutator struct {
 	Client  client.Client
 	decoder admission.Decoder
 
-	// ExcludedNamespaces won't have the gate added
-	ExcludedNamespaces map[string]bool
+	// Namespaces decides which namespaces are gated, shared with the Pod gate controller
+	// (nil = all)
+	Namespaces *scope.Namespaces
+
+	// Exclusions are the images exempt from scanning, shared with the Pod gate controller.
+	// Pods whose images are all exempt aren't gated.
+	Exclusions *imageexclude.Exclusions
+
+	// Scans looks up existing scan verdicts for the fast path (nil = always gate)
+	Scans *ScanLookup
+
+	// FastPathMaxAge is how recently every image's scan must have been checked for the pod
+	// to be admitted without the gate (0 = always gate)
+	FastPathMaxAge time.Duration
+
+	// PinDigests rewrites tag references to the digest they resolve to
+	PinDigests bool
+
+	// Resolver resolves tags to digests for PinDigests, sharing its cache with the Pod gate controller
+	Resolver *imageref.CachingResolver
 
-	// ExcludedImages won't trigger gating (e.g., known-safe images)
-	ExcludedImages []string
+	// APIReader reads pull secrets and service accounts without caching them
+	// cluster-wide (nil = use Client)
+	APIReader client.Reader
+
+	// Prefetcher starts the scans of gated and audited pods in the background (nil = left
+	// to the Pod gate controller)
+	Prefetcher *ScanPrefetcher
+
+	// Enforcement decides per namespace whether pods are gated or only audited (nil = gate)
+	Enforcement *enforcement.Modes
 }
 
-// +kubebuilder:webhook:path=/mutate-v1-pod,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod.scans.aquasec.community,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-v1-pod,mutating=true,failurePolicy=fail,sideEffects=NoneOnDryRun,groups="",resources=pods,verbs=create;update,versions=v1,name=mpod.scans.aquasec.community,admissionReviewVersions=v1
 
 func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
 	ctx, span := tracing.StartSpan(ctx, "PodMutator.Handle",
@@ -53,26 +93,45 @@ func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 		span.SetStatus(codes.Error, "Failed to decode pod")
 		return admission.Errored(http.StatusBadRequest, err)
 	}
+	// The namespace may be left to the API server; pull secrets are looked up in it
+	if pod.Namespace == "" {
+		pod.Namespace = req.Namespace
+	}
 
 	// Skip excluded namespaces
-	if m.ExcludedNamespaces[req.Namespace] {
+	if m.Namespaces.IsExcluded(ctx, req.Namespace) {
 		span.SetAttributes(attribute.Bool("excluded_namespace", true))
 		logger.V(1).Info("Skipping excluded namespace", "namespace", req.Namespace)
 		return admission.Allowed("excluded namespace")
 	}
 
-	// Skip if bypass annotation is set
+	// Existing pods are only mutated to record who added the bypass annotation
+	if req.Operation == admissionv1.Update {
+		return m.recordBypass(span, req, pod)
+	}
+
+	// Skip if bypass annotation is set; the validating webhook checks who may bypass
 	if pod.Annotations != nil && pod.Annotations[AnnotationBypassScan] == "true" {
 		span.SetAttributes(attribute.Bool("bypassed", true))
-		logger.Info("Bypass annotation found, skipping gate injection", "pod", pod.Name)
-		return admission.Allowed("bypass annotation")
+		logger.Info("Bypass annotation found, skipping gate injection", "pod", pod.Name, "user", req.UserInfo.Username)
+		// Pods created from a workload's pod template keep who bypassed scanning on the
+		// template, which the validating webhook checks against the workload
+		if !isWorkloadController(req.UserInfo) || pod.Annotations[AnnotationBypassedBy] == "" {
+			pod.Annotations[AnnotationBypassedBy] = req.UserInfo.Username
+		}
+		return patchResponse(span, req, pod)
 	}
 
+	// Only bypassed pods record who bypassed scanning, and only pinning records the
+	// digests scanned in place of the pod's images
+	delete(pod.Annotations, AnnotationBypassedBy)
+	delete(pod.Annotations, imageref.AnnotationScannedDigests)
+
 	// Skip pods that already have our gate
 	for _, gate := range pod.Spec.SchedulingGates {
 		if gate.Name == SchedulingGateName {
 			span.SetAttributes(attribute.Bool("gate_already_present", true))
-			return admission.Allowed("gate already present")
+			return patchResponse(span, req, pod)
 		}
 	}
 
@@ -80,7 +139,38 @@ func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 	if m.allImagesExcluded(pod) {
 		span.SetAttributes(attribute.Bool("all_images_excluded", true))
 		logger.V(1).Info("All images excluded, skipping gate injection", "pod", pod.Name)
-		return admission.Allowed("all images excluded")
+		return patchResponse(span, req, pod)
+	}
+
+	// Pin images before looking up their verdicts, so the fast path finds their scans
+	warnings := m.pinImages(ctx, pod, podContainerImages(&pod.Spec))
+
+	// Ensure annotations and labels exist for tracking
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+
+	// Skip the gate/ungate round trip when every image already has a fresh verdict
+	if m.allImagesApproved(ctx, req.Namespace, pod) {
+		span.SetAttributes(attribute.Bool("fast_path", true))
+		logger.Info("All images already approved, admitting without gate", "pod", pod.Name, "namespace", req.Namespace)
+		pod.Annotations[AnnotationAdmissionDecision] = DecisionApproved
+		return patchResponse(span, req, pod).WithWarnings(warnings...)
+	}
+
+	// In warn and audit mode the pod runs right away; the Pod gate controller records
+	// whether enforce mode would have blocked it once its scans complete
+	if mode := m.Enforcement.Mode(ctx, req.Namespace); mode != enforcement.Enforce {
+		span.SetAttributes(attribute.String("enforcement_mode", string(mode)))
+		logger.Info("Admitting pod ungated for audit", "pod", pod.Name, "namespace", req.Namespace, "mode", mode)
+		pod.Annotations[AnnotationAdmissionDecision] = DecisionAudited
+		pod.Labels[enforcement.LabelAudit] = enforcement.AuditPending
+		pod.Annotations[enforcement.AnnotationMode] = string(mode)
+		m.prefetch(ctx, span, req, pod)
+		return patchResponse(span, req, pod).WithWarnings(warnings...)
 	}
 
 	// Add our scheduling gate
@@ -90,12 +180,43 @@ func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 		Name: SchedulingGateName,
 	})
 
-	// Ensure labels exist for tracking
-	if pod.Labels == nil {
-		pod.Labels = make(map[string]string)
-	}
+	pod.Annotations[AnnotationAdmissionDecision] = DecisionGated
 	pod.Labels["scans.aquasec.community/gated"] = "true"
 
+	m.prefetch(ctx, span, req, pod)
+	return patchResponse(span, req, pod).WithWarnings(warnings...)
+}
+
+// prefetch starts scanning the pod's images now instead of after the pod is reconciled.
+func (m *PodMutator) prefetch(ctx context.Context, span trace.Span, req admission.Request, pod *corev1.Pod) {
+	if m.Prefetcher == nil || (req.DryRun != nil && *req.DryRun) {
+		return
+	}
+	queued := m.Prefetcher.Enqueue(pod.DeepCopy())
+	span.SetAttributes(attribute.Bool("scans_prefetched", queued))
+	if !queued {
+		log.FromContext(ctx).V(1).Info("Scan prefetch queue full, leaving scans to the Pod gate controller", "pod", pod.Name)
+	}
+}
+
+// recordBypass records who added the bypass annotation to an existing pod, which the
+// validating webhook authorizes
+func (m *PodMutator) recordBypass(span trace.Span, req admission.Request, pod *corev1.Pod) admission.Response {
+	oldPod := &corev1.Pod{}
+	if err := m.decoder.DecodeRaw(req.OldObject, oldPod); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode pod")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !bypassAdded(oldPod, pod) {
+		return admission.Allowed("")
+	}
+	span.SetAttributes(attribute.Bool("bypassed", true))
+	pod.Annotations[AnnotationBypassedBy] = req.UserInfo.Username
+	return patchResponse(span, req, pod)
+}
+
+func patchResponse(span trace.Span, req admission.Request, pod *corev1.Pod) admission.Response {
 	marshaledPod, err := json.Marshal(pod)
 	if err != nil {
 		span.RecordError(err)
@@ -106,28 +227,51 @@ func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod)
 }
 
-func (m *PodMutator) allImagesExcluded(pod *corev1.Pod) bool {
-	if len(m.ExcludedImages) == 0 {
+// allImagesApproved returns true if every image of the pod that isn't exempt from scanning
+// has a Registered or Passed scan checked within FastPathMaxAge. Pods in namespaces with
+// scan policy rules are always gated, since the rules are evaluated per pod. Lookup errors
+// gate the pod.
+func (m *PodMutator) allImagesApproved(ctx context.Context, namespace string, pod *corev1.Pod) bool {
+	if m.Scans == nil || m.FastPathMaxAge <= 0 {
 		return false
 	}
+	logger := log.FromContext(ctx)
 
-	excludedSet := make(map[string]bool)
-	for _, img := range m.ExcludedImages {
-		excludedSet[img] = true
+	images := m.Exclusions.Filter(imageref.ExtractFromPod(pod))
+	if len(images) == 0 {
+		return false
 	}
-
-	for _, c := range pod.Spec.InitContainers {
-		if !excludedSet[c.Image] {
+	for _, img := range images {
+		img, ok := m.Scans.ImageRef(img)
+		if !ok {
 			return false
 		}
-	}
-	for _, c := range pod.Spec.Containers {
-		if !excludedSet[c.Image] {
+		if m.Exclusions.Excluded(img) {
+			continue
+		}
+		status, err := m.Scans.Status(ctx, namespace, img)
+		if err != nil {
+			logger.Error(err, "Failed to look up ImageScan verdict", "image", img.Image)
+			return false
+		}
+		if !isApproved(status, m.FastPathMaxAge) {
 			return false
 		}
 	}
 
-	return true
+	hasRules, err := m.Scans.HasRules(ctx, namespace)
+	if err != nil {
+		logger.Error(err, "Failed to list scan policies")
+		return false
+	}
+	return !hasRules
+}
+
+// allImagesExcluded returns true if every image of the pod, including those of ephemeral
+// containers, is exempt from scanning.
+func (m *PodMutator) allImagesExcluded(pod *corev1.Pod) bool {
+	images := imageref.ExtractFromPod(pod)
+	return len(images) > 0 && len(m.Exclusions.Filter(images)) == 0
 }
 
 func (m *PodMutator) InjectDecoder(d admission.Decoder) error {
@@ -0,0 +1,301 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	imagemutate "github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/enforcement"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageexclude"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/scope"
+)
+
+// patchedPod applies the JSON patch of a mutating webhook response to pod.
+func patchedPod(pod *corev1.Pod, resp admission.Response) *corev1.Pod {
+	Expect(resp.Allowed).To(BeTrue())
+	original, err := json.Marshal(pod)
+	Expect(err).NotTo(HaveOccurred())
+	ops, err := json.Marshal(resp.Patches)
+	Expect(err).NotTo(HaveOccurred())
+	patch, err := jsonpatch.DecodePatch(ops)
+	Expect(err).NotTo(HaveOccurred())
+	patched, err := patch.Apply(original)
+	Expect(err).NotTo(HaveOccurred())
+
+	result := &corev1.Pod{}
+	Expect(json.Unmarshal(patched, result)).To(Succeed())
+	return result
+}
+
+var _ = Describe("PodMutator", func() {
+	const image = "registry.example.com/app@" + testDigest
+
+	var (
+		scheme *runtime.Scheme
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		ctx = context.Background()
+	})
+
+	scan := func(phase securityv1alpha1.ScanPhase, checked time.Time) *securityv1alpha1.ImageScan {
+		lastChecked := metav1.NewTime(checked)
+		return &securityv1alpha1.ImageScan{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      imageref.ScanName(imageref.ImageRef{Image: image, Digest: testDigest}),
+				Namespace: "default",
+			},
+			Spec:   securityv1alpha1.ImageScanSpec{Image: image, Digest: testDigest},
+			Status: securityv1alpha1.ImageScanStatus{Phase: phase, LastCheckedTime: &lastChecked},
+		}
+	}
+
+	mutate := func(objs ...client.Object) *corev1.Pod {
+		m := &PodMutator{
+			Scans: &ScanLookup{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+			},
+			FastPathMaxAge: time.Hour,
+		}
+		Expect(m.InjectDecoder(admission.NewDecoder(scheme))).To(Succeed())
+		pod := testPod(nil, image)
+		return patchedPod(pod, m.Handle(ctx, podRequest(admissionv1.Create, pod)))
+	}
+
+	It("should admit pods whose images are all approved without the gate", func() {
+		pod := mutate(scan(securityv1alpha1.ScanPhasePassed, time.Now()))
+		Expect(pod.Spec.SchedulingGates).To(BeEmpty())
+		Expect(pod.Annotations).To(HaveKeyWithValue(AnnotationAdmissionDecision, DecisionApproved))
+	})
+
+	It("should gate pods with stale, pending or unknown verdicts", func() {
+		for _, objs := range [][]client.Object{
+			{scan(securityv1alpha1.ScanPhasePassed, time.Now().Add(-2*time.Hour))},
+			{scan(securityv1alpha1.ScanPhasePending, time.Now())},
+			nil,
+		} {
+			pod := mutate(objs...)
+			Expect(pod.Spec.SchedulingGates).To(ContainElement(corev1.PodSchedulingGate{Name: SchedulingGateName}))
+			Expect(pod.Annotations).To(HaveKeyWithValue(AnnotationAdmissionDecision, DecisionGated))
+		}
+	})
+
+	It("should gate tag-only images whose digest the Pod gate controller resolves", func() {
+		tagged := imageref.ImageRef{Image: "registry.example.com/app:v1"}
+		approved := scan(securityv1alpha1.ScanPhasePassed, time.Now())
+		approved.Name = imageref.ScanName(tagged)
+		approved.Spec = securityv1alpha1.ImageScanSpec{Image: tagged.Image}
+		m := &PodMutator{
+			Scans: &ScanLookup{
+				Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(approved).Build(),
+				ResolvesTags: true,
+			},
+			FastPathMaxAge: time.Hour,
+		}
+		Expect(m.InjectDecoder(admission.NewDecoder(scheme))).To(Succeed())
+
+		pod := testPod(nil, tagged.Image)
+		pod = patchedPod(pod, m.Handle(ctx, podRequest(admissionv1.Create, pod)))
+		Expect(pod.Spec.SchedulingGates).To(ContainElement(corev1.PodSchedulingGate{Name: SchedulingGateName}))
+	})
+
+	It("should gate pods when scan policy rules apply", func() {
+		pod := mutate(
+			scan(securityv1alpha1.ScanPhaseRegistered, time.Now()),
+			&securityv1alpha1.ScanPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "rules", Namespace: "default"},
+				Spec: securityv1alpha1.ScanPolicySpec{Rules: []securityv1alpha1.Rule{
+					{Name: "jobs-only", Expression: "pod.ownerKind == 'Job'"},
+				}},
+			},
+		)
+		Expect(pod.Spec.SchedulingGates).To(ContainElement(corev1.PodSchedulingGate{Name: SchedulingGateName}))
+	})
+
+	It("should only wait on images that aren't exempt from scanning", func() {
+		list, err := imageexclude.ParseList("prefix:registry.k8s.io")
+		Expect(err).NotTo(HaveOccurred())
+		exclusions, err := imageexclude.New(list, "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		m := &PodMutator{
+			Scans: &ScanLookup{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(scan(securityv1alpha1.ScanPhasePassed, time.Now())).Build(),
+			},
+			FastPathMaxAge: time.Hour,
+			Exclusions:     exclusions,
+		}
+		Expect(m.InjectDecoder(admission.NewDecoder(scheme))).To(Succeed())
+
+		// The sidecar has no scan, but only the approved image counts
+		original := testPod(nil, image, "registry.k8s.io/pause:3.9")
+		pod := patchedPod(original, m.Handle(ctx, podRequest(admissionv1.Create, original)))
+		Expect(pod.Spec.SchedulingGates).To(BeEmpty())
+		Expect(pod.Annotations).To(HaveKeyWithValue(AnnotationAdmissionDecision, DecisionApproved))
+
+		original = testPod(nil, "registry.example.com/other:v1", "registry.k8s.io/pause:3.9")
+		pod = patchedPod(original, m.Handle(ctx, podRequest(admissionv1.Create, original)))
+		Expect(pod.Spec.SchedulingGates).To(ContainElement(corev1.PodSchedulingGate{Name: SchedulingGateName}))
+
+		resp := m.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, "registry.k8s.io/pause:3.9")))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+
+	It("should skip namespaces outside the namespace selector", func() {
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		).Build()
+		namespaces, err := scope.NewNamespaces(c, nil, "scans.aquasec.community/gate=enabled", "")
+		Expect(err).NotTo(HaveOccurred())
+		m := &PodMutator{Scans: &ScanLookup{Client: c}, Namespaces: namespaces}
+		Expect(m.InjectDecoder(admission.NewDecoder(scheme))).To(Succeed())
+
+		resp := m.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, image)))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+
+	It("should admit pods ungated for audit in warn and audit mode", func() {
+		m := &PodMutator{
+			Scans:       &ScanLookup{Client: fake.NewClientBuilder().WithScheme(scheme).Build()},
+			Enforcement: &enforcement.Modes{Default: enforcement.Warn},
+		}
+		Expect(m.InjectDecoder(admission.NewDecoder(scheme))).To(Succeed())
+		original := testPod(nil, image)
+		pod := patchedPod(original, m.Handle(ctx, podRequest(admissionv1.Create, original)))
+
+		Expect(pod.Spec.SchedulingGates).To(BeEmpty())
+		Expect(pod.Annotations).To(HaveKeyWithValue(AnnotationAdmissionDecision, DecisionAudited))
+		Expect(pod.Annotations).To(HaveKeyWithValue(enforcement.AnnotationMode, "warn"))
+		Expect(pod.Labels).To(HaveKeyWithValue(enforcement.LabelAudit, enforcement.AuditPending))
+	})
+
+	Describe("digest pinning", func() {
+		It("should pin tag references and record the originals", func() {
+			server := httptest.NewServer(registry.New())
+			defer server.Close()
+
+			tagged := strings.TrimPrefix(server.URL, "http://") + "/app:v1"
+			img, err := random.Image(64, 1)
+			Expect(err).NotTo(HaveOccurred())
+			ref, err := name.ParseReference(tagged)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Write(ref, img)).To(Succeed())
+			digest, err := img.Digest()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			m := &PodMutator{
+				Client:     fake.NewClientBuilder().WithScheme(scheme).Build(),
+				PinDigests: true,
+				Resolver:   imageref.NewCachingResolver(imageref.NewResolver(), time.Minute),
+			}
+			Expect(m.InjectDecoder(admission.NewDecoder(scheme))).To(Succeed())
+
+			pod := testPod(nil, tagged, image)
+			pod.Spec.InitContainers = []corev1.Container{{Name: "init", Image: tagged}}
+			resp := m.Handle(ctx, podRequest(admissionv1.Create, pod))
+			Expect(resp.Warnings).To(BeEmpty())
+			patched := patchedPod(pod, resp)
+
+			pinned := tagged + "@" + digest.String()
+			Expect(patched.Spec.InitContainers[0].Image).To(Equal(pinned))
+			Expect(patched.Spec.Containers[0].Image).To(Equal(pinned))
+			Expect(patched.Spec.Containers[1].Image).To(Equal(image))
+			Expect(patched.Annotations[AnnotationOriginalImages]).To(MatchJSON(
+				`{"init": "` + tagged + `", "c0": "` + tagged + `"}`))
+		})
+
+		It("should pin multi-arch images to their index digest and scan the platform digest", func() {
+			server := httptest.NewServer(registry.New())
+			defer server.Close()
+
+			tagged := strings.TrimPrefix(server.URL, "http://") + "/multi:v1"
+			idx := imagemutate.IndexMediaType(empty.Index, types.OCIImageIndex)
+			var amd64 v1.Hash
+			for _, arch := range []string{"amd64", "arm64"} {
+				img, err := random.Image(64, 1)
+				Expect(err).NotTo(HaveOccurred())
+				if arch == "amd64" {
+					amd64, err = img.Digest()
+					Expect(err).NotTo(HaveOccurred())
+				}
+				idx = imagemutate.AppendManifests(idx, imagemutate.IndexAddendum{
+					Add:        img,
+					Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: arch}},
+				})
+			}
+			ref, err := name.ParseReference(tagged)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.WriteIndex(ref, idx)).To(Succeed())
+			digest, err := idx.Digest()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			m := &PodMutator{
+				Client:     fake.NewClientBuilder().WithScheme(scheme).Build(),
+				PinDigests: true,
+				Resolver:   imageref.NewCachingResolver(imageref.NewResolver(), time.Minute),
+			}
+			Expect(m.InjectDecoder(admission.NewDecoder(scheme))).To(Succeed())
+
+			pod := testPod(nil, tagged)
+			pod.Annotations = map[string]string{imageref.AnnotationScannedDigests: `{"forged": "sha256:0"}`}
+			resp := m.Handle(ctx, podRequest(admissionv1.Create, pod))
+			Expect(resp.Warnings).To(BeEmpty())
+			patched := patchedPod(pod, resp)
+			pinned := tagged + "@" + digest.String()
+			Expect(patched.Spec.Containers[0].Image).To(Equal(pinned))
+			Expect(patched.Annotations[imageref.AnnotationScannedDigests]).To(MatchJSON(
+				`{"` + pinned + `": "` + amd64.String() + `"}`))
+
+			// Pinned pods are scanned like unpinned pods of the same tag
+			Expect(imageref.ExtractFromPod(patched)).To(ConsistOf(
+				imageref.ImageRef{Image: tagged, Digest: amd64.String()}))
+		})
+
+		It("should admit the pod unpinned with a warning when resolution fails", func() {
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			m := &PodMutator{
+				Client:     fake.NewClientBuilder().WithScheme(scheme).Build(),
+				PinDigests: true,
+				Resolver:   imageref.NewCachingResolver(imageref.NewResolver(), time.Minute),
+			}
+			Expect(m.InjectDecoder(admission.NewDecoder(scheme))).To(Succeed())
+
+			pod := testPod(nil, "127.0.0.1:1/app:v1")
+			resp := m.Handle(ctx, podRequest(admissionv1.Create, pod))
+			Expect(resp.Warnings).To(ContainElement(ContainSubstring("image digests not pinned")))
+			patched := patchedPod(pod, resp)
+			Expect(patched.Spec.Containers[0].Image).To(Equal("127.0.0.1:1/app:v1"))
+			Expect(patched.Spec.SchedulingGates).To(ContainElement(corev1.PodSchedulingGate{Name: SchedulingGateName}))
+		})
+	})
+})
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/policy"
+)
+
+// ScanLookup finds the scan verdicts the Pod gate controller would gate a pod's images on,
+// without creating scans or contacting registries
+type ScanLookup struct {
+	// Client reads scans and policies, usually from the informer cache
+	Client client.Reader
+
+	// ResolvesTags is set when the Pod gate controller resolves tag-only images to the
+	// digests their scans are named by. Only the replica running it caches those digests,
+	// so the verdicts of tag-only images aren't looked up (false = scans are named by the
+	// image reference)
+	ResolvesTags bool
+
+	// Namespace where ImageScan CRs are created (empty = same as pod)
+	ScanNamespace string
+
+	// ClusterScans looks up ClusterImageScans instead of ImageScans, unless
+	// ClusterScanReferences gates pods on the namespaced references
+	ClusterScans          bool
+	ClusterScanReferences bool
+}
+
+// ImageRef returns the image as the Pod gate controller names its scan, or false for
+// tag-only images the controller resolves, whose scans every replica can't find alike.
+// Pinned images have their digest.
+func (l *ScanLookup) ImageRef(img imageref.ImageRef) (imageref.ImageRef, bool) {
+	return img, img.Digest != "" || !l.ResolvesTags
+}
+
+// Status returns the status of the scan that gates img in namespace, or nil if it
+// doesn't exist yet.
+func (l *ScanLookup) Status(ctx context.Context, namespace string, img imageref.ImageRef) (*securityv1alpha1.ImageScanStatus, error) {
+	scanName := imageref.ScanName(img)
+	if l.ClusterScans && !l.ClusterScanReferences {
+		var clusterScan securityv1alpha1.ClusterImageScan
+		if err := l.Client.Get(ctx, types.NamespacedName{Name: scanName}, &clusterScan); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		return &clusterScan.Status, nil
+	}
+
+	if l.ScanNamespace != "" {
+		namespace = l.ScanNamespace
+	}
+	var imageScan securityv1alpha1.ImageScan
+	if err := l.Client.Get(ctx, types.NamespacedName{Name: scanName, Namespace: namespace}, &imageScan); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return &imageScan.Status, nil
+}
+
+// HasRules returns true if a ClusterScanPolicy or a ScanPolicy in namespace has CEL
+// rules, which the Pod gate controller evaluates per pod.
+func (l *ScanLookup) HasRules(ctx context.Context, namespace string) (bool, error) {
+	var clusterPolicies securityv1alpha1.ClusterScanPolicyList
+	if err := l.Client.List(ctx, &clusterPolicies); err != nil {
+		return false, err
+	}
+	var policies securityv1alpha1.ScanPolicyList
+	if err := l.Client.List(ctx, &policies, client.InNamespace(namespace)); err != nil {
+		return false, err
+	}
+	return len(policy.WithRules(policy.FromScanPolicies(clusterPolicies.Items, policies.Items))) > 0, nil
+}
+
+// isApproved returns true if the scan admits the image and was checked within maxAge.
+func isApproved(status *securityv1alpha1.ImageScanStatus, maxAge time.Duration) bool {
+	if status == nil || status.LastCheckedTime == nil {
+		return false
+	}
+	if status.Phase != securityv1alpha1.ScanPhaseRegistered && status.Phase != securityv1alpha1.ScanPhasePassed {
+		return false
+	}
+	return time.Since(status.LastCheckedTime.Time) <= maxAge
+}
@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultScanPrefetchTimeout bounds how long starting the scans of one pod may take
+const DefaultScanPrefetchTimeout = 30 * time.Second
+
+// ScanStarter creates the scans that will gate a pod's images
+type ScanStarter interface {
+	StartScans(ctx context.Context, pod *corev1.Pod) error
+}
+
+// ScanPrefetcher starts the scans of admitted pods in the background, so Aqua is asked
+// about their images a reconcile round trip earlier. Enqueueing never blocks admission:
+// when the queue is full the pod is left to the Pod gate controller.
+type ScanPrefetcher struct {
+	starter ScanStarter
+	workers int
+	queue   chan *corev1.Pod
+}
+
+// NewScanPrefetcher creates a prefetcher with workers goroutines and room for queueSize pods.
+func NewScanPrefetcher(starter ScanStarter, workers, queueSize int) *ScanPrefetcher {
+	return &ScanPrefetcher{
+		starter: starter,
+		workers: max(workers, 1),
+		queue:   make(chan *corev1.Pod, queueSize),
+	}
+}
+
+// Enqueue queues the pod's scans to be started, returning false if the queue is full.
+func (p *ScanPrefetcher) Enqueue(pod *corev1.Pod) bool {
+	if p == nil {
+		return false
+	}
+	select {
+	case p.queue <- pod:
+		return true
+	default:
+		return false
+	}
+}
+
+// Start runs the workers until the context is cancelled.
+func (p *ScanPrefetcher) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("scan-prefetch")
+	for range p.workers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case pod := <-p.queue:
+					p.startScans(log.IntoContext(ctx, logger), pod)
+				}
+			}
+		}()
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (p *ScanPrefetcher) startScans(ctx context.Context, pod *corev1.Pod) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultScanPrefetchTimeout)
+	defer cancel()
+	if err := p.starter.StartScans(ctx, pod); err != nil {
+		// The Pod gate controller retries once it sees the gated pod
+		log.FromContext(ctx).Error(err, "Failed to start scans at admission",
+			"pod", pod.Name, "namespace", pod.Namespace)
+	}
+}
+
+// NeedLeaderElection returns false, since every replica serves admission requests.
+func (p *ScanPrefetcher) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+// fakeScanStarter records the pods whose scans were started
+type fakeScanStarter struct {
+	mu   sync.Mutex
+	pods []*corev1.Pod
+	err  error
+}
+
+func (f *fakeScanStarter) StartScans(_ context.Context, pod *corev1.Pod) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pods = append(f.pods, pod)
+	return f.err
+}
+
+func (f *fakeScanStarter) started() []*corev1.Pod {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*corev1.Pod(nil), f.pods...)
+}
+
+var _ = Describe("ScanPrefetcher", func() {
+	const image = "registry.example.com/app:v1"
+
+	It("should start the scans of gated pods without failing admission", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		starter := &fakeScanStarter{err: errors.New("aqua unavailable")}
+		prefetcher := NewScanPrefetcher(starter, 2, 10)
+		go func() {
+			defer GinkgoRecover()
+			Expect(prefetcher.Start(ctx)).To(Succeed())
+		}()
+
+		scheme := runtime.NewScheme()
+		Expect(securityv1alpha1.AddToScheme(scheme)).To(Succeed())
+		m := &PodMutator{
+			Scans:      &ScanLookup{Client: fake.NewClientBuilder().WithScheme(scheme).Build()},
+			Prefetcher: prefetcher,
+		}
+		Expect(m.InjectDecoder(admission.NewDecoder(scheme))).To(Succeed())
+
+		pod := patchedPod(testPod(nil, image), m.Handle(ctx, podRequest(admissionv1.Create, testPod(nil, image))))
+		Expect(pod.Spec.SchedulingGates).To(ContainElement(corev1.PodSchedulingGate{Name: SchedulingGateName}))
+
+		Eventually(starter.started).WithTimeout(5 * time.Second).Should(HaveLen(1))
+		started := starter.started()[0]
+		Expect(started.Namespace).To(Equal("default"))
+		Expect(started.Spec.Containers[0].Image).To(Equal(image))
+	})
+
+	It("should not enqueue bypassed pods", func() {
+		prefetcher := NewScanPrefetcher(&fakeScanStarter{}, 1, 1)
+		m := &PodMutator{Prefetcher: prefetcher}
+		Expect(m.InjectDecoder(admission.NewDecoder(runtime.NewScheme()))).To(Succeed())
+
+		pod := testPod(map[string]string{AnnotationBypassScan: "true"}, image)
+		Expect(m.Handle(context.Background(), podRequest(admissionv1.Create, pod)).Allowed).To(BeTrue())
+		Expect(prefetcher.queue).To(BeEmpty())
+	})
+
+	It("should drop pods when the queue is full", func() {
+		prefetcher := NewScanPrefetcher(&fakeScanStarter{}, 1, 1)
+		Expect(prefetcher.Enqueue(testPod(nil, image))).To(BeTrue())
+		Expect(prefetcher.Enqueue(testPod(nil, image))).To(BeFalse())
+
+		var disabled *ScanPrefetcher
+		Expect(disabled.Enqueue(testPod(nil, image))).To(BeFalse())
+	})
+})
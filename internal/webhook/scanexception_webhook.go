@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+// ScanExceptionMutator records in spec.approvedBy the user who created a ScanException or
+// last changed what it waives, replacing whatever the request set
+type ScanExceptionMutator struct{}
+
+// +kubebuilder:webhook:path=/mutate-scans-aquasec-community-v1alpha1-scanexception,mutating=true,failurePolicy=fail,sideEffects=None,groups=scans.aquasec.community,resources=scanexceptions,verbs=create;update,versions=v1alpha1,name=mscanexception.scans.aquasec.community,admissionReviewVersions=v1
+
+func (m *ScanExceptionMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	_, span := tracing.StartSpan(ctx, "ScanExceptionMutator.Handle",
+		trace.WithAttributes(
+			attribute.String("name", req.Name),
+			attribute.String("operation", string(req.Operation)),
+		),
+	)
+	defer span.End()
+
+	exception := &securityv1alpha1.ScanException{}
+	if err := json.Unmarshal(req.Object.Raw, exception); err != nil {
+		span.RecordError(err)
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	approvedBy := req.UserInfo.Username
+	if req.Operation == admissionv1.Update {
+		oldException := &securityv1alpha1.ScanException{}
+		if err := json.Unmarshal(req.OldObject.Raw, oldException); err != nil {
+			span.RecordError(err)
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		// Only changes to what is waived, where or until when need a new approval
+		oldSpec := oldException.Spec
+		oldSpec.ApprovedBy = exception.Spec.ApprovedBy
+		if equality.Semantic.DeepEqual(oldSpec, exception.Spec) {
+			approvedBy = oldException.Spec.ApprovedBy
+		}
+	}
+	if exception.Spec.ApprovedBy == approvedBy {
+		return admission.Allowed("")
+	}
+
+	span.SetAttributes(attribute.String("approved_by", approvedBy))
+	log.FromContext(ctx).V(1).Info("Recording who approved the scan exception",
+		"name", req.Name, "approvedBy", approvedBy, "requested", exception.Spec.ApprovedBy)
+	exception.Spec.ApprovedBy = approvedBy
+	marshaled, err := json.Marshal(exception)
+	if err != nil {
+		span.RecordError(err)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
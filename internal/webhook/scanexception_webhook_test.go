@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+var _ = Describe("ScanExceptionMutator", func() {
+	newException := func(approvedBy string, cves ...string) *securityv1alpha1.ScanException {
+		return &securityv1alpha1.ScanException{
+			ObjectMeta: metav1.ObjectMeta{Name: "openssl"},
+			Spec: securityv1alpha1.ScanExceptionSpec{
+				CVEs:       cves,
+				Expires:    metav1.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)),
+				ApprovedBy: approvedBy,
+				Reason:     "No fix available upstream",
+			},
+		}
+	}
+	// mutate admits exception as username, updating oldException if it isn't nil, and
+	// returns the exception as stored
+	mutate := func(exception, oldException *securityv1alpha1.ScanException, username string) *securityv1alpha1.ScanException {
+		raw, err := json.Marshal(exception)
+		Expect(err).NotTo(HaveOccurred())
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Name:      exception.Name,
+			Object:    runtime.RawExtension{Raw: raw},
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+		}}
+		if oldException != nil {
+			oldRaw, err := json.Marshal(oldException)
+			Expect(err).NotTo(HaveOccurred())
+			req.Operation = admissionv1.Update
+			req.OldObject = runtime.RawExtension{Raw: oldRaw}
+		}
+
+		resp := (&ScanExceptionMutator{}).Handle(context.Background(), req)
+		Expect(resp.Allowed).To(BeTrue())
+		if len(resp.Patches) > 0 {
+			ops, err := json.Marshal(resp.Patches)
+			Expect(err).NotTo(HaveOccurred())
+			patch, err := jsonpatch.DecodePatch(ops)
+			Expect(err).NotTo(HaveOccurred())
+			raw, err = patch.Apply(raw)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		stored := &securityv1alpha1.ScanException{}
+		Expect(json.Unmarshal(raw, stored)).To(Succeed())
+		return stored
+	}
+
+	It("should record the requesting user instead of the approver the request claims", func() {
+		created := mutate(newException("security-team", "CVE-2024-0001"), nil, "dev")
+		Expect(created.Spec.ApprovedBy).To(Equal("dev"))
+	})
+
+	It("should keep the approver until what is waived changes", func() {
+		created := mutate(newException("", "CVE-2024-0001"), nil, "security-lead")
+
+		relabelled := created.DeepCopy()
+		relabelled.Labels = map[string]string{"team": "platform"}
+		relabelled.Spec.ApprovedBy = "dev"
+		Expect(mutate(relabelled, created, "dev").Spec.ApprovedBy).To(Equal("security-lead"))
+
+		widened := created.DeepCopy()
+		widened.Spec.CVEs = append(widened.Spec.CVEs, "CVE-2024-0002")
+		Expect(mutate(widened, created, "dev").Spec.ApprovedBy).To(Equal("dev"))
+	})
+})
@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/policy"
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+// ScanPolicyValidator rejects ScanPolicies and ClusterScanPolicies whose CEL rules don't compile
+type ScanPolicyValidator struct{}
+
+// +kubebuilder:webhook:path=/validate-scans-aquasec-community-v1alpha1-scanpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=scans.aquasec.community,resources=scanpolicies;clusterscanpolicies,verbs=create;update,versions=v1alpha1,name=vscanpolicy.scans.aquasec.community,admissionReviewVersions=v1
+
+func (v *ScanPolicyValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	_, span := tracing.StartSpan(ctx, "ScanPolicyValidator.Handle",
+		trace.WithAttributes(
+			attribute.String("kind", req.Kind.Kind),
+			attribute.String("name", req.Name),
+			attribute.String("operation", string(req.Operation)),
+		),
+	)
+	defer span.End()
+
+	// ScanPolicy and ClusterScanPolicy share the spec that holds the rules
+	var obj struct {
+		Spec securityv1alpha1.ScanPolicySpec `json:"spec"`
+	}
+	if err := json.Unmarshal(req.Object.Raw, &obj); err != nil {
+		span.RecordError(err)
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	span.SetAttributes(attribute.Int("rule_count", len(obj.Spec.Rules)))
+	if errs := policy.ValidateRules(obj.Spec); len(errs) > 0 {
+		span.SetAttributes(attribute.Bool("rejected", true))
+		log.FromContext(ctx).V(1).Info("Rejecting scan policy with invalid rules",
+			"kind", req.Kind.Kind, "name", req.Name, "errors", errs.ToAggregate().Error())
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
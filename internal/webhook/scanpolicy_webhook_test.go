@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+var _ = Describe("ScanPolicyValidator", func() {
+	policyRequest := func(expression string) admission.Request {
+		policy := &securityv1alpha1.ClusterScanPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "production"},
+			Spec: securityv1alpha1.ScanPolicySpec{Rules: []securityv1alpha1.Rule{
+				{Name: "no-fixable-highs", Expression: expression},
+			}},
+		}
+		raw, err := json.Marshal(policy)
+		Expect(err).NotTo(HaveOccurred())
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Name:      policy.Name,
+			Kind:      metav1.GroupVersionKind{Group: "scans.aquasec.community", Version: "v1alpha1", Kind: "ClusterScanPolicy"},
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	It("should admit policies whose rules compile", func() {
+		resp := (&ScanPolicyValidator{}).Handle(context.Background(),
+			policyRequest("namespaceObject.labels.?env.orValue('') != 'prod' || scan.fixable.high == 0"))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+
+	It("should reject rules with misspelled fields", func() {
+		resp := (&ScanPolicyValidator{}).Handle(context.Background(), policyRequest("scan.fixabel.high == 0"))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring("spec.rules[0].expression"))
+		Expect(resp.Result.Message).To(ContainSubstring("fixabel"))
+	})
+})
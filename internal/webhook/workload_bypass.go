@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/scope"
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+// workloadRequest is a workload admission request with a pod template
+type workloadRequest struct {
+	workload    *unstructured.Unstructured
+	path        []string
+	annotations map[string]string
+	// oldAnnotations are the pod template annotations before an update
+	oldAnnotations map[string]string
+	// specChanged is set when an update changes the pod template's spec
+	specChanged bool
+}
+
+// decodeWorkload decodes the workload of an admission request, returning nil for kinds
+// without a pod template.
+func decodeWorkload(req admission.Request) (*workloadRequest, error) {
+	path, ok := workloadTemplatePaths[schema.GroupKind{Group: req.Kind.Group, Kind: req.Kind.Kind}]
+	if !ok {
+		return nil, nil
+	}
+	workload := &unstructured.Unstructured{}
+	if err := json.Unmarshal(req.Object.Raw, &workload.Object); err != nil {
+		return nil, err
+	}
+	w := &workloadRequest{workload: workload, path: path, annotations: templateAnnotations(workload, path)}
+	if req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+		oldWorkload := &unstructured.Unstructured{}
+		if err := json.Unmarshal(req.OldObject.Raw, &oldWorkload.Object); err != nil {
+			return nil, err
+		}
+		w.oldAnnotations = templateAnnotations(oldWorkload, path)
+		specPath := slices.Concat(path, []string{"spec"})
+		oldSpec, _, _ := unstructured.NestedFieldNoCopy(oldWorkload.Object, specPath...)
+		spec, _, _ := unstructured.NestedFieldNoCopy(workload.Object, specPath...)
+		w.specChanged = !reflect.DeepEqual(oldSpec, spec)
+	}
+	return w, nil
+}
+
+// bypassRequested returns true if the pod template has the bypass annotation and it
+// wasn't already authorized for the same pod template spec before an update, so changing
+// the images of a bypassed workload is authorized for the user changing them.
+func (w *workloadRequest) bypassRequested() bool {
+	return w.annotations[AnnotationBypassScan] == "true" &&
+		(w.oldAnnotations[AnnotationBypassScan] != "true" || w.oldAnnotations[AnnotationBypassedBy] == "" ||
+			w.specChanged)
+}
+
+// WorkloadMutator records on workload pod templates with the bypass annotation who set
+// it, so the pods created from the template are attributed to that user rather than to
+// the workload controller that creates them
+type WorkloadMutator struct {
+	// Namespaces decides which namespaces are gated, shared with the pod webhooks (nil = all)
+	Namespaces *scope.Namespaces
+}
+
+// +kubebuilder:webhook:path=/mutate-workload,mutating=true,failurePolicy=fail,sideEffects=None,groups=apps;batch,resources=deployments;replicasets;statefulsets;daemonsets;jobs;cronjobs,verbs=create;update,versions=v1,name=mworkload.scans.aquasec.community,admissionReviewVersions=v1
+
+func (m *WorkloadMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ctx, span := tracing.StartSpan(ctx, "WorkloadMutator.Handle",
+		trace.WithAttributes(
+			attribute.String("workload.kind", req.Kind.Kind),
+			attribute.String("workload.name", req.Name),
+			attribute.String("workload.namespace", req.Namespace),
+		),
+	)
+	defer span.End()
+
+	if m.Namespaces.IsExcluded(ctx, req.Namespace) {
+		return admission.Allowed("excluded namespace")
+	}
+	w, err := decodeWorkload(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode workload")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if w == nil {
+		return admission.Allowed("")
+	}
+
+	bypassedBy := ""
+	switch {
+	case w.annotations[AnnotationBypassScan] != "true":
+		// Only bypassed templates record who bypassed scanning
+	case isWorkloadController(req.UserInfo) && w.annotations[AnnotationBypassedBy] != "":
+		// Copied from the owner's template, which the validating webhook checks
+		bypassedBy = w.annotations[AnnotationBypassedBy]
+	case !w.bypassRequested():
+		bypassedBy = w.oldAnnotations[AnnotationBypassedBy]
+	default:
+		bypassedBy = req.UserInfo.Username
+	}
+	if w.annotations[AnnotationBypassedBy] == bypassedBy {
+		return admission.Allowed("")
+	}
+
+	span.SetAttributes(attribute.Bool("bypassed", bypassedBy != ""))
+	annotations := w.annotations
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if bypassedBy == "" {
+		delete(annotations, AnnotationBypassedBy)
+	} else {
+		annotations[AnnotationBypassedBy] = bypassedBy
+	}
+	path := slices.Concat(w.path, []string{"metadata", "annotations"})
+	if err := unstructured.SetNestedStringMap(w.workload.Object, annotations, path...); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	marshaled, err := json.Marshal(w.workload.Object)
+	if err != nil {
+		span.RecordError(err)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// WorkloadValidator checks that users adding the bypass annotation to workload pod
+// templates may bypass scanning, as PodValidator does for pods
+type WorkloadValidator struct {
+	// Namespaces decides which namespaces are validated, shared with the pod webhooks
+	// (nil = all)
+	Namespaces *scope.Namespaces
+
+	// Bypass authorizes the users setting the bypass annotation (nil = anyone may bypass)
+	Bypass *BypassAuthorizer
+}
+
+// +kubebuilder:webhook:path=/validate-workload,mutating=false,failurePolicy=fail,sideEffects=None,groups=apps;batch,resources=deployments;replicasets;statefulsets;daemonsets;jobs;cronjobs,verbs=create;update,versions=v1,name=vworkload.scans.aquasec.community,admissionReviewVersions=v1
+
+func (v *WorkloadValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ctx, span := tracing.StartSpan(ctx, "WorkloadValidator.Handle",
+		trace.WithAttributes(
+			attribute.String("workload.kind", req.Kind.Kind),
+			attribute.String("workload.name", req.Name),
+			attribute.String("workload.namespace", req.Namespace),
+		),
+	)
+	defer span.End()
+
+	if v.Bypass == nil || v.Namespaces.IsExcluded(ctx, req.Namespace) {
+		return admission.Allowed("")
+	}
+	w, err := decodeWorkload(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode workload")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if w == nil || !w.bypassRequested() {
+		return admission.Allowed("")
+	}
+
+	logger := log.FromContext(ctx)
+	user := req.UserInfo
+	inherited, err := v.Bypass.OwnerBypassedBy(ctx, req.Namespace, w.workload.GetOwnerReferences(), user)
+	if err != nil {
+		logger.Error(err, "Failed to read the workload's owner", "kind", req.Kind.Kind, "name", req.Name, "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	bypassedBy := w.annotations[AnnotationBypassedBy]
+	if inherited != "" && inherited == bypassedBy {
+		return admission.Allowed("")
+	}
+
+	allowed, reason, err := v.Bypass.Authorize(ctx, req.Namespace, user)
+	if err != nil {
+		logger.Error(err, "Failed to authorize scan bypass", "user", user.Username, "namespace", req.Namespace)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if !allowed {
+		bypasses.WithLabelValues(req.Namespace, "denied").Inc()
+		logger.Info("Rejecting unauthorized scan bypass on a pod template", "kind", req.Kind.Kind,
+			"name", req.Name, "namespace", req.Namespace, "user", user.Username, "groups", user.Groups)
+		return bypassDenied(req.Namespace, user, reason)
+	}
+	if bypassedBy != user.Username {
+		return admission.Denied(AnnotationBypassedBy + " is set at admission and can't be changed")
+	}
+
+	bypasses.WithLabelValues(req.Namespace, "allowed").Inc()
+	logger.Info("Honouring scan bypass on a pod template", "kind", req.Kind.Kind, "name", req.Name,
+		"namespace", req.Namespace, "user", user.Username, "reason", reason)
+	resp := admission.Allowed("")
+	resp.AuditAnnotations = map[string]string{"bypassed-by": user.Username}
+	return resp
+}
@@ -173,7 +173,7 @@ func (tm *TokenManager) fetchToken(ctx context.Context) (string, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return "", &APIError{Op: "token request", StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	// Parse response
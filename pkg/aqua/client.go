@@ -31,12 +31,157 @@ const (
 	StatusFound    ScanStatus = "found"
 )
 
+// Aqua scan_status values reported in the v2 image payload
+const (
+	AquaScanStatusFinished   = "finished"
+	AquaScanStatusPending    = "pending"
+	AquaScanStatusInProgress = "in_progress"
+	AquaScanStatusFailed     = "failed"
+)
+
+// VulnerabilityCounts contains the number of vulnerabilities by severity
+type VulnerabilityCounts struct {
+	Critical   int
+	High       int
+	Medium     int
+	Low        int
+	Negligible int
+	// Total is the total number of vulnerabilities found, across all severities
+	Total int
+}
+
+// FindingCounts contains the number of findings other than vulnerabilities
+type FindingCounts struct {
+	Malware int
+	// SensitiveData counts exposed secrets of every type, including private keys
+	SensitiveData     int
+	PrivateKeys       int
+	Misconfigurations int
+}
+
 // ScanResult contains the results from Aqua
-// With v2 API, we only care about whether the image was found (scanned) or not
+// Status tells whether the image was found (scanned) or not; the remaining
+// fields are parsed from the v2 image payload when the image was found
 type ScanResult struct {
 	Status ScanStatus
 	Image  string
 	Digest string
+
+	// Vulnerabilities contains the vulnerability counts by severity
+	Vulnerabilities VulnerabilityCounts
+	// Findings contains the malware, sensitive data and misconfiguration counts
+	Findings FindingCounts
+	// ScanDate is when Aqua last scanned the image (zero if not reported)
+	ScanDate time.Time
+	// Disallowed is true when Aqua's assurance policies disallow the image
+	Disallowed bool
+	// FailedPolicies names the assurance policies the image failed
+	FailedPolicies []string
+	// ScanStatus is Aqua's scan status (e.g., finished, pending, in_progress, failed)
+	ScanStatus string
+	// ScanError is Aqua's error message when the scan failed
+	ScanError string
+}
+
+// InProgress returns true if Aqua knows the image but has not finished scanning it.
+func (r *ScanResult) InProgress() bool {
+	return r.ScanStatus == AquaScanStatusPending || r.ScanStatus == AquaScanStatusInProgress
+}
+
+// ScanFailed returns true if Aqua reported that scanning the image failed.
+func (r *ScanResult) ScanFailed() bool {
+	return r.ScanStatus == AquaScanStatusFailed
+}
+
+// imageResponse is the subset of the GET /api/v2/images/{registry}/{image}/{tag}
+// payload used by the controller
+type imageResponse struct {
+	Name       string `json:"name"`
+	VulnsFound int    `json:"vulns_found"`
+	CritVulns  int    `json:"crit_vulns"`
+	HighVulns  int    `json:"high_vulns"`
+	MedVulns   int    `json:"med_vulns"`
+	LowVulns   int    `json:"low_vulns"`
+	NegVulns   int    `json:"neg_vulns"`
+	ScanStatus string `json:"scan_status"`
+	ScanDate   string `json:"scan_date"`
+	ScanError  string `json:"scan_error"`
+	Disallowed bool   `json:"disallowed"`
+
+	Malware            int            `json:"malware"`
+	SensitiveData      int            `json:"sensitive_data"`
+	SensitiveDataTypes map[string]int `json:"sensitive_data_types"`
+	Misconfigurations  int            `json:"misconfigurations"`
+
+	AssuranceResults *assuranceResults `json:"assurance_results"`
+}
+
+// sensitiveDataPrivateKey is the sensitive data type Aqua reports for embedded private keys
+const sensitiveDataPrivateKey = "private_key"
+
+// assuranceResults is Aqua's evaluation of the image against its assurance policies
+type assuranceResults struct {
+	Disallowed      bool             `json:"disallowed"`
+	ChecksPerformed []assuranceCheck `json:"checks_performed"`
+}
+
+// assuranceCheck is a single assurance policy control evaluated against the image
+type assuranceCheck struct {
+	PolicyName string `json:"policy_name"`
+	Control    string `json:"control"`
+	Failed     bool   `json:"failed"`
+}
+
+// failedPolicies returns the names of the assurance policies with a failed check,
+// in the order Aqua reported them.
+func (r *assuranceResults) failedPolicies() []string {
+	if r == nil {
+		return nil
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, check := range r.ChecksPerformed {
+		if !check.Failed || check.PolicyName == "" || seen[check.PolicyName] {
+			continue
+		}
+		seen[check.PolicyName] = true
+		names = append(names, check.PolicyName)
+	}
+	return names
+}
+
+// toScanResult converts the image payload into a ScanResult with StatusFound.
+// An unparseable scan date is left as the zero time.
+func (r imageResponse) toScanResult(image, digest string) *ScanResult {
+	result := &ScanResult{
+		Status: StatusFound,
+		Image:  image,
+		Digest: digest,
+		Vulnerabilities: VulnerabilityCounts{
+			Critical:   r.CritVulns,
+			High:       r.HighVulns,
+			Medium:     r.MedVulns,
+			Low:        r.LowVulns,
+			Negligible: r.NegVulns,
+			Total:      r.VulnsFound,
+		},
+		Findings: FindingCounts{
+			Malware:           r.Malware,
+			SensitiveData:     r.SensitiveData,
+			PrivateKeys:       r.SensitiveDataTypes[sensitiveDataPrivateKey],
+			Misconfigurations: r.Misconfigurations,
+		},
+		Disallowed:     r.Disallowed || (r.AssuranceResults != nil && r.AssuranceResults.Disallowed),
+		FailedPolicies: r.AssuranceResults.failedPolicies(),
+		ScanStatus:     r.ScanStatus,
+		ScanError:      r.ScanError,
+	}
+	if r.ScanDate != "" {
+		if scanDate, err := time.Parse(time.RFC3339, r.ScanDate); err == nil {
+			result.ScanDate = scanDate
+		}
+	}
+	return result
 }
 
 // Registry represents an Aqua registry configuration
@@ -74,6 +219,9 @@ type Client interface {
 	// TriggerScan initiates a new scan for an image
 	TriggerScan(ctx context.Context, image, digest string) (string, error)
 
+	// GetVulnerabilities retrieves every vulnerability Aqua found in a scanned image
+	GetVulnerabilities(ctx context.Context, image, digest string) ([]Vulnerability, error)
+
 	// GetRegistries retrieves all configured registries from Aqua
 	GetRegistries(ctx context.Context) ([]Registry, error)
 
@@ -390,20 +538,33 @@ func (c *aquaClient) GetScanResult(ctx context.Context, image, digest string) (*
 		}, nil
 	}
 
-	// Any other non-error response means the image has been scanned
-	// We don't care about the enforcement - Aqua enforcer handles that
+	// Any other non-error response means Aqua knows the image; parse the
+	// payload for the scan status and vulnerability counts
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		span.SetAttributes(tracing.AttrScanStatus.String(string(StatusFound)))
-		return &ScanResult{
-			Status: StatusFound,
-			Image:  image,
-			Digest: digest,
-		}, nil
+		var imageResp imageResponse
+		if err := json.NewDecoder(resp.Body).Decode(&imageResp); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to decode response")
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+
+		result := imageResp.toScanResult(image, digest)
+		span.SetAttributes(
+			tracing.AttrScanStatus.String(string(StatusFound)),
+			attribute.String("aqua.scan_status", result.ScanStatus),
+			attribute.Int("vulnerabilities.critical", result.Vulnerabilities.Critical),
+			attribute.Int("vulnerabilities.high", result.Vulnerabilities.High),
+			attribute.Int("findings.malware", result.Findings.Malware),
+			attribute.Int("findings.sensitive_data", result.Findings.SensitiveData),
+			attribute.Bool("disallowed", result.Disallowed),
+			attribute.StringSlice("failed_policies", result.FailedPolicies),
+		)
+		return result, nil
 	}
 
 	// Read response body for error details
 	bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-	err = fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	err = &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	span.RecordError(err)
 	span.SetStatus(codes.Error, "Unexpected status code")
 	return nil, err
@@ -516,7 +677,7 @@ func (c *aquaClient) TriggerScan(ctx context.Context, image, digest string) (str
 
 	// Read response body for error details
 	respBodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-	err = fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(respBodyBytes))
+	err = &APIError{StatusCode: resp.StatusCode, Body: string(respBodyBytes)}
 	span.RecordError(err)
 	span.SetStatus(codes.Error, "Unexpected status code")
 	return "", err
@@ -608,7 +769,7 @@ func (c *aquaClient) fetchRegistries(ctx context.Context) ([]Registry, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		err = fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+		err = &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Unexpected status code")
 		return nil, err
@@ -672,7 +833,7 @@ func (c *aquaClient) FindRegistryByPrefix(ctx context.Context, containerRegistry
 		return name, nil
 	}
 
-	return "", fmt.Errorf("no Aqua registry found for container registry %q", containerRegistry)
+	return "", fmt.Errorf("%w for container registry %q", ErrRegistryNotFound, containerRegistry)
 }
 
 // findRegistryInList searches for a matching registry in the given list
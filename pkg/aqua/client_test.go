@@ -3,6 +3,7 @@ package aqua
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -165,6 +166,161 @@ var _ = Describe("GetScanResult", func() {
 		})
 	})
 
+	Context("when the image payload includes scan results", func() {
+		BeforeEach(func() {
+			server = createMockServerWithToken("test-bearer-token", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{
+					"name": "library/nginx",
+					"vulns_found": 20,
+					"crit_vulns": 1,
+					"high_vulns": 2,
+					"med_vulns": 3,
+					"low_vulns": 4,
+					"neg_vulns": 10,
+					"scan_status": "finished",
+					"scan_date": "2026-01-08T12:00:00Z",
+					"disallowed": true,
+					"malware": 1,
+					"sensitive_data": 3,
+					"sensitive_data_types": {"private_key": 2, "aws_access_key": 1},
+					"misconfigurations": 4
+				}`))
+			})
+
+			client = NewClient(Config{
+				BaseURL:  server.URL,
+				Registry: "test-registry",
+				Auth: AuthConfig{
+					APIKey:     "test-api-key",
+					HMACSecret: "test-secret",
+					AuthURL:    server.URL,
+				},
+			})
+		})
+
+		It("should parse malware, sensitive data and misconfiguration findings", func() {
+			result, err := client.GetScanResult(context.Background(), "nginx:latest", "sha256:abc123")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Findings).To(Equal(FindingCounts{
+				Malware:           1,
+				SensitiveData:     3,
+				PrivateKeys:       2,
+				Misconfigurations: 4,
+			}))
+		})
+
+		It("should parse severity counts, scan date, disallowed flag and scan status", func() {
+			result, err := client.GetScanResult(context.Background(), "nginx:latest", "sha256:abc123")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFound))
+			Expect(result.Vulnerabilities).To(Equal(VulnerabilityCounts{
+				Critical:   1,
+				High:       2,
+				Medium:     3,
+				Low:        4,
+				Negligible: 10,
+				Total:      20,
+			}))
+			Expect(result.ScanDate).To(Equal(time.Date(2026, 1, 8, 12, 0, 0, 0, time.UTC)))
+			Expect(result.Disallowed).To(BeTrue())
+			Expect(result.ScanStatus).To(Equal(AquaScanStatusFinished))
+			Expect(result.InProgress()).To(BeFalse())
+			Expect(result.ScanFailed()).To(BeFalse())
+		})
+	})
+
+	Context("when the image payload includes assurance results", func() {
+		BeforeEach(func() {
+			server = createMockServerWithToken("test-bearer-token", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{
+					"name": "library/nginx",
+					"scan_status": "finished",
+					"disallowed": false,
+					"assurance_results": {
+						"disallowed": true,
+						"checks_performed": [
+							{"policy_name": "Default", "control": "max_severity", "failed": true},
+							{"policy_name": "PCI", "control": "malware", "failed": false},
+							{"policy_name": "Production", "control": "max_score", "failed": true},
+							{"policy_name": "Default", "control": "root_user", "failed": true}
+						]
+					}
+				}`))
+			})
+
+			client = NewClient(Config{
+				BaseURL:  server.URL,
+				Registry: "test-registry",
+				Auth: AuthConfig{
+					APIKey:     "test-api-key",
+					HMACSecret: "test-secret",
+					AuthURL:    server.URL,
+				},
+			})
+		})
+
+		It("should return the verdict and the failed policy names", func() {
+			result, err := client.GetScanResult(context.Background(), "nginx:latest", "sha256:abc123")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Disallowed).To(BeTrue())
+			Expect(result.FailedPolicies).To(Equal([]string{"Default", "Production"}))
+		})
+	})
+
+	Context("when Aqua is still scanning the image", func() {
+		BeforeEach(func() {
+			server = createMockServerWithToken("test-bearer-token", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"name": "library/nginx", "scan_status": "in_progress", "scan_date": ""}`))
+			})
+
+			client = NewClient(Config{
+				BaseURL:  server.URL,
+				Registry: "test-registry",
+				Auth: AuthConfig{
+					APIKey:     "test-api-key",
+					HMACSecret: "test-secret",
+					AuthURL:    server.URL,
+				},
+			})
+		})
+
+		It("should report the scan as in progress", func() {
+			result, err := client.GetScanResult(context.Background(), "nginx:latest", "sha256:abc123")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFound))
+			Expect(result.InProgress()).To(BeTrue())
+			Expect(result.ScanDate.IsZero()).To(BeTrue())
+		})
+	})
+
+	Context("when the image payload is malformed", func() {
+		BeforeEach(func() {
+			server = createMockServerWithToken("test-bearer-token", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`not json`))
+			})
+
+			client = NewClient(Config{
+				BaseURL:  server.URL,
+				Registry: "test-registry",
+				Auth: AuthConfig{
+					APIKey:     "test-api-key",
+					HMACSecret: "test-secret",
+					AuthURL:    server.URL,
+				},
+			})
+		})
+
+		It("should return a decoding error", func() {
+			_, err := client.GetScanResult(context.Background(), "nginx:latest", "sha256:abc123")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("decoding response"))
+		})
+	})
+
 	Context("when image is not found (not scanned)", func() {
 		BeforeEach(func() {
 			server = createMockServerWithToken("test-bearer-token", func(w http.ResponseWriter, r *http.Request) {
@@ -214,6 +370,8 @@ var _ = Describe("GetScanResult", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("500"))
 			Expect(err.Error()).To(ContainSubstring("internal server error"))
+			Expect(IsServerError(err)).To(BeTrue())
+			Expect(errors.Is(err, ErrAuthentication)).To(BeFalse())
 		})
 	})
 
@@ -243,6 +401,8 @@ var _ = Describe("GetScanResult", func() {
 			_, err := client.GetScanResult(context.Background(), "nginx:latest", "sha256:abc123")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("401"))
+			Expect(errors.Is(err, ErrAuthentication)).To(BeTrue())
+			Expect(IsServerError(err)).To(BeFalse())
 		})
 	})
 })
@@ -594,6 +754,7 @@ var _ = Describe("FindRegistryByPrefix", func() {
 			_, err := client.FindRegistryByPrefix(context.Background(), "unknown.registry.io")
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("no Aqua registry found"))
+			Expect(errors.Is(err, ErrRegistryNotFound)).To(BeTrue())
 		})
 	})
 
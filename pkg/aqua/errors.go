@@ -0,0 +1,41 @@
+package aqua
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrAuthentication is returned when Aqua rejects the configured credentials
+	ErrAuthentication = errors.New("authentication failed")
+	// ErrRegistryNotFound is returned when no Aqua registry matches a container registry
+	ErrRegistryNotFound = errors.New("no Aqua registry found")
+)
+
+// APIError is returned when the Aqua API responds with an unexpected status code.
+type APIError struct {
+	// Op describes the failed request, e.g. "token request". Empty for regular API calls.
+	Op         string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	if e.Op != "" {
+		return fmt.Sprintf("%s failed with status %d: %s", e.Op, e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// Is reports 401 and 403 responses as ErrAuthentication.
+func (e *APIError) Is(target error) bool {
+	return target == ErrAuthentication &&
+		(e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden)
+}
+
+// IsServerError returns true if err is caused by a 5xx response from Aqua.
+func IsServerError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= http.StatusInternalServerError
+}
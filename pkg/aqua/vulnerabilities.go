@@ -0,0 +1,199 @@
+package aqua
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+// VulnerabilitiesPageSize is the number of vulnerabilities requested per page
+const VulnerabilitiesPageSize = 1000
+
+// Vulnerability is a single vulnerability Aqua found in an image
+type Vulnerability struct {
+	// Name is the vulnerability ID (e.g., CVE-2023-12345)
+	Name string
+	// Package is the name of the affected package or file
+	Package string
+	// PackageType is the package's purl type (e.g., deb, npm, golang), empty if unknown
+	PackageType string
+	// InstalledVersion is the version of the package in the image
+	InstalledVersion string
+	// FixedVersion is the first version that fixes the vulnerability (empty if none)
+	FixedVersion string
+	// Severity is Aqua's severity rating (critical, high, medium, low, negligible)
+	Severity string
+	// Score is Aqua's vulnerability score
+	Score float64
+	// Acknowledged is true when the vulnerability was acknowledged in Aqua
+	Acknowledged bool
+}
+
+// vulnerabilitiesResponse is the response from
+// GET /api/v2/images/{registry}/{image}/{tag}/vulnerabilities
+type vulnerabilitiesResponse struct {
+	Count    int                     `json:"count"`
+	Page     int                     `json:"page"`
+	PageSize int                     `json:"pagesize"`
+	Result   []vulnerabilityResponse `json:"result"`
+}
+
+type vulnerabilityResponse struct {
+	Name     string `json:"name"`
+	Resource struct {
+		Format  string `json:"format"`
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"resource"`
+	FixVersion       string  `json:"fix_version"`
+	AquaSeverity     string  `json:"aqua_severity"`
+	AquaScore        float64 `json:"aqua_score"`
+	AcknowledgedDate string  `json:"acknowledged_date"`
+}
+
+func (v vulnerabilityResponse) toVulnerability() Vulnerability {
+	return Vulnerability{
+		Name:             v.Name,
+		Package:          v.Resource.Name,
+		PackageType:      packageType(v.Resource.Format),
+		InstalledVersion: v.Resource.Version,
+		FixedVersion:     v.FixVersion,
+		Severity:         v.AquaSeverity,
+		Score:            v.AquaScore,
+		Acknowledged:     v.AcknowledgedDate != "",
+	}
+}
+
+// packageTypes maps Aqua's package formats to purl types; other formats are used as is
+var packageTypes = map[string]string{
+	"python":   "pypi",
+	"jar":      "maven",
+	"go":       "golang",
+	"gobinary": "golang",
+	"rust":     "cargo",
+	"php":      "composer",
+	"dotnet":   "nuget",
+}
+
+// packageType returns the purl type of an Aqua package format. Files and binaries that
+// aren't packages have no purl type.
+func packageType(format string) string {
+	format = strings.ToLower(format)
+	if typ, ok := packageTypes[format]; ok {
+		return typ
+	}
+	if format == "binary" || format == "file" {
+		return ""
+	}
+	return format
+}
+
+func (c *aquaClient) GetVulnerabilities(ctx context.Context, image, digest string) ([]Vulnerability, error) {
+	ctx, span := tracing.StartSpan(ctx, "AquaClient.GetVulnerabilities",
+		trace.WithAttributes(
+			tracing.AttrImageName.String(image),
+			tracing.AttrImageDigest.String(digest),
+		),
+	)
+	defer span.End()
+
+	containerRegistry, imageName, tag, err := parseImageReference(image, digest)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to parse image reference")
+		return nil, err
+	}
+
+	// Apply registry mirrors if configured (for airgapped environments)
+	containerRegistry, imageName = ApplyRegistryMirror(containerRegistry, imageName, c.config.RegistryMirrors)
+
+	aquaRegistry, err := c.FindRegistryByPrefix(ctx, containerRegistry)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find Aqua registry")
+		return nil, fmt.Errorf("finding Aqua registry for %q: %w", containerRegistry, err)
+	}
+
+	span.SetAttributes(tracing.AttrAquaRegistry.String(aquaRegistry))
+
+	apiURL, err := url.JoinPath(c.config.BaseURL, "api", "v2", "images", aquaRegistry, imageName, tag, "vulnerabilities")
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to build API URL")
+		return nil, fmt.Errorf("building API URL: %w", err)
+	}
+
+	span.SetAttributes(
+		tracing.AttrHTTPMethod.String("GET"),
+		tracing.AttrHTTPURL.String(apiURL),
+	)
+
+	var vulnerabilities []Vulnerability
+	for page := 1; ; page++ {
+		resp, err := c.getVulnerabilitiesPage(ctx, apiURL, page)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to get vulnerabilities")
+			return nil, err
+		}
+		for _, v := range resp.Result {
+			vulnerabilities = append(vulnerabilities, v.toVulnerability())
+		}
+		if len(resp.Result) == 0 || len(vulnerabilities) >= resp.Count {
+			break
+		}
+	}
+
+	span.SetAttributes(attribute.Int("vulnerability_count", len(vulnerabilities)))
+	return vulnerabilities, nil
+}
+
+// getVulnerabilitiesPage fetches one page of an image's vulnerabilities.
+func (c *aquaClient) getVulnerabilitiesPage(ctx context.Context, apiURL string, page int) (*vulnerabilitiesResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	query := req.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("pagesize", strconv.Itoa(VulnerabilitiesPageSize))
+	req.URL.RawQuery = query.Encode()
+
+	// Get bearer token (this will fetch via HMAC-signed request if needed)
+	token, err := c.tokenManager.GetToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting auth token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	var vulnsResp vulnerabilitiesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&vulnsResp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &vulnsResp, nil
+}
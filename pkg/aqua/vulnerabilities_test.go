@@ -0,0 +1,99 @@
+package aqua
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetVulnerabilities", func() {
+	var (
+		server *httptest.Server
+		client Client
+	)
+
+	AfterEach(func() {
+		if server != nil {
+			server.Close()
+		}
+	})
+
+	newClient := func() Client {
+		return NewClient(Config{
+			BaseURL:  server.URL,
+			Registry: "test-registry",
+			Auth: AuthConfig{
+				APIKey:     "test-api-key",
+				HMACSecret: "test-secret",
+				AuthURL:    server.URL,
+			},
+		})
+	}
+
+	Context("when the vulnerabilities span several pages", func() {
+		var requestedPages []string
+
+		BeforeEach(func() {
+			requestedPages = nil
+			pages := map[string][]vulnerabilityResponse{
+				"1": {
+					{Name: "CVE-2024-0001", FixVersion: "1.1.1w", AquaSeverity: "critical", AquaScore: 9.8},
+					{Name: "CVE-2024-0002", AquaSeverity: "high", AquaScore: 7.5, AcknowledgedDate: "2026-01-02T00:00:00Z"},
+				},
+				"2": {
+					{Name: "CVE-2024-0003", AquaSeverity: "low", AquaScore: 2.1},
+				},
+			}
+			pages["1"][0].Resource.Format = "deb"
+			pages["1"][0].Resource.Name = "openssl"
+			pages["1"][0].Resource.Version = "1.1.1k"
+
+			server = createMockServerWithToken("test-bearer-token", func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer test-bearer-token"))
+				Expect(strings.HasSuffix(r.URL.Path, "/vulnerabilities")).To(BeTrue())
+				page := r.URL.Query().Get("page")
+				requestedPages = append(requestedPages, page)
+				w.WriteHeader(http.StatusOK)
+				_ = json.NewEncoder(w).Encode(vulnerabilitiesResponse{Count: 3, Result: pages[page]})
+			})
+			client = newClient()
+		})
+
+		It("should fetch every page and convert the vulnerabilities", func() {
+			vulns, err := client.GetVulnerabilities(context.Background(), "nginx:latest", "sha256:abc123")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requestedPages).To(Equal([]string{"1", "2"}))
+			Expect(vulns).To(HaveLen(3))
+			Expect(vulns[0]).To(Equal(Vulnerability{
+				Name:             "CVE-2024-0001",
+				Package:          "openssl",
+				PackageType:      "deb",
+				InstalledVersion: "1.1.1k",
+				FixedVersion:     "1.1.1w",
+				Severity:         "critical",
+				Score:            9.8,
+			}))
+			Expect(vulns[1].Acknowledged).To(BeTrue())
+		})
+	})
+
+	Context("when the API returns an error", func() {
+		BeforeEach(func() {
+			server = createMockServerWithToken("test-bearer-token", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			})
+			client = newClient()
+		})
+
+		It("should return an APIError", func() {
+			_, err := client.GetVulnerabilities(context.Background(), "nginx:latest", "sha256:abc123")
+			Expect(err).To(HaveOccurred())
+			Expect(IsServerError(err)).To(BeTrue())
+		})
+	})
+})
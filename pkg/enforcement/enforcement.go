@@ -0,0 +1,107 @@
+// Package enforcement decides whether failed scans block pods or are only reported,
+// globally and per namespace.
+package enforcement
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Mode is how scan verdicts are enforced
+type Mode string
+
+const (
+	// Enforce gates pods until their images pass and rejects images that already failed
+	Enforce Mode = "enforce"
+	// Warn admits pods ungated, warning the client about images that failed
+	Warn Mode = "warn"
+	// Audit admits pods ungated, only recording events and metrics for failed images
+	Audit Mode = "audit"
+)
+
+const (
+	// LabelMode on a namespace overrides the default mode for its pods
+	LabelMode = "scans.aquasec.community/enforcement-mode"
+
+	// AnnotationMode records on an audited pod the mode it was admitted or released in
+	AnnotationMode = "scans.aquasec.community/enforcement-mode"
+
+	// LabelAudit tracks the verdict of pods admitted ungated in warn or audit mode
+	LabelAudit = "scans.aquasec.community/audit"
+
+	AuditPending    = "pending"
+	AuditPassed     = "passed"
+	AuditWouldBlock = "would-block"
+)
+
+// wouldBlock counts pods warn or audit mode let through that enforce mode would have blocked
+var wouldBlock = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "aqua_scan_gate_would_block_total",
+	Help: "Number of pods that would have been blocked in enforce mode, by mode and stage (admission or scan)",
+}, []string{"mode", "namespace", "stage"})
+
+func init() {
+	metrics.Registry.MustRegister(wouldBlock)
+}
+
+// ParseMode parses an enforcement mode.
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case Enforce, Warn, Audit:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid enforcement mode %q: must be enforce, warn or audit", s)
+}
+
+// Modes looks up the enforcement mode of namespaces
+type Modes struct {
+	// Reader reads namespaces, normally from the informer cache
+	Reader client.Reader
+	// Default applies to namespaces without a valid LabelMode label
+	Default Mode
+}
+
+// Mode returns the enforcement mode of a namespace. A nil Modes enforces everywhere; a
+// namespace that can't be read, or has an invalid label, gets the default mode.
+func (m *Modes) Mode(ctx context.Context, namespace string) Mode {
+	if m == nil {
+		return Enforce
+	}
+	defaultMode := m.Default
+	if defaultMode == "" {
+		defaultMode = Enforce
+	}
+	if m.Reader == nil || namespace == "" {
+		return defaultMode
+	}
+
+	var ns corev1.Namespace
+	if err := m.Reader.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.FromContext(ctx).Error(err, "Failed to read namespace enforcement mode", "namespace", namespace)
+		}
+		return defaultMode
+	}
+	value, ok := ns.Labels[LabelMode]
+	if !ok {
+		return defaultMode
+	}
+	mode, err := ParseMode(value)
+	if err != nil {
+		log.FromContext(ctx).Info("Ignoring invalid enforcement mode label", "namespace", namespace, "value", value)
+		return defaultMode
+	}
+	return mode
+}
+
+// RecordWouldBlock counts a pod that enforce mode would have blocked at the given stage.
+func RecordWouldBlock(mode Mode, namespace, stage string) {
+	wouldBlock.WithLabelValues(string(mode), namespace, stage).Inc()
+}
@@ -0,0 +1,65 @@
+package enforcement
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMode(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	namespace := func(name, mode string) *corev1.Namespace {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if mode != "" {
+			ns.Labels = map[string]string{LabelMode: mode}
+		}
+		return ns
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		namespace("unlabelled", ""),
+		namespace("audited", "audit"),
+		namespace("enforced", "enforce"),
+		namespace("typo", "audt"),
+	).Build()
+
+	tests := []struct {
+		name      string
+		modes     *Modes
+		namespace string
+		want      Mode
+	}{
+		{name: "nil modes enforce", namespace: "audited", want: Enforce},
+		{name: "empty default enforces", modes: &Modes{Reader: reader}, namespace: "unlabelled", want: Enforce},
+		{name: "default", modes: &Modes{Reader: reader, Default: Warn}, namespace: "unlabelled", want: Warn},
+		{name: "label overrides default", modes: &Modes{Reader: reader, Default: Enforce}, namespace: "audited", want: Audit},
+		{name: "label enforces under audit default", modes: &Modes{Reader: reader, Default: Audit}, namespace: "enforced", want: Enforce},
+		{name: "invalid label uses default", modes: &Modes{Reader: reader, Default: Warn}, namespace: "typo", want: Warn},
+		{name: "missing namespace uses default", modes: &Modes{Reader: reader, Default: Audit}, namespace: "missing", want: Audit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.modes.Mode(context.Background(), tt.namespace); got != tt.want {
+				t.Errorf("expected mode %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"enforce", "warn", "audit"} {
+		if mode, err := ParseMode(s); err != nil || string(mode) != s {
+			t.Errorf("ParseMode(%q) = %q, %v", s, mode, err)
+		}
+	}
+	if _, err := ParseMode("Enforce"); err == nil {
+		t.Error("expected an error for an invalid mode")
+	}
+}
@@ -0,0 +1,86 @@
+package imageexclude
+
+import (
+	"context"
+	"time"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+	"github.com/richardmsong/aqua-scan-gate/pkg/reloadfile"
+)
+
+// Exclusions combines the exclusions given as flags with those in a file, which is
+// reloaded when it changes, e.g., when the ConfigMap it is mounted from is updated. The
+// webhooks and the controllers share one Exclusions, so they exempt the same images.
+type Exclusions struct {
+	static *Matcher
+	file   *reloadfile.File[*Matcher]
+}
+
+// New returns the exclusions in static and, unless path is empty, in the file at path,
+// which must be valid.
+func New(static *Matcher, path string, interval time.Duration) (*Exclusions, error) {
+	e := &Exclusions{static: static}
+	if path != "" {
+		file, err := reloadfile.New("image exclusions", path, interval, Parse)
+		if err != nil {
+			return nil, err
+		}
+		e.file = file
+	}
+	return e, nil
+}
+
+// Excluded returns true if the image is exempt from scanning. Nil Exclusions exempt nothing.
+func (e *Exclusions) Excluded(img imageref.ImageRef) bool {
+	if e == nil {
+		return false
+	}
+	if e.static.Excluded(img) {
+		return true
+	}
+	return e.file.Value().Excluded(img)
+}
+
+// Filter returns the images that aren't exempt from scanning.
+func (e *Exclusions) Filter(images []imageref.ImageRef) []imageref.ImageRef {
+	if e == nil {
+		return images
+	}
+	filtered := make([]imageref.ImageRef, 0, len(images))
+	for _, img := range images {
+		if !e.Excluded(img) {
+			filtered = append(filtered, img)
+		}
+	}
+	return filtered
+}
+
+// Len returns the number of exclusions.
+func (e *Exclusions) Len() int {
+	if e == nil {
+		return 0
+	}
+	return e.static.Len() + e.file.Value().Len()
+}
+
+// Reload reads the exclusions file, returning true if it changed. An invalid file keeps
+// the previous exclusions.
+func (e *Exclusions) Reload() (bool, error) {
+	if e.file == nil {
+		return false, nil
+	}
+	return e.file.Reload()
+}
+
+// Start reloads the exclusions file every interval until the context is cancelled.
+func (e *Exclusions) Start(ctx context.Context) error {
+	if e.file == nil {
+		return nil
+	}
+	return e.file.Start(ctx)
+}
+
+// NeedLeaderElection returns false, since every replica serves admission requests.
+func (e *Exclusions) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,150 @@
+// Package imageexclude decides which images are exempt from scanning, by glob, regular
+// expression, registry or repository prefix, or digest.
+package imageexclude
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+)
+
+// Prefixes of --excluded-images entries; other entries are globs
+const (
+	PrefixRegex      = "regex:"
+	PrefixRepository = "prefix:"
+	PrefixDigest     = "sha256:"
+)
+
+// Config lists the images exempt from scanning, usually loaded from a file mounted from a
+// ConfigMap
+type Config struct {
+	// Images are glob patterns matched against the image reference as written in the pod
+	// spec (e.g., registry.example.com/base/*:*). As in path.Match, * doesn't match /.
+	Images []string `json:"images,omitempty"`
+
+	// Regexes are regular expressions the whole image reference must match
+	Regexes []string `json:"regexes,omitempty"`
+
+	// Prefixes are registries (e.g., registry.k8s.io) or registry and repository prefixes
+	// (e.g., ghcr.io/example-org); docker.io matches images without a registry
+	Prefixes []string `json:"prefixes,omitempty"`
+
+	// Digests (sha256:...) are exempt however they are referenced, including by a tag
+	// that resolves to them
+	Digests []string `json:"digests,omitempty"`
+}
+
+// Matcher matches images against a compiled Config
+type Matcher struct {
+	globs    []string
+	regexes  []*regexp.Regexp
+	prefixes []string
+	digests  map[string]bool
+}
+
+// Parse parses a YAML or JSON Config.
+func Parse(data []byte) (*Matcher, error) {
+	var config Config
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing image exclusions: %w", err)
+	}
+	return config.Compile()
+}
+
+// ParseList parses a comma-separated list of exclusions: regex:<expression>,
+// prefix:<registry[/repository]>, a sha256:<hex> digest, or else a glob.
+func ParseList(list string) (*Matcher, error) {
+	var config Config
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if regex, ok := strings.CutPrefix(entry, PrefixRegex); ok {
+			config.Regexes = append(config.Regexes, regex)
+		} else if prefix, ok := strings.CutPrefix(entry, PrefixRepository); ok {
+			config.Prefixes = append(config.Prefixes, prefix)
+		} else if strings.HasPrefix(entry, PrefixDigest) {
+			config.Digests = append(config.Digests, entry)
+		} else if entry != "" {
+			config.Images = append(config.Images, entry)
+		}
+	}
+	return config.Compile()
+}
+
+// Compile validates the Config and returns its Matcher.
+func (c Config) Compile() (*Matcher, error) {
+	m := &Matcher{globs: c.Images, digests: map[string]bool{}}
+	for _, glob := range c.Images {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid image glob %q: %w", glob, err)
+		}
+	}
+	for _, expr := range c.Regexes {
+		regex, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid image regex %q: %w", expr, err)
+		}
+		m.regexes = append(m.regexes, regex)
+	}
+	for _, entry := range c.Prefixes {
+		prefix, err := imageref.NormalizePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image prefix %q: %w", entry, err)
+		}
+		m.prefixes = append(m.prefixes, prefix)
+	}
+	for _, digest := range c.Digests {
+		if _, err := v1.NewHash(digest); err != nil {
+			return nil, fmt.Errorf("invalid image digest %q: %w", digest, err)
+		}
+		m.digests[digest] = true
+	}
+	return m, nil
+}
+
+// Len returns the number of exclusions.
+func (m *Matcher) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.globs) + len(m.regexes) + len(m.prefixes) + len(m.digests)
+}
+
+// Excluded returns true if the image is exempt from scanning. Digest exclusions only
+// match tag references once their digest is resolved.
+func (m *Matcher) Excluded(img imageref.ImageRef) bool {
+	if m == nil {
+		return false
+	}
+	if img.Digest != "" && m.digests[img.Digest] {
+		return true
+	}
+	for _, glob := range m.globs {
+		if ok, _ := path.Match(glob, img.Image); ok {
+			return true
+		}
+	}
+	for _, regex := range m.regexes {
+		if regex.MatchString(img.Image) {
+			return true
+		}
+	}
+	if len(m.prefixes) > 0 {
+		ref, err := name.ParseReference(img.Image)
+		if err != nil {
+			return false
+		}
+		for _, prefix := range m.prefixes {
+			if imageref.HasPrefix(ref.Context().Name(), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package imageexclude
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+)
+
+const (
+	digest      = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	otherDigest = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+)
+
+func TestExcluded(t *testing.T) {
+	matcher, err := ParseList("registry.example.com/base/*:*, regex:ghcr\\.io/example-org/.+-debug:.*, " +
+		"prefix:registry.k8s.io, prefix:docker.io/library/busybox, " + digest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		img  imageref.ImageRef
+		want bool
+	}{
+		{name: "glob", img: imageref.ImageRef{Image: "registry.example.com/base/alpine:3.20"}, want: true},
+		{name: "glob doesn't match /", img: imageref.ImageRef{Image: "registry.example.com/base/team/app:v1"}},
+		{name: "glob needs a tag", img: imageref.ImageRef{Image: "registry.example.com/base/alpine"}},
+		{name: "regex", img: imageref.ImageRef{Image: "ghcr.io/example-org/app-debug:v1"}, want: true},
+		{name: "regex is anchored", img: imageref.ImageRef{Image: "mirror.example.com/ghcr.io/example-org/app-debug:v1"}},
+		{name: "registry prefix", img: imageref.ImageRef{Image: "registry.k8s.io/pause:3.9"}, want: true},
+		{name: "repository prefix", img: imageref.ImageRef{Image: "busybox:1.36"}, want: true},
+		{name: "repository prefix doesn't match partial names", img: imageref.ImageRef{Image: "docker.io/library/busyboxplus:latest"}},
+		{name: "digest reference", img: imageref.ImageRef{Image: "quay.io/team/app@" + digest, Digest: digest}, want: true},
+		{name: "resolved tag", img: imageref.ImageRef{Image: "quay.io/team/app:v1", Digest: digest}, want: true},
+		{name: "unresolved tag", img: imageref.ImageRef{Image: "quay.io/team/app:v1"}},
+		{name: "other digest", img: imageref.ImageRef{Image: "quay.io/team/app:v1", Digest: otherDigest}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matcher.Excluded(tt.img); got != tt.want {
+				t.Errorf("Excluded(%v) = %v, want %v", tt.img, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidExclusions(t *testing.T) {
+	for _, list := range []string{"registry.example.com/[app", "regex:(", "prefix:bad registry", "sha256:abc"} {
+		if _, err := ParseList(list); err == nil {
+			t.Errorf("expected an error for %q", list)
+		}
+	}
+	if _, err := Parse([]byte("image: [nginx]")); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestExclusionsReload(t *testing.T) {
+	static, err := ParseList("prefix:registry.k8s.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "exclusions.yaml")
+	if err := os.WriteFile(path, []byte("images: ['nginx:*']"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	exclusions, err := New(static, path, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	images := []imageref.ImageRef{{Image: "registry.k8s.io/pause:3.9"}, {Image: "nginx:1.27"}, {Image: "redis:7"}}
+	if got := exclusions.Filter(images); len(got) != 1 || got[0].Image != "redis:7" {
+		t.Errorf("unexpected images %v", got)
+	}
+	if changed, err := exclusions.Reload(); err != nil || changed {
+		t.Errorf("expected unchanged exclusions, got changed=%v, err=%v", changed, err)
+	}
+
+	if err := os.WriteFile(path, []byte("images: ['redis:*']"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := exclusions.Reload(); err != nil || !changed {
+		t.Errorf("expected changed exclusions, got changed=%v, err=%v", changed, err)
+	}
+	if got := exclusions.Filter(images); len(got) != 1 || got[0].Image != "nginx:1.27" {
+		t.Errorf("unexpected images %v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("regexes: ['(']"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exclusions.Reload(); err == nil {
+		t.Error("expected an error for invalid exclusions")
+	}
+	if !exclusions.Excluded(imageref.ImageRef{Image: "redis:7"}) {
+		t.Error("expected the previous exclusions to be kept")
+	}
+}
+
+func TestNilExclusions(t *testing.T) {
+	var exclusions *Exclusions
+	images := []imageref.ImageRef{{Image: "nginx:1.27"}}
+	if exclusions.Excluded(images[0]) || len(exclusions.Filter(images)) != 1 {
+		t.Error("expected nil exclusions to exempt nothing")
+	}
+}
@@ -0,0 +1,30 @@
+package imagepolicy
+
+import (
+	"time"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/reloadfile"
+)
+
+// FileSource holds the policy loaded from a file and reloads it when the file changes,
+// e.g., when the ConfigMap it is mounted from is updated.
+type FileSource struct {
+	*reloadfile.File[*Config]
+}
+
+// NewFileSource loads the policy in path, which must be valid.
+func NewFileSource(path string, interval time.Duration) (*FileSource, error) {
+	file, err := reloadfile.New("image policy", path, interval, Parse)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSource{File: file}, nil
+}
+
+// Config returns the current policy.
+func (s *FileSource) Config() *Config {
+	if s == nil {
+		return nil
+	}
+	return s.Value()
+}
@@ -0,0 +1,104 @@
+// Package imagepolicy checks image references against an allowlist of registries and
+// rules on how they are referenced, independently of scan results.
+package imagepolicy
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"sigs.k8s.io/yaml"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+)
+
+// Config is the image reference policy, usually loaded from a file mounted from a ConfigMap
+type Config struct {
+	// AllowedRegistries are the registries (e.g., registry.example.com) or registry and
+	// repository prefixes (e.g., ghcr.io/example-org) images may be pulled from.
+	// Empty allows every registry.
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+
+	// RequireMirror rejects images from a registry that has a configured mirror, so
+	// they are pulled through the mirror instead
+	RequireMirror bool `json:"requireMirror,omitempty"`
+
+	// ProtectedNamespaces are the namespaces DenyLatest and RequireDigest apply to
+	ProtectedNamespaces []string `json:"protectedNamespaces,omitempty"`
+
+	// DenyLatest rejects images referenced by the latest tag, explicitly or implicitly,
+	// without a digest
+	DenyLatest bool `json:"denyLatest,omitempty"`
+
+	// RequireDigest rejects images referenced by tag only
+	RequireDigest bool `json:"requireDigest,omitempty"`
+}
+
+// Parse parses a YAML or JSON image reference policy.
+func Parse(data []byte) (*Config, error) {
+	var config Config
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing image policy: %w", err)
+	}
+	for _, entry := range config.AllowedRegistries {
+		if _, err := imageref.NormalizePrefix(entry); err != nil {
+			return nil, fmt.Errorf("invalid allowed registry %q: %w", entry, err)
+		}
+	}
+	return &config, nil
+}
+
+// Check returns the reasons an image in namespace violates the policy. mirrors are the
+// registry mirrors images must be pulled through when RequireMirror is set.
+func (c *Config) Check(namespace, image string, mirrors []aqua.RegistryMirror) []string {
+	if c == nil {
+		return nil
+	}
+
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid image reference: %v", err)}
+	}
+	registry := ref.Context().RegistryStr()
+	repository := ref.Context().RepositoryStr()
+
+	var violations []string
+	if !c.allowed(ref.Context().Name()) {
+		violations = append(violations, fmt.Sprintf("registry %s is not allowed", registry))
+	}
+	if c.RequireMirror {
+		if mirror, mirrored := aqua.ApplyRegistryMirror(registry, repository, mirrors); mirror != registry {
+			violations = append(violations, fmt.Sprintf("registry %s must be pulled through its mirror %s/%s", registry, mirror, mirrored))
+		}
+	}
+
+	if slices.Contains(c.ProtectedNamespaces, namespace) {
+		if tag, ok := ref.(name.Tag); ok {
+			switch {
+			case c.DenyLatest && tag.TagStr() == name.DefaultTag:
+				violations = append(violations, "the latest tag is not allowed")
+			case c.RequireDigest:
+				violations = append(violations, "a digest is required")
+			}
+		}
+	}
+	return violations
+}
+
+// allowed returns true if the repository is in an allowed registry or under an allowed prefix.
+func (c *Config) allowed(repository string) bool {
+	if len(c.AllowedRegistries) == 0 {
+		return true
+	}
+	for _, entry := range c.AllowedRegistries {
+		prefix, err := imageref.NormalizePrefix(entry)
+		if err != nil {
+			continue
+		}
+		if imageref.HasPrefix(repository, prefix) {
+			return true
+		}
+	}
+	return false
+}
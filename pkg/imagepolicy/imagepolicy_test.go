@@ -0,0 +1,149 @@
+package imagepolicy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/aqua"
+)
+
+const testPolicy = `
+allowedRegistries:
+- registry.example.com
+- ghcr.io/example-org
+- docker.io
+requireMirror: true
+protectedNamespaces:
+- prod
+denyLatest: true
+requireDigest: true
+`
+
+func TestCheck(t *testing.T) {
+	config, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mirrors := []aqua.RegistryMirror{{Source: "docker.io", Mirror: "registry.example.com/docker-remote"}}
+	const digest = "@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name      string
+		namespace string
+		image     string
+		want      []string
+	}{
+		{
+			name:      "allowed registry",
+			namespace: "dev",
+			image:     "registry.example.com/team/app:v1",
+		},
+		{
+			name:      "allowed repository prefix",
+			namespace: "dev",
+			image:     "ghcr.io/example-org/tool:v1",
+		},
+		{
+			name:      "other repository in prefix registry",
+			namespace: "dev",
+			image:     "ghcr.io/example-organization/tool:v1",
+			want:      []string{"registry ghcr.io is not allowed"},
+		},
+		{
+			name:      "registry not allowed",
+			namespace: "dev",
+			image:     "quay.io/team/app:v1",
+			want:      []string{"registry quay.io is not allowed"},
+		},
+		{
+			name:      "mirrored registry",
+			namespace: "dev",
+			image:     "nginx:1.25",
+			want:      []string{"must be pulled through its mirror registry.example.com/docker-remote/library/nginx"},
+		},
+		{
+			name:      "implicit latest in protected namespace",
+			namespace: "prod",
+			image:     "registry.example.com/team/app",
+			want:      []string{"the latest tag is not allowed"},
+		},
+		{
+			name:      "tag only in protected namespace",
+			namespace: "prod",
+			image:     "registry.example.com/team/app:v1",
+			want:      []string{"a digest is required"},
+		},
+		{
+			name:      "digest in protected namespace",
+			namespace: "prod",
+			image:     "registry.example.com/team/app:latest" + digest,
+		},
+		{
+			name:      "invalid reference",
+			namespace: "dev",
+			image:     "Registry.example.com/UPPER:v1",
+			want:      []string{"invalid image reference"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := config.Check(tt.namespace, tt.image, mirrors)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d violations, got %v", len(tt.want), got)
+			}
+			for i := range got {
+				if !strings.Contains(got[i], tt.want[i]) {
+					t.Errorf("expected violation containing %q, got %q", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidPolicies(t *testing.T) {
+	if _, err := Parse([]byte("allowedRegistry: [registry.example.com]")); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if _, err := Parse([]byte("allowedRegistries: ['bad registry']")); err == nil {
+		t.Error("expected an error for an invalid registry")
+	}
+}
+
+func TestFileSourceReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("allowedRegistries: [registry.example.com]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewFileSource(path, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if changed, err := source.Reload(); err != nil || changed {
+		t.Errorf("expected an unchanged policy, got changed=%v, err=%v", changed, err)
+	}
+
+	if err := os.WriteFile(path, []byte("allowedRegistries: [ghcr.io]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := source.Reload(); err != nil || !changed {
+		t.Errorf("expected a changed policy, got changed=%v, err=%v", changed, err)
+	}
+	if got := source.Config().AllowedRegistries; len(got) != 1 || got[0] != "ghcr.io" {
+		t.Errorf("unexpected allowed registries %v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("allowedRegistries: ['bad registry']"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Reload(); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+	if got := source.Config().AllowedRegistries; got[0] != "ghcr.io" {
+		t.Errorf("expected the previous policy to be kept, got %v", got)
+	}
+}
@@ -0,0 +1,189 @@
+package imageref
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/richardmsong/aqua-scan-gate/pkg/tracing"
+)
+
+// DefaultDigestCacheTTL is the default time-to-live for resolved tag digests
+const DefaultDigestCacheTTL = 5 * time.Minute
+
+// digestCacheKey identifies a cached resolution of an image reference, either to the
+// target platform's digest or to the digest of the manifest it points at
+type digestCacheKey struct {
+	image    string
+	manifest bool
+}
+
+// digestCacheEntry holds a resolved digest with the time it was resolved
+type digestCacheEntry struct {
+	digest     string
+	resolvedAt time.Time
+}
+
+// CachingResolver wraps a Resolver with an in-memory TTL cache of tag-to-digest
+// resolutions, so that many pods referencing the same tag only hit the registry once
+// per TTL window. Digests are content addresses, so the cache is shared regardless
+// of which credentials were used to resolve them. Expired entries are removed when
+// looked up, and by Start every TTL for images that aren't looked up again.
+type CachingResolver struct {
+	resolver *Resolver
+	ttl      time.Duration
+
+	mu      sync.RWMutex
+	entries map[digestCacheKey]digestCacheEntry
+
+	// now is overridable for tests
+	now func() time.Time
+}
+
+// NewCachingResolver creates a CachingResolver. A zero or negative ttl uses
+// DefaultDigestCacheTTL.
+func NewCachingResolver(resolver *Resolver, ttl time.Duration) *CachingResolver {
+	if ttl <= 0 {
+		ttl = DefaultDigestCacheTTL
+	}
+	return &CachingResolver{
+		resolver: resolver,
+		ttl:      ttl,
+		entries:  make(map[digestCacheKey]digestCacheEntry),
+		now:      time.Now,
+	}
+}
+
+// ResolveImageRef resolves an ImageRef, populating the Digest field if empty.
+// Cached digests are returned without contacting the registry while they are fresh.
+func (c *CachingResolver) ResolveImageRef(ctx context.Context, img ImageRef, extraOpts ...remote.Option) (ImageRef, error) {
+	if img.Digest != "" {
+		return img, nil
+	}
+
+	if digest, ok := c.Lookup(img.Image); ok {
+		_, span := tracing.StartSpan(ctx, "imageref.CachingResolver.ResolveImageRef",
+			trace.WithAttributes(
+				tracing.AttrImageName.String(img.Image),
+				tracing.AttrImageDigest.String(digest),
+				attribute.Bool("cache_hit", true),
+			))
+		span.End()
+		return ImageRef{Image: img.Image, Digest: digest}, nil
+	}
+
+	resolved, err := c.resolver.ResolveImageRef(ctx, img, extraOpts...)
+	if err != nil {
+		return img, err
+	}
+
+	c.store(digestCacheKey{image: img.Image}, resolved.Digest)
+	return resolved, nil
+}
+
+// ResolveManifestDigest resolves an image reference to the digest of the manifest it
+// points at, the index digest for multi-arch images. Cached digests are returned without
+// contacting the registry while they are fresh.
+func (c *CachingResolver) ResolveManifestDigest(ctx context.Context, image string, extraOpts ...remote.Option) (string, error) {
+	if digest, ok := c.LookupManifest(image); ok {
+		_, span := tracing.StartSpan(ctx, "imageref.CachingResolver.ResolveManifestDigest",
+			trace.WithAttributes(
+				tracing.AttrImageName.String(image),
+				tracing.AttrImageDigest.String(digest),
+				attribute.Bool("cache_hit", true),
+			))
+		span.End()
+		return digest, nil
+	}
+
+	digest, err := c.resolver.ResolveManifestDigest(ctx, image, extraOpts...)
+	if err != nil {
+		return "", err
+	}
+	c.store(digestCacheKey{image: image, manifest: true}, digest)
+	return digest, nil
+}
+
+func (c *CachingResolver) store(key digestCacheKey, digest string) {
+	c.mu.Lock()
+	c.entries[key] = digestCacheEntry{
+		digest:     digest,
+		resolvedAt: c.now(),
+	}
+	c.mu.Unlock()
+}
+
+// Lookup returns the cached digest for an image reference if present and not expired.
+// It never contacts the registry.
+func (c *CachingResolver) Lookup(image string) (string, bool) {
+	return c.lookup(digestCacheKey{image: image})
+}
+
+// LookupManifest returns the cached manifest digest for an image reference if present
+// and not expired. It never contacts the registry.
+func (c *CachingResolver) LookupManifest(image string) (string, bool) {
+	return c.lookup(digestCacheKey{image: image, manifest: true})
+}
+
+func (c *CachingResolver) lookup(key digestCacheKey) (string, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	if c.now().Sub(entry.resolvedAt) >= c.ttl {
+		c.mu.Lock()
+		// Re-check under the write lock in case another caller refreshed the entry
+		if current, ok := c.entries[key]; ok && current.resolvedAt == entry.resolvedAt {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+		return "", false
+	}
+	return entry.digest, true
+}
+
+// Sweep removes the expired entries, returning how many were removed.
+func (c *CachingResolver) Sweep() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	removed := 0
+	for key, entry := range c.entries {
+		if now.Sub(entry.resolvedAt) >= c.ttl {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of cached entries, including expired ones not yet swept.
+func (c *CachingResolver) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Start sweeps expired entries every TTL until the context is cancelled.
+func (c *CachingResolver) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("digest-cache")
+	wait.UntilWithContext(ctx, func(context.Context) {
+		if removed := c.Sweep(); removed > 0 {
+			logger.V(1).Info("Removed expired digests", "removed", removed, "remaining", c.Len())
+		}
+	}, c.ttl)
+	return nil
+}
+
+// NeedLeaderElection returns false, since every replica resolves digests.
+func (c *CachingResolver) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,213 @@
+package imageref
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// newTestRegistry starts an in-memory registry with a single random image pushed
+// as <host>/app:v1. It returns the tag reference, the image digest and a counter
+// of manifest requests served.
+func newTestRegistry(t *testing.T) (string, string, *atomic.Int32) {
+	t.Helper()
+
+	var manifestHits atomic.Int32
+	regHandler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/") {
+			manifestHits.Add(1)
+		}
+		regHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	tag := host + "/app:v1"
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("creating random image: %v", err)
+	}
+	ref, err := name.ParseReference(tag)
+	if err != nil {
+		t.Fatalf("parsing reference: %v", err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("pushing image: %v", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("getting digest: %v", err)
+	}
+
+	manifestHits.Store(0)
+	return tag, digest.String(), &manifestHits
+}
+
+func TestCachingResolver(t *testing.T) {
+	ctx := context.Background()
+	tag, digest, hits := newTestRegistry(t)
+
+	now := time.Now()
+	c := NewCachingResolver(NewResolver(), time.Minute)
+	c.now = func() time.Time { return now }
+
+	t.Run("resolves and caches", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			ref, err := c.ResolveImageRef(ctx, ImageRef{Image: tag})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ref.Digest != digest {
+				t.Errorf("expected digest %q, got %q", digest, ref.Digest)
+			}
+		}
+
+		first := hits.Load()
+		if first == 0 {
+			t.Fatal("expected registry to be queried")
+		}
+
+		if _, err := c.ResolveImageRef(ctx, ImageRef{Image: tag}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if hits.Load() != first {
+			t.Errorf("expected cached lookup, registry hits went from %d to %d", first, hits.Load())
+		}
+
+		if cached, ok := c.Lookup(tag); !ok || cached != digest {
+			t.Errorf("expected Lookup to return %q, got %q (ok=%v)", digest, cached, ok)
+		}
+	})
+
+	t.Run("expires after TTL", func(t *testing.T) {
+		before := hits.Load()
+		now = now.Add(2 * time.Minute)
+
+		if _, ok := c.Lookup(tag); ok {
+			t.Error("expected expired entry to be evicted")
+		}
+
+		if _, err := c.ResolveImageRef(ctx, ImageRef{Image: tag}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if hits.Load() == before {
+			t.Error("expected registry to be queried after TTL expiry")
+		}
+	})
+
+	t.Run("digest references bypass the cache", func(t *testing.T) {
+		img := ImageRef{Image: "nginx@" + digest, Digest: digest}
+		ref, err := c.ResolveImageRef(ctx, img)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ref != img {
+			t.Errorf("expected %v, got %v", img, ref)
+		}
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		missing := strings.TrimSuffix(tag, ":v1") + ":missing"
+		if _, err := c.ResolveImageRef(ctx, ImageRef{Image: missing}); err == nil {
+			t.Fatal("expected error for missing tag")
+		}
+		if _, ok := c.Lookup(missing); ok {
+			t.Error("expected failed resolution not to be cached")
+		}
+	})
+}
+
+func TestCachingResolverManifestDigest(t *testing.T) {
+	ctx := context.Background()
+	tag, digest, hits := newTestRegistry(t)
+
+	idx, err := random.Index(64, 1, 2)
+	if err != nil {
+		t.Fatalf("creating random index: %v", err)
+	}
+	multiArch := strings.TrimSuffix(tag, "app:v1") + "multi:v1"
+	ref, err := name.ParseReference(multiArch)
+	if err != nil {
+		t.Fatalf("parsing reference: %v", err)
+	}
+	if err := remote.WriteIndex(ref, idx); err != nil {
+		t.Fatalf("pushing index: %v", err)
+	}
+	indexDigest, err := idx.Digest()
+	if err != nil {
+		t.Fatalf("getting digest: %v", err)
+	}
+
+	c := NewCachingResolver(NewResolver(), time.Minute)
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: tag, want: digest},
+		{image: multiArch, want: indexDigest.String()},
+		{image: "nginx@" + digest, want: digest},
+	}
+	for _, tt := range tests {
+		got, err := c.ResolveManifestDigest(ctx, tt.image)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.image, err)
+		}
+		if got != tt.want {
+			t.Errorf("ResolveManifestDigest(%s) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+
+	before := hits.Load()
+	if got, ok := c.LookupManifest(multiArch); !ok || got != indexDigest.String() {
+		t.Errorf("expected LookupManifest to return %q, got %q (ok=%v)", indexDigest, got, ok)
+	}
+	if _, err := c.ResolveManifestDigest(ctx, multiArch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hits.Load() != before {
+		t.Error("expected cached lookup")
+	}
+	// Manifest digests are cached apart from platform digests
+	if _, ok := c.Lookup(multiArch); ok {
+		t.Error("expected no platform digest to be cached")
+	}
+}
+
+func TestCachingResolverSweep(t *testing.T) {
+	ctx := context.Background()
+	tag, _, _ := newTestRegistry(t)
+
+	now := time.Now()
+	c := NewCachingResolver(NewResolver(), time.Minute)
+	c.now = func() time.Time { return now }
+
+	if _, err := c.ResolveImageRef(ctx, ImageRef{Image: tag}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(30 * time.Second)
+	if _, err := c.ResolveManifestDigest(ctx, tag); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if removed := c.Sweep(); removed != 0 || c.Len() != 2 {
+		t.Errorf("expected fresh entries to be kept, removed %d, %d left", removed, c.Len())
+	}
+	now = now.Add(45 * time.Second)
+	if removed := c.Sweep(); removed != 1 || c.Len() != 1 {
+		t.Errorf("expected the expired entry to be removed, removed %d, %d left", removed, c.Len())
+	}
+	if _, ok := c.LookupManifest(tag); !ok {
+		t.Error("expected the fresh manifest digest to be kept")
+	}
+}
@@ -4,12 +4,20 @@ package imageref
 
 import (
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	corev1 "k8s.io/api/core/v1"
 )
 
+// AnnotationScannedDigests records the digests scanned for images pinned at admission,
+// as a JSON object of pinned image reference to the digest of the image for the target
+// platform. Multi-arch images are pinned to their index digest so every node can pull
+// them, but scanned, like unpinned images, by their platform digest.
+const AnnotationScannedDigests = "scans.aquasec.community/scanned-digests"
+
 // ImageRef represents a container image reference with its digest.
 type ImageRef struct {
 	// Image is the full image reference (e.g., nginx:latest or registry.example.com/app@sha256:abc...)
@@ -55,9 +63,25 @@ func ExtractFromPodSpec(spec *corev1.PodSpec) []ImageRef {
 	return images
 }
 
-// ExtractFromPod extracts all unique image references from a Pod.
+// ExtractFromPod extracts all unique image references from a Pod. Images pinned at
+// admission are returned as the tag they were pinned from, with the digest recorded in
+// AnnotationScannedDigests.
 func ExtractFromPod(pod *corev1.Pod) []ImageRef {
-	return ExtractFromPodSpec(&pod.Spec)
+	images := ExtractFromPodSpec(&pod.Spec)
+	data := pod.Annotations[AnnotationScannedDigests]
+	if data == "" {
+		return images
+	}
+	scanned := map[string]string{}
+	if err := json.Unmarshal([]byte(data), &scanned); err != nil {
+		return images
+	}
+	for i, img := range images {
+		if digest, ok := scanned[img.Image]; ok && img.Digest != "" {
+			images[i] = ImageRef{Image: strings.TrimSuffix(img.Image, "@"+img.Digest), Digest: digest}
+		}
+	}
+	return images
 }
 
 // ScanName generates a deterministic name for an ImageScan CR based on the image reference.
@@ -81,3 +105,41 @@ func HashString(s string) string {
 	h := sha256.Sum256([]byte(s))
 	return fmt.Sprintf("%x", h)
 }
+
+// NormalizePrefix returns the canonical form of a registry or registry and repository
+// prefix, so that docker.io matches index.docker.io.
+func NormalizePrefix(entry string) (string, error) {
+	entry = strings.TrimSuffix(entry, "/")
+	if !strings.Contains(entry, "/") {
+		registry, err := name.NewRegistry(entry)
+		if err != nil {
+			return "", err
+		}
+		return registry.Name(), nil
+	}
+	registry, path, _ := strings.Cut(entry, "/")
+	reg, err := name.NewRegistry(registry)
+	if err != nil {
+		return "", err
+	}
+	return reg.Name() + "/" + path, nil
+}
+
+// Repository returns the fully-qualified repository of an image reference, without tag
+// or digest, so that nginx, docker.io/library/nginx and nginx:1.25 all match. References
+// that can't be parsed are returned as is.
+func Repository(image string) string {
+	if ref, err := name.ParseReference(image); err == nil {
+		return ref.Context().Name()
+	}
+	if repo, err := name.NewRepository(image); err == nil {
+		return repo.Name()
+	}
+	return image
+}
+
+// HasPrefix returns true if repository, as returned by name.Repository.Name, is under a
+// prefix normalized by NormalizePrefix.
+func HasPrefix(repository, prefix string) bool {
+	return repository == prefix || strings.HasPrefix(repository, prefix+"/")
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestExtractFromPodSpec(t *testing.T) {
@@ -209,6 +210,27 @@ func TestHashString(t *testing.T) {
 	})
 }
 
+func TestRepository(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{image: "nginx", expected: "index.docker.io/library/nginx"},
+		{image: "docker.io/library/nginx:1.25", expected: "index.docker.io/library/nginx"},
+		{image: "registry.example.com/team/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", expected: "registry.example.com/team/app"},
+		{image: "registry.example.com/team/app", expected: "registry.example.com/team/app"},
+		{image: "Not An Image", expected: "Not An Image"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if result := Repository(tt.image); result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestExtractFromPod(t *testing.T) {
 	pod := &corev1.Pod{
 		Spec: corev1.PodSpec{
@@ -229,3 +251,33 @@ func TestExtractFromPod(t *testing.T) {
 		t.Errorf("expected image nginx:latest, got %q", result[0].Image)
 	}
 }
+
+func TestExtractFromPinnedPod(t *testing.T) {
+	const (
+		index    = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		platform = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			AnnotationScannedDigests: `{"nginx:1.27@` + index + `": "` + platform + `"}`,
+		}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Image: "nginx:1.27@" + index},
+				{Name: "sidecar", Image: "busybox@" + index},
+			},
+		},
+	}
+
+	result := ExtractFromPod(pod)
+
+	expected := []ImageRef{{Image: "nginx:1.27", Digest: platform}, {Image: "busybox@" + index, Digest: index}}
+	if len(result) != len(expected) {
+		t.Fatalf("expected %d images, got %d", len(expected), len(result))
+	}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], result[i])
+		}
+	}
+}
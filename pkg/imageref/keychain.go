@@ -0,0 +1,172 @@
+package imageref
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// dockerConfigEntry is a single registry entry in a .dockerconfigjson or .dockercfg secret
+type dockerConfigEntry struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	RegistryToken string `json:"registrytoken,omitempty"`
+}
+
+// dockerConfigJSON is the format stored under the .dockerconfigjson key
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+// secretKeychain is an authn.Keychain backed by Kubernetes image pull secrets.
+type secretKeychain struct {
+	// entries holds credentials keyed by normalized registry host, in secret order
+	entries []keychainEntry
+}
+
+type keychainEntry struct {
+	registry string
+	config   authn.AuthConfig
+}
+
+// NewSecretKeychain builds an authn.Keychain from Kubernetes image pull secrets.
+// Both kubernetes.io/dockerconfigjson and kubernetes.io/dockercfg secrets are supported;
+// secrets of other types are ignored. When several secrets provide credentials for the
+// same registry, the first one wins, matching kubelet behavior.
+// Registries without matching credentials resolve to anonymous access.
+func NewSecretKeychain(secrets []corev1.Secret) (authn.Keychain, error) {
+	kc := &secretKeychain{}
+	for _, secret := range secrets {
+		var auths map[string]dockerConfigEntry
+
+		switch secret.Type {
+		case corev1.SecretTypeDockerConfigJson:
+			var cfg dockerConfigJSON
+			if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &cfg); err != nil {
+				return nil, fmt.Errorf("parsing secret %s/%s: %w", secret.Namespace, secret.Name, err)
+			}
+			auths = cfg.Auths
+		case corev1.SecretTypeDockercfg:
+			if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+				return nil, fmt.Errorf("parsing secret %s/%s: %w", secret.Namespace, secret.Name, err)
+			}
+		default:
+			continue
+		}
+
+		for registry, entry := range auths {
+			cfg, err := entry.authConfig()
+			if err != nil {
+				return nil, fmt.Errorf("parsing credentials for %q in secret %s/%s: %w",
+					registry, secret.Namespace, secret.Name, err)
+			}
+			kc.entries = append(kc.entries, keychainEntry{
+				registry: normalizeKeychainRegistry(registry),
+				config:   cfg,
+			})
+		}
+	}
+	return kc, nil
+}
+
+// PodKeychain builds a registry keychain from the pod's imagePullSecrets and those of
+// its service account. Missing secrets or service accounts are skipped, as the kubelet does.
+func PodKeychain(ctx context.Context, reader client.Reader, pod *corev1.Pod) (authn.Keychain, error) {
+	secretNames := make([]string, 0, len(pod.Spec.ImagePullSecrets))
+	for _, ref := range pod.Spec.ImagePullSecrets {
+		secretNames = append(secretNames, ref.Name)
+	}
+
+	saName := pod.Spec.ServiceAccountName
+	if saName == "" {
+		saName = "default"
+	}
+	var sa corev1.ServiceAccount
+	err := reader.Get(ctx, types.NamespacedName{Name: saName, Namespace: pod.Namespace}, &sa)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("getting service account %s: %w", saName, err)
+	}
+	for _, ref := range sa.ImagePullSecrets {
+		secretNames = append(secretNames, ref.Name)
+	}
+
+	var secrets []corev1.Secret
+	seen := make(map[string]bool)
+	for _, secretName := range secretNames {
+		if secretName == "" || seen[secretName] {
+			continue
+		}
+		seen[secretName] = true
+
+		var secret corev1.Secret
+		if err := reader.Get(ctx, types.NamespacedName{Name: secretName, Namespace: pod.Namespace}, &secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("getting pull secret %s: %w", secretName, err)
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return NewSecretKeychain(secrets)
+}
+
+// Resolve implements authn.Keychain.
+func (k *secretKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	registry := normalizeKeychainRegistry(target.RegistryStr())
+	for _, entry := range k.entries {
+		if entry.registry == registry {
+			return authn.FromConfig(entry.config), nil
+		}
+	}
+	return authn.Anonymous, nil
+}
+
+// authConfig converts a docker config entry into an authn.AuthConfig,
+// decoding the base64 "auth" field when username/password are not set.
+func (e dockerConfigEntry) authConfig() (authn.AuthConfig, error) {
+	cfg := authn.AuthConfig{
+		Username:      e.Username,
+		Password:      e.Password,
+		IdentityToken: e.IdentityToken,
+		RegistryToken: e.RegistryToken,
+	}
+	if cfg.Username == "" && cfg.Password == "" && e.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(e.Auth)
+		if err != nil {
+			return authn.AuthConfig{}, fmt.Errorf("decoding auth field: %w", err)
+		}
+		user, pass, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return authn.AuthConfig{}, fmt.Errorf("auth field is not in user:password format")
+		}
+		cfg.Username = user
+		cfg.Password = pass
+	}
+	return cfg, nil
+}
+
+// normalizeKeychainRegistry reduces a docker config key to a bare registry host.
+// Keys may be URLs (https://index.docker.io/v1/) or hosts with paths; Docker Hub
+// aliases are collapsed to index.docker.io.
+func normalizeKeychainRegistry(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	if idx := strings.Index(registry, "/"); idx != -1 {
+		registry = registry[:idx]
+	}
+	if registry == "docker.io" || registry == "registry-1.docker.io" {
+		registry = "index.docker.io"
+	}
+	return registry
+}
@@ -0,0 +1,166 @@
+package imageref
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewSecretKeychain(t *testing.T) {
+	encodedAuth := base64.StdEncoding.EncodeToString([]byte("hub-user:hub-pass"))
+
+	secrets := []corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "regcred", Namespace: "default"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"username":"user","password":"pass"}}}`),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default"},
+			Type:       corev1.SecretTypeDockercfg,
+			Data: map[string][]byte{
+				corev1.DockerConfigKey: []byte(`{"https://index.docker.io/v1/":{"auth":"` + encodedAuth + `"}}`),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "shadowed", Namespace: "default"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"username":"other","password":"other"}}}`),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "default"},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"foo": []byte("not json")},
+		},
+	}
+
+	kc, err := NewSecretKeychain(secrets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		image        string
+		wantUser     string
+		wantPassword string
+		wantAnon     bool
+	}{
+		{
+			name:         "dockerconfigjson registry",
+			image:        "registry.example.com/app:v1",
+			wantUser:     "user",
+			wantPassword: "pass",
+		},
+		{
+			name:         "dockercfg docker hub alias",
+			image:        "nginx:latest",
+			wantUser:     "hub-user",
+			wantPassword: "hub-pass",
+		},
+		{
+			name:     "unknown registry is anonymous",
+			image:    "ghcr.io/org/app:v1",
+			wantAnon: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := name.ParseReference(tt.image)
+			if err != nil {
+				t.Fatalf("parsing reference: %v", err)
+			}
+
+			auth, err := kc.Resolve(ref.Context())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.wantAnon {
+				if auth != authn.Anonymous {
+					t.Errorf("expected anonymous authenticator, got %v", auth)
+				}
+				return
+			}
+
+			cfg, err := auth.Authorization()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Username != tt.wantUser {
+				t.Errorf("expected username %q, got %q", tt.wantUser, cfg.Username)
+			}
+			if cfg.Password != tt.wantPassword {
+				t.Errorf("expected password %q, got %q", tt.wantPassword, cfg.Password)
+			}
+		})
+	}
+}
+
+func TestNewSecretKeychainInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret corev1.Secret
+	}{
+		{
+			name: "malformed json",
+			secret: corev1.Secret{
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{`)},
+			},
+		},
+		{
+			name: "auth not base64",
+			secret: corev1.Secret{
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"r.io":{"auth":"!!!"}}}`)},
+			},
+		},
+		{
+			name: "auth missing separator",
+			secret: corev1.Secret{
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(
+					`{"auths":{"r.io":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("nocolon")) + `"}}}`)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSecretKeychain([]corev1.Secret{tt.secret}); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestNormalizeKeychainRegistry(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "registry.example.com", expected: "registry.example.com"},
+		{input: "https://registry.example.com/", expected: "registry.example.com"},
+		{input: "https://index.docker.io/v1/", expected: "index.docker.io"},
+		{input: "docker.io", expected: "index.docker.io"},
+		{input: "registry.example.com:5000/path", expected: "registry.example.com:5000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := normalizeKeychainRegistry(tt.input); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -41,7 +41,8 @@ func NewResolver(opts ...remote.Option) *Resolver {
 // If the image already has a digest, it returns that digest.
 // For tag-based references, it queries the registry to get the digest.
 // For multi-arch images (index), it resolves to the linux/amd64 manifest digest.
-func (r *Resolver) ResolveDigest(ctx context.Context, imageRef string) (string, error) {
+// Additional options (e.g. per-call authentication) are applied after the resolver's own.
+func (r *Resolver) ResolveDigest(ctx context.Context, imageRef string, extraOpts ...remote.Option) (string, error) {
 	ctx, span := tracing.StartSpan(ctx, "imageref.ResolveDigest",
 		trace.WithAttributes(
 			tracing.AttrImageName.String(imageRef),
@@ -74,6 +75,7 @@ func (r *Resolver) ResolveDigest(ctx context.Context, imageRef string) (string,
 
 	// Add context to options
 	opts := append([]remote.Option{remote.WithContext(ctx)}, r.Options...)
+	opts = append(opts, extraOpts...)
 
 	// Try to get as an index (multi-arch image) first
 	digest, err := r.fetchIndex(ctx, ref, opts)
@@ -178,9 +180,53 @@ func (r *Resolver) resolveFromIndex(idx v1.ImageIndex) (string, error) {
 	return "", fmt.Errorf("no manifest found for platform %s/%s", r.Platform.OS, r.Platform.Architecture)
 }
 
+// ResolveManifestDigest resolves an image reference to the digest of the manifest it
+// points at. Unlike ResolveDigest, multi-arch images resolve to their index digest, which
+// every node can pull whatever its platform.
+func (r *Resolver) ResolveManifestDigest(ctx context.Context, imageRef string, extraOpts ...remote.Option) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "imageref.ResolveManifestDigest",
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodHead,
+			tracing.AttrImageName.String(imageRef),
+		))
+	defer span.End()
+
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to parse image reference")
+		return "", fmt.Errorf("parsing image reference: %w", err)
+	}
+	if digestRef, ok := ref.(name.Digest); ok {
+		span.SetAttributes(attribute.Bool("already_resolved", true))
+		return digestRef.DigestStr(), nil
+	}
+
+	opts := append([]remote.Option{remote.WithContext(ctx)}, r.Options...)
+	opts = append(opts, extraOpts...)
+
+	// Not every registry answers HEAD requests for manifests
+	desc, err := remote.Head(ref, opts...)
+	if err != nil {
+		got, getErr := remote.Get(ref, opts...)
+		if getErr != nil {
+			span.RecordError(getErr)
+			span.SetStatus(codes.Error, "failed to fetch manifest")
+			return "", fmt.Errorf("fetching manifest: %w", getErr)
+		}
+		desc = &got.Descriptor
+	}
+
+	span.SetAttributes(
+		tracing.AttrImageDigest.String(desc.Digest.String()),
+		attribute.Bool("multi_arch", desc.MediaType.IsIndex()),
+	)
+	return desc.Digest.String(), nil
+}
+
 // ResolveImageRef resolves an ImageRef, populating the Digest field if empty.
 // Returns a new ImageRef with the resolved digest.
-func (r *Resolver) ResolveImageRef(ctx context.Context, img ImageRef) (ImageRef, error) {
+func (r *Resolver) ResolveImageRef(ctx context.Context, img ImageRef, extraOpts ...remote.Option) (ImageRef, error) {
 	ctx, span := tracing.StartSpan(ctx, "imageref.ResolveImageRef",
 		trace.WithAttributes(
 			tracing.AttrImageName.String(img.Image),
@@ -196,7 +242,7 @@ func (r *Resolver) ResolveImageRef(ctx context.Context, img ImageRef) (ImageRef,
 		return img, nil
 	}
 
-	digest, err := r.ResolveDigest(ctx, img.Image)
+	digest, err := r.ResolveDigest(ctx, img.Image, extraOpts...)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "failed to resolve digest")
@@ -0,0 +1,129 @@
+package policy
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+)
+
+// ActiveExceptions returns the unexpired exceptions that cover an image in namespace.
+// An empty namespace (a ClusterImageScan) is only covered by exceptions that apply
+// to every namespace.
+func ActiveExceptions(exceptions []securityv1alpha1.ScanException, namespace, image, digest string, now time.Time) []securityv1alpha1.ScanException {
+	var active []securityv1alpha1.ScanException
+	for _, e := range exceptions {
+		if e.Spec.Expires.Time.After(now) && Covers(&e, namespace, image, digest) {
+			active = append(active, e)
+		}
+	}
+	return active
+}
+
+// Covers returns true if the exception's namespaces, images and digests select an image
+// in namespace, regardless of whether the exception has expired.
+func Covers(exception *securityv1alpha1.ScanException, namespace, image, digest string) bool {
+	spec := exception.Spec
+	if len(spec.Namespaces) > 0 && !slices.Contains(spec.Namespaces, namespace) {
+		return false
+	}
+	return coversImage(spec, image, digest)
+}
+
+// WaivesImage returns true if any exception waives every finding in the image, i.e.
+// lists the image or digest without restricting it to specific CVEs.
+func WaivesImage(exceptions []securityv1alpha1.ScanException) bool {
+	for _, e := range exceptions {
+		if len(e.Spec.CVEs) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Waive returns the vulnerability summary with the findings waived by exceptions
+// subtracted. CVE exceptions are matched against vulnerabilities, the per-CVE details
+// of the scan. The exceptions must already be filtered by ActiveExceptions.
+func Waive(summary *securityv1alpha1.VulnerabilitySummary, vulnerabilities []securityv1alpha1.Vulnerability, exceptions []securityv1alpha1.ScanException) *securityv1alpha1.VulnerabilitySummary {
+	if len(exceptions) == 0 {
+		return summary
+	}
+	if WaivesImage(exceptions) {
+		return &securityv1alpha1.VulnerabilitySummary{}
+	}
+
+	matched, _ := splitWaived(vulnerabilities, exceptions)
+	return Subtract(summary, matched)
+}
+
+// Unwaived returns the vulnerabilities that exceptions don't waive. The exceptions must
+// already be filtered by ActiveExceptions.
+func Unwaived(vulnerabilities []securityv1alpha1.Vulnerability, exceptions []securityv1alpha1.ScanException) []securityv1alpha1.Vulnerability {
+	if WaivesImage(exceptions) {
+		return nil
+	}
+	_, unwaived := splitWaived(vulnerabilities, exceptions)
+	return unwaived
+}
+
+// splitWaived splits vulnerabilities into those the CVE exceptions waive and the others.
+func splitWaived(vulnerabilities []securityv1alpha1.Vulnerability, exceptions []securityv1alpha1.ScanException) (waived, unwaived []securityv1alpha1.Vulnerability) {
+	cves := map[string]bool{}
+	for _, e := range exceptions {
+		for _, cve := range e.Spec.CVEs {
+			cves[strings.ToUpper(cve)] = true
+		}
+	}
+	for _, v := range vulnerabilities {
+		if cves[strings.ToUpper(v.ID)] {
+			waived = append(waived, v)
+		} else {
+			unwaived = append(unwaived, v)
+		}
+	}
+	return waived, unwaived
+}
+
+// Subtract returns the vulnerability summary with vulnerabilities subtracted from the
+// counts of their severity. Counts never drop below zero.
+func Subtract(summary *securityv1alpha1.VulnerabilitySummary, vulnerabilities []securityv1alpha1.Vulnerability) *securityv1alpha1.VulnerabilitySummary {
+	var counts securityv1alpha1.VulnerabilitySummary
+	if summary != nil {
+		counts = *summary
+	}
+	for _, v := range vulnerabilities {
+		switch strings.ToLower(v.Severity) {
+		case "critical":
+			counts.Critical = max(counts.Critical-1, 0)
+		case "high":
+			counts.High = max(counts.High-1, 0)
+		case "medium":
+			counts.Medium = max(counts.Medium-1, 0)
+		case "low":
+			counts.Low = max(counts.Low-1, 0)
+		default:
+			counts.Unknown = max(counts.Unknown-1, 0)
+		}
+	}
+	return &counts
+}
+
+// coversImage returns true if the exception's image and digest selectors match the image.
+// An exception without image or digest selectors covers every image.
+func coversImage(spec securityv1alpha1.ScanExceptionSpec, image, digest string) bool {
+	if len(spec.Images) == 0 && len(spec.Digests) == 0 {
+		return true
+	}
+	if digest != "" && slices.Contains(spec.Digests, digest) {
+		return true
+	}
+	repo := imageref.Repository(image)
+	for _, img := range spec.Images {
+		if imageref.Repository(img) == repo {
+			return true
+		}
+	}
+	return false
+}
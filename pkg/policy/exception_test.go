@@ -0,0 +1,129 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+const exceptionDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestActiveExceptions(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	exception := func(name string, expires time.Time, spec securityv1alpha1.ScanExceptionSpec) securityv1alpha1.ScanException {
+		spec.Expires = metav1.NewTime(expires)
+		return securityv1alpha1.ScanException{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec}
+	}
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		exception securityv1alpha1.ScanException
+		namespace string
+		image     string
+		want      bool
+	}{
+		{
+			name:      "cve exception applies everywhere",
+			exception: exception("cve", later, securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}}),
+			namespace: "team-a",
+			image:     "nginx:1.25",
+			want:      true,
+		},
+		{
+			name:      "expired",
+			exception: exception("cve", now.Add(-time.Hour), securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}}),
+			namespace: "team-a",
+			image:     "nginx:1.25",
+		},
+		{
+			name:      "other namespace",
+			exception: exception("ns", later, securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}, Namespaces: []string{"team-b"}}),
+			namespace: "team-a",
+			image:     "nginx:1.25",
+		},
+		{
+			name:      "namespaced exception skips cluster scans",
+			exception: exception("ns", later, securityv1alpha1.ScanExceptionSpec{CVEs: []string{"CVE-2024-0001"}, Namespaces: []string{"team-a"}}),
+			image:     "nginx:1.25",
+		},
+		{
+			name:      "image repository matches any tag",
+			exception: exception("image", later, securityv1alpha1.ScanExceptionSpec{Images: []string{"docker.io/library/nginx"}}),
+			namespace: "team-a",
+			image:     "nginx:1.25",
+			want:      true,
+		},
+		{
+			name:      "other image",
+			exception: exception("image", later, securityv1alpha1.ScanExceptionSpec{Images: []string{"redis"}}),
+			namespace: "team-a",
+			image:     "nginx:1.25",
+		},
+		{
+			name:      "digest",
+			exception: exception("digest", later, securityv1alpha1.ScanExceptionSpec{Digests: []string{exceptionDigest}}),
+			namespace: "team-a",
+			image:     "registry.example.com/app:v1",
+			want:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active := ActiveExceptions([]securityv1alpha1.ScanException{tt.exception}, tt.namespace, tt.image, exceptionDigest, now)
+			if got := len(active) == 1; got != tt.want {
+				t.Errorf("expected active=%v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWaive(t *testing.T) {
+	summary := &securityv1alpha1.VulnerabilitySummary{Critical: 1, High: 2, Low: 1}
+	vulnerabilities := []securityv1alpha1.Vulnerability{
+		{ID: "CVE-2024-0001", Severity: "critical"},
+		{ID: "CVE-2024-0002", Severity: "high"},
+		{ID: "CVE-2024-0003", Severity: "high"},
+		{ID: "CVE-2024-0004", Severity: "low"},
+	}
+
+	tests := []struct {
+		name       string
+		exceptions []securityv1alpha1.ScanException
+		want       securityv1alpha1.VulnerabilitySummary
+	}{
+		{
+			name: "no exceptions",
+			want: *summary,
+		},
+		{
+			name: "cves",
+			exceptions: []securityv1alpha1.ScanException{
+				{Spec: securityv1alpha1.ScanExceptionSpec{CVEs: []string{"cve-2024-0001", "CVE-2024-0003"}}},
+			},
+			want: securityv1alpha1.VulnerabilitySummary{High: 1, Low: 1},
+		},
+		{
+			name: "whole image",
+			exceptions: []securityv1alpha1.ScanException{
+				{Spec: securityv1alpha1.ScanExceptionSpec{Digests: []string{exceptionDigest}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Waive(summary, vulnerabilities, tt.exceptions)
+			if *got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, *got)
+			}
+		})
+	}
+	if summary.Critical != 1 {
+		t.Error("expected the original summary to be left unchanged")
+	}
+}
@@ -0,0 +1,137 @@
+// Package policy evaluates image scan results against ScanPolicy and
+// ClusterScanPolicy thresholds.
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+const (
+	// KindScanPolicy is the kind of namespaced scan policies
+	KindScanPolicy = "ScanPolicy"
+	// KindClusterScanPolicy is the kind of cluster-scoped scan policies
+	KindClusterScanPolicy = "ClusterScanPolicy"
+	// KindAquaAssurancePolicy identifies assurance policies managed in Aqua
+	KindAquaAssurancePolicy = "AquaAssurancePolicy"
+)
+
+// Policy is a scan policy of either kind, flattened for evaluation.
+type Policy struct {
+	// Kind is ScanPolicy or ClusterScanPolicy
+	Kind string
+	// Namespace is empty for ClusterScanPolicy
+	Namespace string
+	Name      string
+	Spec      securityv1alpha1.ScanPolicySpec
+}
+
+// Ref returns a human-readable reference to the policy,
+// e.g. ClusterScanPolicy/baseline or ScanPolicy/team-a/strict.
+func (p Policy) Ref() string {
+	if p.Namespace == "" {
+		return p.Kind + "/" + p.Name
+	}
+	return p.Kind + "/" + p.Namespace + "/" + p.Name
+}
+
+// FromScanPolicies converts namespaced and cluster-scoped policies into Policy values.
+// Cluster policies come first so that evaluation order is stable.
+func FromScanPolicies(cluster []securityv1alpha1.ClusterScanPolicy, namespaced []securityv1alpha1.ScanPolicy) []Policy {
+	policies := make([]Policy, 0, len(cluster)+len(namespaced))
+	for _, p := range cluster {
+		policies = append(policies, Policy{
+			Kind: KindClusterScanPolicy,
+			Name: p.Name,
+			Spec: p.Spec,
+		})
+	}
+	for _, p := range namespaced {
+		policies = append(policies, Policy{
+			Kind:      KindScanPolicy,
+			Namespace: p.Namespace,
+			Name:      p.Name,
+			Spec:      p.Spec,
+		})
+	}
+	return policies
+}
+
+// Result is the outcome of evaluating scan results against a set of policies.
+type Result struct {
+	// Passed is true when no policy was violated
+	Passed bool
+	// Policy is the reference of the first violated policy
+	Policy string
+	// Message describes the violation, or summarizes the evaluation when passed
+	Message string
+}
+
+// Evaluate checks a vulnerability and finding summary against every policy. An image
+// must satisfy all applicable policies; the first violation found is reported. Malware
+// and private keys are checked before vulnerabilities, so they block an image whatever
+// its vulnerability counts. A nil summary is treated as having no vulnerabilities or findings.
+func Evaluate(summary *securityv1alpha1.VulnerabilitySummary, findings *securityv1alpha1.FindingSummary, policies []Policy) Result {
+	var counts securityv1alpha1.VulnerabilitySummary
+	if summary != nil {
+		counts = *summary
+	}
+	var found securityv1alpha1.FindingSummary
+	if findings != nil {
+		found = *findings
+	}
+
+	for _, p := range policies {
+		if p.Spec.BlockMalware && found.Malware > 0 {
+			return Result{Policy: p.Ref(), Message: fmt.Sprintf("%d malware findings are not allowed", found.Malware)}
+		}
+		if p.Spec.BlockPrivateKeys && found.PrivateKeys > 0 {
+			return Result{Policy: p.Ref(), Message: fmt.Sprintf("%d embedded private keys are not allowed", found.PrivateKeys)}
+		}
+		if msg := violation("sensitive data findings", found.SensitiveData, p.Spec.MaxSensitiveData); msg != "" {
+			return Result{Policy: p.Ref(), Message: msg}
+		}
+		if msg := violation("misconfigurations", found.Misconfigurations, p.Spec.MaxMisconfigurations); msg != "" {
+			return Result{Policy: p.Ref(), Message: msg}
+		}
+		if msg := violation("critical vulnerabilities", counts.Critical, p.Spec.MaxCritical); msg != "" {
+			return Result{Policy: p.Ref(), Message: msg}
+		}
+		if msg := violation("high vulnerabilities", counts.High, p.Spec.MaxHigh); msg != "" {
+			return Result{Policy: p.Ref(), Message: msg}
+		}
+		if msg := violation("medium vulnerabilities", counts.Medium, p.Spec.MaxMedium); msg != "" {
+			return Result{Policy: p.Ref(), Message: msg}
+		}
+	}
+
+	return Result{
+		Passed:  true,
+		Message: fmt.Sprintf("Image satisfies %d scan policies", len(policies)),
+	}
+}
+
+// EvaluateAssurance returns Aqua's assurance policy verdict for an image. failed names
+// the assurance policies the image failed; the result's Policy references them all.
+func EvaluateAssurance(disallowed bool, failed []string) Result {
+	if !disallowed {
+		return Result{Passed: true, Message: "Image allowed by Aqua assurance policies"}
+	}
+	if len(failed) == 0 {
+		return Result{Policy: KindAquaAssurancePolicy, Message: "Image disallowed by Aqua assurance policies"}
+	}
+	return Result{
+		Policy:  KindAquaAssurancePolicy + "/" + strings.Join(failed, ","),
+		Message: fmt.Sprintf("Image failed Aqua assurance policies: %s", strings.Join(failed, ", ")),
+	}
+}
+
+// violation returns a message if count exceeds the threshold, or "" otherwise.
+func violation(what string, count int, max *int) string {
+	if max == nil || count <= *max {
+		return ""
+	}
+	return fmt.Sprintf("%d %s exceed the maximum of %d", count, what, *max)
+}
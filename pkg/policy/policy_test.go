@@ -0,0 +1,195 @@
+package policy
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestEvaluate(t *testing.T) {
+	baseline := Policy{
+		Kind: KindClusterScanPolicy,
+		Name: "baseline",
+		Spec: securityv1alpha1.ScanPolicySpec{MaxCritical: intPtr(0)},
+	}
+	strict := Policy{
+		Kind:      KindScanPolicy,
+		Namespace: "team-a",
+		Name:      "strict",
+		Spec: securityv1alpha1.ScanPolicySpec{
+			MaxCritical: intPtr(0),
+			MaxHigh:     intPtr(2),
+			MaxMedium:   intPtr(10),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		summary    *securityv1alpha1.VulnerabilitySummary
+		findings   *securityv1alpha1.FindingSummary
+		policies   []Policy
+		wantPassed bool
+		wantPolicy string
+	}{
+		{
+			name:       "no policies",
+			summary:    &securityv1alpha1.VulnerabilitySummary{Critical: 5},
+			wantPassed: true,
+		},
+		{
+			name:       "nil summary passes",
+			policies:   []Policy{strict},
+			wantPassed: true,
+		},
+		{
+			name:       "within thresholds",
+			summary:    &securityv1alpha1.VulnerabilitySummary{High: 2, Medium: 10, Low: 100},
+			policies:   []Policy{baseline, strict},
+			wantPassed: true,
+		},
+		{
+			name:       "critical exceeds cluster policy",
+			summary:    &securityv1alpha1.VulnerabilitySummary{Critical: 1},
+			policies:   []Policy{baseline, strict},
+			wantPolicy: "ClusterScanPolicy/baseline",
+		},
+		{
+			name:       "high exceeds namespaced policy",
+			summary:    &securityv1alpha1.VulnerabilitySummary{High: 3},
+			policies:   []Policy{baseline, strict},
+			wantPolicy: "ScanPolicy/team-a/strict",
+		},
+		{
+			name:       "medium exceeds namespaced policy",
+			summary:    &securityv1alpha1.VulnerabilitySummary{Medium: 11},
+			policies:   []Policy{strict},
+			wantPolicy: "ScanPolicy/team-a/strict",
+		},
+		{
+			name:     "malware blocks whatever the vulnerabilities",
+			findings: &securityv1alpha1.FindingSummary{Malware: 1},
+			policies: []Policy{{
+				Kind: KindClusterScanPolicy,
+				Name: "no-malware",
+				Spec: securityv1alpha1.ScanPolicySpec{BlockMalware: true},
+			}},
+			wantPolicy: "ClusterScanPolicy/no-malware",
+		},
+		{
+			name:     "private keys",
+			findings: &securityv1alpha1.FindingSummary{SensitiveData: 2, PrivateKeys: 1},
+			policies: []Policy{{
+				Kind: KindClusterScanPolicy,
+				Name: "no-keys",
+				Spec: securityv1alpha1.ScanPolicySpec{BlockPrivateKeys: true},
+			}},
+			wantPolicy: "ClusterScanPolicy/no-keys",
+		},
+		{
+			name:     "sensitive data without private keys",
+			findings: &securityv1alpha1.FindingSummary{SensitiveData: 2},
+			policies: []Policy{{
+				Kind: KindClusterScanPolicy,
+				Name: "no-keys",
+				Spec: securityv1alpha1.ScanPolicySpec{BlockPrivateKeys: true, MaxSensitiveData: intPtr(2)},
+			}},
+			wantPassed: true,
+		},
+		{
+			name:     "misconfigurations exceed",
+			findings: &securityv1alpha1.FindingSummary{Misconfigurations: 4},
+			policies: []Policy{{
+				Kind: KindClusterScanPolicy,
+				Name: "hardened",
+				Spec: securityv1alpha1.ScanPolicySpec{MaxMisconfigurations: intPtr(3)},
+			}},
+			wantPolicy: "ClusterScanPolicy/hardened",
+		},
+		{
+			name:       "unset thresholds are unlimited",
+			summary:    &securityv1alpha1.VulnerabilitySummary{High: 50, Medium: 500},
+			policies:   []Policy{baseline},
+			wantPassed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Evaluate(tt.summary, tt.findings, tt.policies)
+			if result.Passed != tt.wantPassed {
+				t.Errorf("expected passed=%v, got %v (%s)", tt.wantPassed, result.Passed, result.Message)
+			}
+			if result.Policy != tt.wantPolicy {
+				t.Errorf("expected policy %q, got %q", tt.wantPolicy, result.Policy)
+			}
+			if result.Message == "" {
+				t.Error("expected a message")
+			}
+		})
+	}
+}
+
+func TestFromScanPolicies(t *testing.T) {
+	cluster := []securityv1alpha1.ClusterScanPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "baseline"}},
+	}
+	namespaced := []securityv1alpha1.ScanPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "strict", Namespace: "team-a"}},
+	}
+
+	policies := FromScanPolicies(cluster, namespaced)
+	if len(policies) != 2 {
+		t.Fatalf("expected 2 policies, got %d", len(policies))
+	}
+	if got := policies[0].Ref(); got != "ClusterScanPolicy/baseline" {
+		t.Errorf("expected cluster policy first, got %q", got)
+	}
+	if got := policies[1].Ref(); got != "ScanPolicy/team-a/strict" {
+		t.Errorf("expected namespaced policy second, got %q", got)
+	}
+}
+
+func TestEvaluateAssurance(t *testing.T) {
+	tests := []struct {
+		name        string
+		disallowed  bool
+		failed      []string
+		wantPassed  bool
+		wantPolicy  string
+		wantMessage string
+	}{
+		{
+			name:        "allowed",
+			wantPassed:  true,
+			wantMessage: "Image allowed by Aqua assurance policies",
+		},
+		{
+			name:        "disallowed by named policies",
+			disallowed:  true,
+			failed:      []string{"Default", "Production"},
+			wantPolicy:  "AquaAssurancePolicy/Default,Production",
+			wantMessage: "Image failed Aqua assurance policies: Default, Production",
+		},
+		{
+			name:        "disallowed without policy names",
+			disallowed:  true,
+			wantPolicy:  "AquaAssurancePolicy",
+			wantMessage: "Image disallowed by Aqua assurance policies",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := EvaluateAssurance(tt.disallowed, tt.failed)
+			if result.Passed != tt.wantPassed || result.Policy != tt.wantPolicy || result.Message != tt.wantMessage {
+				t.Errorf("unexpected result %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+// ruleCostLimit bounds the work a single rule evaluation may do
+const ruleCostLimit = 1_000_000
+
+// podVars, namespaceVars, imageVars and scanVars are the types of the rule variables, so
+// rules referring to fields that don't exist fail to compile
+type podVars struct {
+	Name           string            `cel:"name"`
+	Namespace      string            `cel:"namespace"`
+	Labels         map[string]string `cel:"labels"`
+	Annotations    map[string]string `cel:"annotations"`
+	OwnerKind      string            `cel:"ownerKind"`
+	ServiceAccount string            `cel:"serviceAccount"`
+}
+
+type namespaceVars struct {
+	Name   string            `cel:"name"`
+	Labels map[string]string `cel:"labels"`
+}
+
+type imageVars struct {
+	Reference  string `cel:"reference"`
+	Registry   string `cel:"registry"`
+	Repository string `cel:"repository"`
+	Tag        string `cel:"tag"`
+	Digest     string `cel:"digest"`
+}
+
+type scanVars struct {
+	Phase                   string       `cel:"phase"`
+	Vulnerabilities         severityVars `cel:"vulnerabilities"`
+	Fixable                 severityVars `cel:"fixable"`
+	Findings                findingVars  `cel:"findings"`
+	Disallowed              bool         `cel:"disallowed"`
+	FailedAssurancePolicies []string     `cel:"failedAssurancePolicies"`
+}
+
+type severityVars struct {
+	Critical int `cel:"critical"`
+	High     int `cel:"high"`
+	Medium   int `cel:"medium"`
+	Low      int `cel:"low"`
+	Unknown  int `cel:"unknown"`
+}
+
+type findingVars struct {
+	Malware           int `cel:"malware"`
+	SensitiveData     int `cel:"sensitiveData"`
+	PrivateKeys       int `cel:"privateKeys"`
+	Misconfigurations int `cel:"misconfigurations"`
+}
+
+var (
+	// ruleEnv declares the rule variables. namespace is reserved in CEL, so the pod's
+	// namespace is namespaceObject, as in ValidatingAdmissionPolicy.
+	ruleEnv = sync.OnceValues(func() (*cel.Env, error) {
+		return cel.NewEnv(
+			cel.Variable("pod", cel.ObjectType(celTypeName[podVars]())),
+			cel.Variable("namespaceObject", cel.ObjectType(celTypeName[namespaceVars]())),
+			cel.Variable("image", cel.ObjectType(celTypeName[imageVars]())),
+			cel.Variable("scan", cel.ObjectType(celTypeName[scanVars]())),
+			ext.Strings(),
+			cel.OptionalTypes(),
+			// After OptionalTypes, which needs the default type provider
+			ext.NativeTypes(reflect.TypeFor[podVars](), reflect.TypeFor[namespaceVars](),
+				reflect.TypeFor[imageVars](), reflect.TypeFor[scanVars](), ext.ParseStructTags(true)),
+		)
+	})
+
+	// programs caches compiled rules by expression
+	programs sync.Map
+)
+
+// CompileRule compiles a rule expression and checks that it evaluates to a bool.
+func CompileRule(expression string) (cel.Program, error) {
+	if prg, ok := programs.Load(expression); ok {
+		return prg.(cel.Program), nil
+	}
+
+	env, err := ruleEnv()
+	if err != nil {
+		return nil, fmt.Errorf("creating CEL environment: %w", err)
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if out := ast.OutputType(); !out.IsExactType(cel.BoolType) && !out.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression must evaluate to bool, not %s", out)
+	}
+	prg, err := env.Program(ast, cel.CostLimit(ruleCostLimit))
+	if err != nil {
+		return nil, err
+	}
+	programs.Store(expression, prg)
+	return prg, nil
+}
+
+// ValidateRules compiles every rule of a policy, returning an error per invalid rule.
+func ValidateRules(spec securityv1alpha1.ScanPolicySpec) field.ErrorList {
+	var errs field.ErrorList
+	rulesPath := field.NewPath("spec", "rules")
+	for i, rule := range spec.Rules {
+		if _, err := CompileRule(rule.Expression); err != nil {
+			errs = append(errs, field.Invalid(rulesPath.Index(i).Child("expression"), rule.Expression, err.Error()))
+		}
+	}
+	return errs
+}
+
+// WithRules returns the policies that have CEL rules.
+func WithRules(policies []Policy) []Policy {
+	var withRules []Policy
+	for _, p := range policies {
+		if len(p.Spec.Rules) > 0 {
+			withRules = append(withRules, p)
+		}
+	}
+	return withRules
+}
+
+// celTypeName returns the name ext.NativeTypes gives a struct type.
+func celTypeName[T any]() string {
+	t := reflect.TypeFor[T]()
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// RuleInput holds the variables CEL rules are evaluated with.
+type RuleInput map[string]any
+
+// NewRuleInput builds the rule variables for an image of a pod. namespace may be nil if
+// it could not be read. The vulnerability counts are those left once ScanExceptions and
+// OpenVEX are applied; scans evaluated before a policy had rules fall back to the raw
+// counts, and count every vulnerability as fixable, until they are re-evaluated.
+func NewRuleInput(pod *corev1.Pod, namespace *corev1.Namespace, image, digest string, status *securityv1alpha1.ImageScanStatus) RuleInput {
+	ownerKind := ""
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		ownerKind = owner.Kind
+	}
+	namespaceLabels := map[string]string{}
+	if namespace != nil && namespace.Labels != nil {
+		namespaceLabels = namespace.Labels
+	}
+
+	imageInput := imageVars{Reference: image, Digest: digest}
+	if ref, err := name.ParseReference(image); err == nil {
+		imageInput.Registry = ref.Context().RegistryStr()
+		imageInput.Repository = ref.Context().RepositoryStr()
+		if tag, ok := ref.(name.Tag); ok {
+			imageInput.Tag = tag.TagStr()
+		}
+	}
+
+	vulnerabilities := status.EffectiveVulnerabilities
+	if vulnerabilities == nil {
+		vulnerabilities = status.Vulnerabilities
+	}
+	fixable := status.Fixable
+	if fixable == nil {
+		fixable = vulnerabilities
+	}
+	var findings securityv1alpha1.FindingSummary
+	if status.Findings != nil {
+		findings = *status.Findings
+	}
+
+	return RuleInput{
+		"pod": podVars{
+			Name:           pod.Name,
+			Namespace:      pod.Namespace,
+			Labels:         stringMap(pod.Labels),
+			Annotations:    stringMap(pod.Annotations),
+			OwnerKind:      ownerKind,
+			ServiceAccount: pod.Spec.ServiceAccountName,
+		},
+		"namespaceObject": namespaceVars{
+			Name:   pod.Namespace,
+			Labels: namespaceLabels,
+		},
+		"image": imageInput,
+		"scan": scanVars{
+			Phase:           string(status.Phase),
+			Vulnerabilities: severityCounts(vulnerabilities),
+			Fixable:         severityCounts(fixable),
+			Findings: findingVars{
+				Malware:           findings.Malware,
+				SensitiveData:     findings.SensitiveData,
+				PrivateKeys:       findings.PrivateKeys,
+				Misconfigurations: findings.Misconfigurations,
+			},
+			Disallowed:              status.Disallowed,
+			FailedAssurancePolicies: append([]string{}, status.FailedAssurancePolicies...),
+		},
+	}
+}
+
+// EvaluateRules evaluates the CEL rules of every policy. The first rule that evaluates
+// to false, or fails to evaluate, is reported as a violation.
+func EvaluateRules(policies []Policy, input RuleInput) Result {
+	rules := 0
+	for _, p := range policies {
+		for _, rule := range p.Spec.Rules {
+			rules++
+			passed, err := evaluateRule(rule.Expression, input)
+			if err != nil {
+				return Result{
+					Policy:  p.Ref(),
+					Message: fmt.Sprintf("rule %s could not be evaluated: %v", rule.Name, err),
+				}
+			}
+			if !passed {
+				message := rule.Message
+				if message == "" {
+					message = fmt.Sprintf("rule %s not satisfied", rule.Name)
+				}
+				return Result{Policy: p.Ref(), Message: message}
+			}
+		}
+	}
+	return Result{Passed: true, Message: fmt.Sprintf("Image satisfies %d scan policy rules", rules)}
+}
+
+func evaluateRule(expression string, input RuleInput) (bool, error) {
+	prg, err := CompileRule(expression)
+	if err != nil {
+		return false, err
+	}
+	out, _, err := prg.Eval(map[string]any(input))
+	if err != nil {
+		return false, err
+	}
+	passed, ok := out.Value().(bool)
+	if !ok {
+		return false, errors.New("expression did not evaluate to bool")
+	}
+	return passed, nil
+}
+
+func severityCounts(summary *securityv1alpha1.VulnerabilitySummary) severityVars {
+	var counts securityv1alpha1.VulnerabilitySummary
+	if summary != nil {
+		counts = *summary
+	}
+	return severityVars{
+		Critical: counts.Critical,
+		High:     counts.High,
+		Medium:   counts.Medium,
+		Low:      counts.Low,
+		Unknown:  counts.Unknown,
+	}
+}
+
+func stringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// Fixable counts the vulnerabilities that have a fixed version, by severity.
+func Fixable(vulnerabilities []securityv1alpha1.Vulnerability) *securityv1alpha1.VulnerabilitySummary {
+	var counts securityv1alpha1.VulnerabilitySummary
+	for _, v := range vulnerabilities {
+		if v.FixedVersion == "" {
+			continue
+		}
+		switch strings.ToLower(v.Severity) {
+		case "critical":
+			counts.Critical++
+		case "high":
+			counts.High++
+		case "medium":
+			counts.Medium++
+		case "low":
+			counts.Low++
+		default:
+			counts.Unknown++
+		}
+	}
+	return &counts
+}
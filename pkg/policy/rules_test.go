@@ -0,0 +1,145 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+func TestValidateRules(t *testing.T) {
+	spec := securityv1alpha1.ScanPolicySpec{
+		Rules: []securityv1alpha1.Rule{
+			{Name: "valid", Expression: "scan.vulnerabilities.critical == 0"},
+			{Name: "syntax", Expression: "scan.vulnerabilities.critical =="},
+			{Name: "not-bool", Expression: "'critical'"},
+			{Name: "unknown-variable", Expression: "deployment.replicas > 1"},
+			{Name: "unknown-field", Expression: "pod.serviceAcount == 'default'"},
+		},
+	}
+
+	errs := ValidateRules(spec)
+	if len(errs) != 4 {
+		t.Fatalf("expected 4 errors, got %d: %v", len(errs), errs)
+	}
+	for i, err := range errs {
+		want := "spec.rules[" + string(rune('1'+i)) + "].expression"
+		if err.Field != want {
+			t.Errorf("expected error on %s, got %s", want, err.Field)
+		}
+	}
+}
+
+func TestEvaluateRules(t *testing.T) {
+	controller := true
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web-abc",
+			Namespace: "team-a",
+			Labels:    map[string]string{"app": "web"},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: "web", Controller: &controller},
+			},
+		},
+	}
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"env": "prod"}},
+	}
+	status := &securityv1alpha1.ImageScanStatus{
+		Phase:           securityv1alpha1.ScanPhaseRegistered,
+		Vulnerabilities: &securityv1alpha1.VulnerabilitySummary{Critical: 2, High: 3},
+		Fixable:         &securityv1alpha1.VulnerabilitySummary{High: 1},
+	}
+
+	rulePolicy := func(rules ...securityv1alpha1.Rule) []Policy {
+		return []Policy{{
+			Kind: KindClusterScanPolicy,
+			Name: "rules",
+			Spec: securityv1alpha1.ScanPolicySpec{Rules: rules},
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		status      *securityv1alpha1.ImageScanStatus
+		policies    []Policy
+		wantPassed  bool
+		wantMessage string
+	}{
+		{
+			name:       "no rules",
+			status:     status,
+			wantPassed: true,
+		},
+		{
+			name:   "fixable critical in production",
+			status: status,
+			policies: rulePolicy(securityv1alpha1.Rule{
+				Name:       "no-fixable-critical-in-prod",
+				Expression: "namespaceObject.labels['env'] != 'prod' || scan.fixable.critical == 0",
+			}),
+			wantPassed: true,
+		},
+		{
+			name: "unknown fixable counts fall back to all vulnerabilities",
+			status: &securityv1alpha1.ImageScanStatus{
+				Vulnerabilities: &securityv1alpha1.VulnerabilitySummary{Critical: 2},
+			},
+			policies: rulePolicy(securityv1alpha1.Rule{
+				Name:       "no-fixable-critical-in-prod",
+				Expression: "namespaceObject.labels['env'] != 'prod' || scan.fixable.critical == 0",
+				Message:    "fixable critical vulnerabilities are not allowed in production",
+			}),
+			wantMessage: "fixable critical vulnerabilities are not allowed in production",
+		},
+		{
+			name: "waived and not affected findings don't count",
+			status: &securityv1alpha1.ImageScanStatus{
+				Vulnerabilities:          &securityv1alpha1.VulnerabilitySummary{Critical: 2},
+				EffectiveVulnerabilities: &securityv1alpha1.VulnerabilitySummary{},
+			},
+			policies: rulePolicy(securityv1alpha1.Rule{
+				Name:       "no-criticals",
+				Expression: "scan.vulnerabilities.critical == 0 && scan.fixable.critical == 0",
+			}),
+			wantPassed: true,
+		},
+		{
+			name:   "owner kind and registry",
+			status: status,
+			policies: rulePolicy(securityv1alpha1.Rule{
+				Name:       "deployments-from-internal-registry",
+				Expression: "pod.ownerKind != 'ReplicaSet' || image.registry.endsWith('.example.com')",
+			}),
+			wantMessage: "rule deployments-from-internal-registry not satisfied",
+		},
+		{
+			name:   "evaluation error fails closed",
+			status: status,
+			policies: rulePolicy(securityv1alpha1.Rule{
+				Name:       "missing-label",
+				Expression: "pod.labels['team'] == 'a'",
+			}),
+			wantMessage: "rule missing-label could not be evaluated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := NewRuleInput(pod, namespace, "nginx:1.25", "sha256:0123", tt.status)
+			result := EvaluateRules(tt.policies, input)
+			if result.Passed != tt.wantPassed {
+				t.Errorf("expected passed=%v, got %v (%s)", tt.wantPassed, result.Passed, result.Message)
+			}
+			if !tt.wantPassed && result.Policy != "ClusterScanPolicy/rules" {
+				t.Errorf("unexpected policy %q", result.Policy)
+			}
+			if tt.wantMessage != "" && !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("expected message containing %q, got %q", tt.wantMessage, result.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Package reloadfile loads configuration files, such as the image policy and the image
+// exclusions, and reloads them when they change, e.g., when the ConfigMap they are mounted
+// from is updated.
+package reloadfile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultInterval is how often a file is checked for changes
+const DefaultInterval = 30 * time.Second
+
+// File holds the value parsed from a file. It is a manager Runnable reloading the file
+// every interval.
+type File[T any] struct {
+	// name describes the file's contents in errors and logs, e.g., "image policy"
+	name     string
+	path     string
+	interval time.Duration
+	parse    func([]byte) (T, error)
+
+	mu     sync.RWMutex
+	data   []byte
+	value  T
+	loaded bool
+}
+
+// New loads the file at path with parse; the file must be valid.
+func New[T any](name, path string, interval time.Duration, parse func([]byte) (T, error)) (*File[T], error) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	f := &File[T]{name: name, path: path, interval: interval, parse: parse}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Value returns the value parsed from the file. A nil File returns the zero value.
+func (f *File[T]) Value() T {
+	if f == nil {
+		var zero T
+		return zero
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.value
+}
+
+// Reload reads the file, returning true if it changed. An invalid file keeps the previous
+// value.
+func (f *File[T]) Reload() (bool, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", f.name, err)
+	}
+
+	f.mu.RLock()
+	unchanged := f.loaded && bytes.Equal(data, f.data)
+	f.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	value, err := f.parse(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", f.path, err)
+	}
+	f.mu.Lock()
+	f.data, f.value, f.loaded = data, value, true
+	f.mu.Unlock()
+	return true, nil
+}
+
+// Start reloads the file every interval until the context is cancelled.
+func (f *File[T]) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName(strings.ReplaceAll(f.name, " ", "-"))
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		changed, err := f.Reload()
+		if err != nil {
+			logger.Error(err, "Failed to reload "+f.name+", keeping the previous one")
+			return
+		}
+		if changed {
+			logger.Info("Reloaded "+f.name, "path", f.path)
+		}
+	}, f.interval)
+	return nil
+}
+
+// NeedLeaderElection returns false, since every replica serves admission requests.
+func (f *File[T]) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,68 @@
+package reloadfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseUpper(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("empty file")
+	}
+	return strings.ToUpper(string(data)), nil
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	parses := 0
+	f, err := New("test config", path, time.Minute, func(data []byte) (string, error) {
+		parses++
+		return parseUpper(data)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := f.Value(); got != "A" {
+		t.Errorf("expected A, got %q", got)
+	}
+
+	if changed, err := f.Reload(); err != nil || changed || parses != 1 {
+		t.Errorf("expected an unchanged file not to be parsed again, got changed=%v, err=%v, parses=%d", changed, err, parses)
+	}
+
+	if err := os.WriteFile(path, []byte("b"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := f.Reload(); err != nil || !changed || f.Value() != "B" {
+		t.Errorf("expected B, got changed=%v, err=%v, value=%q", changed, err, f.Value())
+	}
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Reload(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("expected an error naming the file, got %v", err)
+	}
+	if got := f.Value(); got != "B" {
+		t.Errorf("expected the previous value to be kept, got %q", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("test config", filepath.Join(t.TempDir(), "missing"), 0, parseUpper); err == nil ||
+		!strings.Contains(err.Error(), "reading test config") {
+		t.Errorf("expected an error reading a missing file, got %v", err)
+	}
+
+	var f *File[string]
+	if got := f.Value(); got != "" {
+		t.Errorf("expected the zero value from a nil File, got %q", got)
+	}
+}
@@ -0,0 +1,76 @@
+// Package scope decides which namespaces have their pods gated, from a static list of
+// names and namespace label selectors.
+package scope
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Namespaces selects the namespaces whose pods are gated. The webhooks and controllers
+// share one, so they reach the same decision for a namespace.
+type Namespaces struct {
+	// Reader reads namespace labels, normally from the informer cache, so relabelling a
+	// namespace takes effect without a restart
+	Reader client.Reader
+	// Excluded namespaces are never gated, whatever their labels
+	Excluded map[string]bool
+	// Selector limits gating to namespaces with matching labels (nil = every namespace)
+	Selector labels.Selector
+	// ExcludeSelector excludes namespaces with matching labels (nil = none)
+	ExcludeSelector labels.Selector
+}
+
+// NewNamespaces creates Namespaces from excluded names and label selectors in kubectl
+// syntax, where an empty selector is unset.
+func NewNamespaces(reader client.Reader, excluded map[string]bool, selector, excludeSelector string) (*Namespaces, error) {
+	n := &Namespaces{Reader: reader, Excluded: excluded}
+	var err error
+	if n.Selector, err = parseSelector(selector); err != nil {
+		return nil, fmt.Errorf("parsing namespace selector: %w", err)
+	}
+	if n.ExcludeSelector, err = parseSelector(excludeSelector); err != nil {
+		return nil, fmt.Errorf("parsing excluded namespace selector: %w", err)
+	}
+	return n, nil
+}
+
+func parseSelector(selector string) (labels.Selector, error) {
+	if selector == "" {
+		return nil, nil
+	}
+	return labels.Parse(selector)
+}
+
+// IsExcluded returns true if pods in namespace aren't gated. A nil Namespaces excludes
+// nothing. A namespace that can't be read is not excluded, so its pods are gated.
+func (n *Namespaces) IsExcluded(ctx context.Context, namespace string) bool {
+	if n == nil {
+		return false
+	}
+	if n.Excluded[namespace] {
+		return true
+	}
+	if (n.Selector == nil && n.ExcludeSelector == nil) || n.Reader == nil {
+		return false
+	}
+
+	var ns corev1.Namespace
+	if err := n.Reader.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.FromContext(ctx).Error(err, "Failed to read namespace labels", "namespace", namespace)
+		}
+		return false
+	}
+	set := labels.Set(ns.Labels)
+	if n.ExcludeSelector != nil && n.ExcludeSelector.Matches(set) {
+		return true
+	}
+	return n.Selector != nil && !n.Selector.Matches(set)
+}
@@ -0,0 +1,77 @@
+package scope
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestIsExcluded(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		namespace("team-a", map[string]string{"scan-gate": "enabled"}),
+		namespace("team-b", map[string]string{"scan-gate": "enabled", "scan-gate/opt-out": "true"}),
+		namespace("sandbox", nil),
+		namespace("kube-system", map[string]string{"scan-gate": "enabled"}),
+	).Build()
+
+	tests := []struct {
+		name            string
+		selector        string
+		excludeSelector string
+		want            map[string]bool
+	}{
+		{
+			name: "names only",
+			want: map[string]bool{"team-a": false, "team-b": false, "sandbox": false, "kube-system": true, "missing": false},
+		},
+		{
+			name:     "opt in",
+			selector: "scan-gate=enabled",
+			want:     map[string]bool{"team-a": false, "team-b": false, "sandbox": true, "kube-system": true, "missing": false},
+		},
+		{
+			name:            "opt out",
+			excludeSelector: "scan-gate/opt-out=true",
+			want:            map[string]bool{"team-a": false, "team-b": true, "sandbox": false, "kube-system": true},
+		},
+		{
+			name:            "opt out wins over opt in",
+			selector:        "scan-gate in (enabled)",
+			excludeSelector: "scan-gate/opt-out",
+			want:            map[string]bool{"team-a": false, "team-b": true, "sandbox": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewNamespaces(reader, map[string]bool{"kube-system": true}, tt.selector, tt.excludeSelector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for ns, want := range tt.want {
+				if got := n.IsExcluded(context.Background(), ns); got != want {
+					t.Errorf("%s: expected excluded=%v, got %v", ns, want, got)
+				}
+			}
+		})
+	}
+
+	var unset *Namespaces
+	if unset.IsExcluded(context.Background(), "kube-system") {
+		t.Error("expected nil Namespaces to exclude nothing")
+	}
+	if _, err := NewNamespaces(reader, nil, "scan-gate in (", ""); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+}
@@ -0,0 +1,326 @@
+// Package vex parses OpenVEX documents and matches their statements against
+// image scan findings.
+package vex
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+	"github.com/richardmsong/aqua-scan-gate/pkg/imageref"
+)
+
+// Status is the status of a vulnerability in a VEX statement
+type Status string
+
+const (
+	StatusNotAffected        Status = "not_affected"
+	StatusAffected           Status = "affected"
+	StatusFixed              Status = "fixed"
+	StatusUnderInvestigation Status = "under_investigation"
+)
+
+// Document is an OpenVEX document
+type Document struct {
+	Context    string      `json:"@context"`
+	ID         string      `json:"@id"`
+	Author     string      `json:"author"`
+	Timestamp  *time.Time  `json:"timestamp,omitempty"`
+	Version    int         `json:"version"`
+	Statements []Statement `json:"statements"`
+}
+
+// Statement asserts the status of a vulnerability in a set of products
+type Statement struct {
+	Vulnerability   Vulnerability `json:"vulnerability"`
+	Products        []Product     `json:"products"`
+	Status          Status        `json:"status"`
+	Justification   string        `json:"justification,omitempty"`
+	ImpactStatement string        `json:"impact_statement,omitempty"`
+	Timestamp       *time.Time    `json:"timestamp,omitempty"`
+}
+
+// Vulnerability identifies the vulnerability a statement is about
+type Vulnerability struct {
+	ID      string   `json:"@id,omitempty"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Product is a component a statement applies to, optionally narrowed to some of its
+// subcomponents (e.g., the packages inside an image)
+type Product struct {
+	Component
+	Subcomponents []Component `json:"subcomponents,omitempty"`
+}
+
+// Component identifies software by IRI, purl or hash
+type Component struct {
+	ID          string            `json:"@id,omitempty"`
+	Identifiers map[string]string `json:"identifiers,omitempty"`
+	Hashes      map[string]string `json:"hashes,omitempty"`
+}
+
+// Match is the statement that decided a finding, and the document it came from
+type Match struct {
+	Document  string
+	Statement *Statement
+}
+
+// Parse parses an OpenVEX document.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing OpenVEX document: %w", err)
+	}
+	if !strings.HasPrefix(doc.Context, "https://openvex.dev/ns") {
+		return nil, fmt.Errorf("not an OpenVEX document: unexpected @context %q", doc.Context)
+	}
+	return &doc, nil
+}
+
+// LoadFiles parses the OpenVEX documents in paths. A directory loads every .json file in it.
+func LoadFiles(paths []string) ([]Document, error) {
+	var docs []Document
+	for _, path := range paths {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if info.IsDir() {
+			if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			doc, err := Parse(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			docs = append(docs, *doc)
+		}
+	}
+	return docs, nil
+}
+
+// NotAffected returns the statement declaring a finding in image not_affected. When
+// several statements match the finding, the most recent one decides, so a later
+// affected statement overrides an earlier not_affected one.
+func NotAffected(docs []Document, image, digest string, v securityv1alpha1.Vulnerability) (Match, bool) {
+	var latest Match
+	var latestTime time.Time
+	for i := range docs {
+		doc := &docs[i]
+		for j := range doc.Statements {
+			statement := &doc.Statements[j]
+			if !statement.covers(image, digest, v) {
+				continue
+			}
+			timestamp := statementTime(doc, statement)
+			if latest.Statement == nil || !timestamp.Before(latestTime) {
+				latest = Match{Document: doc.ID, Statement: statement}
+				latestTime = timestamp
+			}
+		}
+	}
+	return latest, latest.Statement != nil && latest.Statement.Status == StatusNotAffected
+}
+
+func statementTime(doc *Document, statement *Statement) time.Time {
+	if statement.Timestamp != nil {
+		return *statement.Timestamp
+	}
+	if doc.Timestamp != nil {
+		return *doc.Timestamp
+	}
+	return time.Time{}
+}
+
+// covers returns true if the statement is about the finding's vulnerability in the image,
+// or, if the statement names no image, in the finding's package in any image.
+func (s *Statement) covers(image, digest string, v securityv1alpha1.Vulnerability) bool {
+	if !s.Vulnerability.is(v.ID) {
+		return false
+	}
+	if !slices.ContainsFunc(s.Products, func(p Product) bool { return p.identifiesImage() }) {
+		return slices.ContainsFunc(s.Products, func(p Product) bool { return p.matchesPackage(v) })
+	}
+	for _, product := range s.Products {
+		if !product.identifiesImage() || !product.matchesImage(image, digest) {
+			continue
+		}
+		if len(product.Subcomponents) == 0 {
+			return true
+		}
+		for _, sub := range product.Subcomponents {
+			if sub.matchesPackage(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v Vulnerability) is(id string) bool {
+	if strings.EqualFold(v.Name, id) || strings.EqualFold(v.ID, id) {
+		return true
+	}
+	for _, alias := range v.Aliases {
+		if strings.EqualFold(alias, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// identifiers returns the component's @id and purl.
+func (c Component) identifiers() []string {
+	var ids []string
+	if c.ID != "" {
+		ids = append(ids, c.ID)
+	}
+	if purl := c.Identifiers["purl"]; purl != "" {
+		ids = append(ids, purl)
+	}
+	return ids
+}
+
+// identifiesImage returns true if the component identifies images rather than a package:
+// it has a hash, a pkg:oci purl, or an identifier that isn't a purl.
+func (c Component) identifiesImage() bool {
+	if len(c.Hashes) > 0 {
+		return true
+	}
+	for _, id := range c.identifiers() {
+		if p, ok := parsePurl(id); !ok || p.typ == "oci" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesImage returns true if the component identifies the image, by digest, by a
+// pkg:oci purl, or by a sha-256 hash.
+func (c Component) matchesImage(image, digest string) bool {
+	if digest != "" && c.Hashes["sha-256"] == strings.TrimPrefix(digest, "sha256:") {
+		return true
+	}
+	for _, id := range c.identifiers() {
+		if p, ok := parsePurl(id); ok {
+			if p.typ == "oci" && ociPurlMatches(p, image, digest) {
+				return true
+			}
+			continue
+		}
+		if digest != "" && (id == digest || strings.HasSuffix(id, "@"+digest)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ociPurlMatches matches a pkg:oci purl, whose version is the image digest, against an
+// image. A purl without a version matches every digest of its repository_url.
+func ociPurlMatches(p purl, image, digest string) bool {
+	if p.version != "" {
+		return p.version == digest
+	}
+	repositoryURL := p.qualifiers["repository_url"]
+	return repositoryURL != "" && imageref.Repository(repositoryURL) == imageref.Repository(image)
+}
+
+// matchesPackage returns true if the component is a package purl of the finding's package
+// type naming its package, and its installed version if the purl has one.
+func (c Component) matchesPackage(v securityv1alpha1.Vulnerability) bool {
+	for _, id := range c.identifiers() {
+		p, ok := parsePurl(id)
+		if !ok || p.typ == "oci" || p.typ != v.PackageType || p.packageName() != v.Package {
+			continue
+		}
+		if p.version == "" || p.version == v.InstalledVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// purl is a parsed package URL (pkg:type/namespace/name@version?qualifiers#subpath)
+type purl struct {
+	typ        string
+	namespace  string
+	name       string
+	version    string
+	qualifiers map[string]string
+}
+
+func parsePurl(s string) (purl, bool) {
+	rest, ok := strings.CutPrefix(s, "pkg:")
+	if !ok {
+		return purl{}, false
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, rawQualifiers, _ := strings.Cut(rest, "?")
+
+	var p purl
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		version, err := url.PathUnescape(rest[i+1:])
+		if err != nil {
+			return purl{}, false
+		}
+		p.version = version
+		rest = rest[:i]
+	}
+	segments := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(segments) < 2 {
+		return purl{}, false
+	}
+	p.typ = strings.ToLower(segments[0])
+	namespace, err := url.PathUnescape(strings.Join(segments[1:len(segments)-1], "/"))
+	if err != nil {
+		return purl{}, false
+	}
+	p.namespace = namespace
+	name, err := url.PathUnescape(segments[len(segments)-1])
+	if err != nil {
+		return purl{}, false
+	}
+	p.name = name
+
+	if rawQualifiers != "" {
+		values, err := url.ParseQuery(rawQualifiers)
+		if err != nil {
+			return purl{}, false
+		}
+		p.qualifiers = map[string]string{}
+		for key := range values {
+			p.qualifiers[strings.ToLower(key)] = values.Get(key)
+		}
+	}
+	return p, true
+}
+
+// osPackageTypes are the purl types whose namespace is the distribution (e.g.,
+// pkg:deb/debian/openssl), which isn't part of the package name Aqua reports
+var osPackageTypes = map[string]bool{"deb": true, "rpm": true, "apk": true, "alpm": true}
+
+// packageName returns the package a purl names as Aqua reports it: the namespace is part
+// of the name (e.g., golang module paths and npm scopes), maven packages are
+// group:artifact, and OS packages are named without their distribution.
+func (p purl) packageName() string {
+	switch {
+	case p.namespace == "" || osPackageTypes[p.typ]:
+		return p.name
+	case p.typ == "maven":
+		return p.namespace + ":" + p.name
+	}
+	return p.namespace + "/" + p.name
+}
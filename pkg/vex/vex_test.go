@@ -0,0 +1,211 @@
+package vex
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	securityv1alpha1 "github.com/richardmsong/aqua-scan-gate/api/v1alpha1"
+)
+
+const (
+	testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	testImage  = "registry.example.com/team/app:v1"
+)
+
+const testDocument = `{
+  "@context": "https://openvex.dev/ns/v0.2.0",
+  "@id": "https://example.com/vex/app-2024-001",
+  "author": "Platform Team",
+  "timestamp": "2024-05-01T00:00:00Z",
+  "version": 1,
+  "statements": [
+    {
+      "vulnerability": {"name": "CVE-2024-0001"},
+      "products": [{"@id": "pkg:oci/app@sha256%3A0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}],
+      "status": "not_affected",
+      "justification": "vulnerable_code_not_in_execute_path"
+    },
+    {
+      "vulnerability": {"name": "CVE-2024-0002", "aliases": ["GHSA-xxxx-yyyy-zzzz"]},
+      "products": [{
+        "@id": "pkg:oci/app?repository_url=registry.example.com/team/app",
+        "subcomponents": [{"@id": "pkg:deb/debian/openssl@3.0.11"}]
+      }],
+      "status": "not_affected",
+      "justification": "inline_mitigations_already_exist"
+    },
+    {
+      "vulnerability": {"name": "CVE-2024-0003"},
+      "products": [{"identifiers": {"purl": "pkg:golang/golang.org/x/net"}}],
+      "status": "not_affected",
+      "impact_statement": "The HTTP/2 server is not used"
+    },
+    {
+      "vulnerability": {"name": "CVE-2024-0004"},
+      "products": [{"hashes": {"sha-256": "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}}],
+      "status": "not_affected",
+      "justification": "component_not_present"
+    },
+    {
+      "vulnerability": {"name": "CVE-2024-0005"},
+      "products": [
+        {"@id": "pkg:oci/app?repository_url=registry.example.com/team/app"},
+        {"@id": "pkg:npm/%40angular/core@17.0.0"}
+      ],
+      "status": "not_affected",
+      "justification": "vulnerable_code_not_present"
+    },
+    {
+      "vulnerability": {"name": "CVE-2024-0004"},
+      "products": [{"@id": "` + testDigest + `"}],
+      "status": "affected",
+      "timestamp": "2024-06-01T00:00:00Z"
+    }
+  ]
+}`
+
+func TestNotAffected(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	docs := []Document{*doc}
+
+	tests := []struct {
+		name          string
+		image         string
+		digest        string
+		vulnerability securityv1alpha1.Vulnerability
+		want          bool
+	}{
+		{
+			name:          "oci purl with digest",
+			image:         testImage,
+			digest:        testDigest,
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0001", Package: "zlib"},
+			want:          true,
+		},
+		{
+			name:          "oci purl with other digest",
+			image:         testImage,
+			digest:        "sha256:ffff",
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0001", Package: "zlib"},
+		},
+		{
+			name:          "repository subcomponent",
+			image:         "registry.example.com/team/app:v2",
+			digest:        "sha256:ffff",
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0002", Package: "openssl", PackageType: "deb", InstalledVersion: "3.0.11"},
+			want:          true,
+		},
+		{
+			name:          "repository subcomponent version mismatch",
+			image:         testImage,
+			digest:        testDigest,
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0002", Package: "openssl", PackageType: "deb", InstalledVersion: "3.0.2"},
+		},
+		{
+			name:          "repository subcomponent type mismatch",
+			image:         testImage,
+			digest:        testDigest,
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0002", Package: "openssl", PackageType: "npm", InstalledVersion: "3.0.11"},
+		},
+		{
+			name:          "alias",
+			image:         testImage,
+			digest:        testDigest,
+			vulnerability: securityv1alpha1.Vulnerability{ID: "ghsa-xxxx-yyyy-zzzz", Package: "openssl", PackageType: "deb", InstalledVersion: "3.0.11"},
+			want:          true,
+		},
+		{
+			name:          "package purl in any image",
+			image:         "nginx:1.25",
+			digest:        "sha256:ffff",
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0003", Package: "golang.org/x/net", PackageType: "golang"},
+			want:          true,
+		},
+		{
+			name:          "package purl in another namespace",
+			image:         "nginx:1.25",
+			digest:        "sha256:ffff",
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0003", Package: "example.com/net", PackageType: "golang"},
+		},
+		{
+			name:          "package purl of another type",
+			image:         "nginx:1.25",
+			digest:        "sha256:ffff",
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0003", Package: "golang.org/x/net", PackageType: "npm"},
+		},
+		{
+			name:          "package product in the named image",
+			image:         testImage,
+			digest:        testDigest,
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0005", Package: "@angular/core", PackageType: "npm", InstalledVersion: "17.0.0"},
+			want:          true,
+		},
+		{
+			name:          "package product outside the named image",
+			image:         "nginx:1.25",
+			digest:        "sha256:ffff",
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0005", Package: "@angular/core", PackageType: "npm", InstalledVersion: "17.0.0"},
+		},
+		{
+			name:          "later affected statement wins",
+			image:         testImage,
+			digest:        testDigest,
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-0004", Package: "zlib"},
+		},
+		{
+			name:          "no statement",
+			image:         testImage,
+			digest:        testDigest,
+			vulnerability: securityv1alpha1.Vulnerability{ID: "CVE-2024-9999", Package: "zlib"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, got := NotAffected(docs, tt.image, tt.digest, tt.vulnerability)
+			if got != tt.want {
+				t.Errorf("expected not affected=%v, got %v", tt.want, got)
+			}
+			if got && match.Document != "https://example.com/vex/app-2024-001" {
+				t.Errorf("unexpected document %q", match.Document)
+			}
+		})
+	}
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	if _, err := Parse([]byte(`{"bomFormat": "CycloneDX"}`)); err == nil {
+		t.Error("expected an error for a non-OpenVEX document")
+	}
+	if _, err := Parse([]byte(`not json`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(testDocument), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := LoadFiles([]string{dir, filepath.Join(dir, "a.json")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(docs) != 3 {
+		t.Errorf("expected 3 documents, got %d", len(docs))
+	}
+
+	if _, err := LoadFiles([]string{filepath.Join(dir, "missing.json")}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}